@@ -3,9 +3,11 @@ package game
 import (
 	_ "embed"
 	"fmt"
+	"image"
 	"image/color"
 	"log"
 	"math"
+	"os"
 	"strconv"
 	"strings"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
 	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/font/opentype"
 )
 
@@ -26,6 +29,14 @@ const (
 	SHADOW_OFFSET = 2
 )
 
+// Colours of the rule grid in the pause menu: boxes for the neighbour counts in the rules, boxes for the ones which
+// aren't, and the frame around the rules being edited.
+var (
+	RULE_GRID_ON_COLOR    = color.RGBA{255, 255, 255, 255}
+	RULE_GRID_OFF_COLOR   = color.RGBA{64, 64, 64, 255}
+	RULE_GRID_FRAME_COLOR = color.RGBA{79, 195, 247, 255}
+)
+
 // The font used by the UI. Embedded so that the binary can be used without depending on a file or remote asset.
 //
 //go:embed assets/JetBrainsMono-Medium.ttf
@@ -41,6 +52,10 @@ type UI struct {
 
 	selectedLiveCellPercent float64
 
+	selectedBoundaryMode  BoundaryMode
+	selectedSymmetry      Symmetry
+	selectedNeighbourhood Neighbourhood
+
 	// Scale factors possible given the screen dimensions (they must divide both fullscreen width and height)
 	// and the index of the scale factor currently selected in the pause menu.
 	possibleScaleFactors []int
@@ -49,18 +64,74 @@ type UI struct {
 	// FPS visibility during simulation.
 	isFpsVisible bool
 
+	// Visibility of the generation counter, and the generation the game is at, kept up to date by the game.
+	isGenerationVisible bool
+	generation          int
+
+	// The number of live cells and the fraction of the board they cover, shown with the generation counter and kept up
+	// to date by the game while it's shown.
+	population         int
+	populationFraction float64
+
+	// The seed R restarts the board from, kept up to date by the game.
+	seed int64
+
 	// True when the application is first started, false afterwards.
 	shouldDisplaySlashScreen bool
 
 	shouldDisplayWritingToFileText bool
 	shouldDisplayRecordingText     bool
 
+	// Says which slots are recording, or is empty if none are.
+	recordingSlotsText string
+
+	// How many more frames to say that a recording couldn't be started because the last one is still being written.
+	recordingBlockedTicks int
+
+	// Whether to show the coordinates and state of the cell under the cursor, and the text describing it. The text is
+	// kept up to date by the game, since the UI doesn't know about the board.
+	isCursorInfoVisible bool
+	cursorInfoText      string
+
+	// The block entropy of the board when it was last captured, or empty if it isn't shown. See complexity.go.
+	complexityText string
+
+	// Says the board is stable and with which period, or "" if it isn't or isn't being checked. Kept up to date by the
+	// game.
+	stableText string
+
+	// The text describing the last board event and how many more frames its cue is shown for.
+	cueText      string
+	cueTicksLeft int
+
+	// Whether recordings should be cropped to the bounding box of the live cells when written to file.
+	isAutoCropEnabled bool
+
 	// Font face for UI text rendering.
 	fontFace font.Face
 
 	// Game updates 2^speed * 60 times per second. So speed = 2 gives effective 120FPS, speed = -3 gives 7.5FPS.
 	// gets rounded when actually setting the Ticks Per Second).
 	speed int
+
+	// An exact number of board updates per second which overrides speed when positive. Set with the -speed flag and
+	// cleared as soon as the speed is changed with the arrow keys.
+	exactSpeed float64
+
+	// The number of board updates per game update actually being run. Follows getSpeedup, either instantly or ramping
+	// smoothly towards it when SPEED_RAMP is set.
+	currentSpeedup float64
+
+	// Whether the speed follows the activity on the board, and how much the selected speed is multiplied by to do so,
+	// kept up to date by the game.
+	isAutoSpeed     bool
+	autoSpeedFactor float64
+
+	// The bounds on the rules rolled with W, the index of the bound being adjusted and the generator the rules are
+	// rolled from, seeded with SEED so that the same rules come up each run.
+	ruleConstraints RuleConstraints
+	constraintIndex int
+	ruleRNG         RNG
 }
 
 func (ui *UI) initialize(BRules, SRules Ruleset, liveCellPercent float64, initialScaleIndex int) {
@@ -72,23 +143,39 @@ func (ui *UI) initialize(BRules, SRules Ruleset, liveCellPercent float64, initia
 	ui.selectedLiveCellPercent = liveCellPercent
 
 	ui.rulesBeingChanged = &ui.selectedBRules
+	ui.ruleConstraints = DEFAULT_RULE_CONSTRAINTS
+	ui.ruleRNG = newRNG(SEED)
 	ui.isFpsVisible = true
 	ui.scaleFactorIndex = initialScaleIndex
 
 	ui.initScaleFactors()
-
-	ui.fontFace = loadFontFace()
+	ui.initFontFace()
 	ui.shouldDisplaySlashScreen = true
 }
 
-// Initialize possible scale factors, i.e. find the integers which divide both the screen width and height.
+// Loads the UI font at a size which follows the size of the screen.
+func (ui *UI) initFontFace() {
+	screenX, screenY := FullscreenSize()
+	ui.fontFace = loadFontFace(FONT_FILE, math.Sqrt(float64(screenX*screenY)/100)) // slightly cursed DPI approximation
+}
+
+// Initialize possible scale factors, i.e. find the integers which divide both the screen width and height. The selected
+// scale factor stays selected if it's still possible.
 func (ui *UI) initScaleFactors() {
+	selected := 0
+	if ui.scaleFactorIndex < len(ui.possibleScaleFactors) {
+		selected = ui.getScaleFactor()
+	}
+
 	ui.possibleScaleFactors = []int{}
-	screenX, screenY := ebiten.ScreenSizeInFullscreen()
+	screenX, screenY := screenSize()
 	smallerDimension := intMin(screenX, screenY)
 	for i := 1; i <= smallerDimension; i++ {
 		if screenX%i == 0 && screenY%i == 0 {
 			ui.possibleScaleFactors = append(ui.possibleScaleFactors, i)
+			if i == selected {
+				ui.scaleFactorIndex = len(ui.possibleScaleFactors) - 1
+			}
 		}
 	}
 	if ui.scaleFactorIndex >= len(ui.possibleScaleFactors) {
@@ -96,37 +183,74 @@ func (ui *UI) initScaleFactors() {
 	}
 }
 
-// Returns the font face used by the UI, loaded from the embedded font file byte slice.
-func loadFontFace() font.Face {
-	tt, err := opentype.Parse(fontBytes)
+// Returns the font face used by the UI, loaded from the font file at path, or from the embedded font if path is "". If
+// the font can't be loaded, the UI falls back to a basic built-in bitmap font rather than not running at all.
+func loadFontFace(path string, dpi float64) font.Face {
+	data := fontBytes
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			log.Printf("could not load font, using the basic font instead: %v", err)
+			return basicfont.Face7x13
+		}
+	}
+
+	uiFont, err := parseFontFace(data, dpi)
+	if err != nil {
+		log.Printf("could not load font, using the basic font instead: %v", err)
+		return basicfont.Face7x13
+	}
+	return uiFont
+}
+
+// Parses an OpenType or TrueType font and returns its face at the UI font size.
+func parseFontFace(data []byte, dpi float64) (font.Face, error) {
+	tt, err := opentype.Parse(data)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	screenX, screenY := ebiten.ScreenSizeInFullscreen()
-	uiFont, err := opentype.NewFace(tt, &opentype.FaceOptions{
+	return opentype.NewFace(tt, &opentype.FaceOptions{
 		Size:    FONT_SIZE,
-		DPI:     math.Sqrt(float64(screenX*screenY) / 100), // slightly cursed DPI approximation
+		DPI:     dpi,
 		Hinting: font.HintingFull,
 	})
-	if err != nil {
-		log.Fatal(err)
-	}
-	return uiFont
 }
 
 func (ui *UI) handleInput(isGamePaused bool) {
 	// Toggle FPS visibility on V press.
-	if inpututil.IsKeyJustPressed(ebiten.KeyV) && !ebiten.IsKeyPressed(ebiten.KeyShift) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) && !ebiten.IsKeyPressed(ebiten.KeyShift) &&
+		!ebiten.IsKeyPressed(ebiten.KeyControl) {
 		ui.isFpsVisible = !ui.isFpsVisible
 	}
 
-	// Adjust update speed on left/right arrow press.
+	// Toggle the generation counter and population on G press. SHIFT+G is handled by the game, since it needs the board.
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) && !ebiten.IsKeyPressed(ebiten.KeyShift) {
+		ui.isGenerationVisible = !ui.isGenerationVisible
+	}
+
+	// Toggle the cursor cell readout on I press.
+	if inpututil.IsKeyJustPressed(ebiten.KeyI) {
+		ui.isCursorInfoVisible = !ui.isCursorInfoVisible
+	}
+
+	// Adjust update speed on left/right arrow press. An exact speed is first rounded to the nearest power of two speed.
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) || inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
+		if ui.exactSpeed > 0 {
+			ui.speed = int(math.Round(math.Log2(ui.exactSpeed / float64(ebiten.TPS()))))
+			ui.exactSpeed = 0
+		}
+	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
 		ui.speed -= 1
 	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
 		ui.speed += 1
 	}
+	// Toggle the speed following the activity on the board on S press. SHIFT+S saves the board while paused.
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) && !ebiten.IsKeyPressed(ebiten.KeyShift) {
+		ui.isAutoSpeed = !ui.isAutoSpeed
+	}
+	ui.updateCurrentSpeedup()
 
 	if !isGamePaused {
 		return
@@ -141,13 +265,40 @@ func (ui *UI) handleInput(isGamePaused bool) {
 		}
 	}
 
-	// Clear selected rules on C press.
+	// Clear the rules being edited on C press, both the birth and survival rules on SHIFT+C, and reset the rules to
+	// Conway's Game of Life on CTRL+C.
 	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
-		if ui.rulesBeingChanged == &ui.selectedBRules {
-			ui.selectedBRules = Ruleset{}
-		} else {
-			ui.selectedSRules = Ruleset{}
+		mode := CLEAR_EDITED
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			mode = CLEAR_BOTH
+		} else if ebiten.IsKeyPressed(ebiten.KeyControl) {
+			mode = CLEAR_TO_DEFAULT
 		}
+		ui.clearRules(mode)
+	}
+
+	// Switch the rules being edited to the next preset on SHIFT+P press. The closest preset line then shows its name.
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) && ebiten.IsKeyPressed(ebiten.KeyShift) {
+		p := nextPreset(ui.selectedBRules, ui.selectedSRules)
+		ui.selectedBRules, ui.selectedSRules = p.BRules, p.SRules
+	}
+
+	// Roll new rules within the constraints on W press. Z selects the next constraint, and , and . lower and raise it.
+	if inpututil.IsKeyJustPressed(ebiten.KeyW) {
+		ui.selectedBRules, ui.selectedSRules = ui.ruleConstraints.random(ui.ruleRNG)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyZ) {
+		ui.constraintIndex = (ui.constraintIndex + 1) % NUM_RULE_CONSTRAINTS
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyComma) && !ebiten.IsKeyPressed(ebiten.KeyShift) {
+		ui.ruleConstraints.adjust(ui.constraintIndex, -1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyPeriod) && !ebiten.IsKeyPressed(ebiten.KeyShift) {
+		ui.ruleConstraints.adjust(ui.constraintIndex, 1)
+	}
+
+	// Toggle auto-cropping of recordings on A press. SHIFT+A switches the recording format.
+	if inpututil.IsKeyJustPressed(ebiten.KeyA) && !ebiten.IsKeyPressed(ebiten.KeyShift) {
+		ui.isAutoCropEnabled = !ui.isAutoCropEnabled
 	}
 
 	// Change initial live cell percentage value, adjusting the increment if SHIFT or CONTROL are pressed to allow for
@@ -164,6 +315,21 @@ func (ui *UI) handleInput(isGamePaused bool) {
 		ui.selectedLiveCellPercent -= delta
 	}
 
+	// Cycle through the boundary modes on B press.
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		ui.selectedBoundaryMode = (ui.selectedBoundaryMode + 1) % NUM_BOUNDARY_MODES
+	}
+
+	// Cycle through the neighbourhoods on SHIFT+K press.
+	if inpututil.IsKeyJustPressed(ebiten.KeyK) && ebiten.IsKeyPressed(ebiten.KeyShift) {
+		ui.selectedNeighbourhood = ui.selectedNeighbourhood.next()
+	}
+
+	// Cycle through the symmetries of the initial fill on M press.
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) {
+		ui.selectedSymmetry = (ui.selectedSymmetry + 1) % NUM_SYMMETRIES
+	}
+
 	// Change selected scale factor to the next larger/smaller scale factor.
 	if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
 		ui.scaleFactorIndex++
@@ -179,17 +345,53 @@ func (ui *UI) handleInput(isGamePaused bool) {
 	ui.scaleFactorIndex = clamp(0, len(ui.possibleScaleFactors)-1, ui.scaleFactorIndex)
 }
 
+// What clearing the rules in the pause menu does.
+type ClearMode int
+
+const (
+	// Clear the rules being edited, leaving the others as they are.
+	CLEAR_EDITED ClearMode = iota
+
+	// Clear both the birth and the survival rules.
+	CLEAR_BOTH
+
+	// Reset both rules to the default ones, Conway's Game of Life.
+	CLEAR_TO_DEFAULT
+)
+
+// Clears the rules being edited in the pause menu as described by mode.
+func (ui *UI) clearRules(mode ClearMode) {
+	switch mode {
+	case CLEAR_BOTH:
+		ui.selectedBRules, ui.selectedSRules = Ruleset{}, Ruleset{}
+	case CLEAR_TO_DEFAULT:
+		ui.selectedBRules, ui.selectedSRules = PRESETS[0].BRules, PRESETS[0].SRules
+	default:
+		*ui.rulesBeingChanged = Ruleset{}
+	}
+}
+
+// Toggles the neighbour counts whose number keys were just pressed in the rules being changed. Several keys can be
+// pressed in the same frame, in which case each of their numbers is toggled exactly once, in ascending order.
 func (ui *UI) handleNumberKeys() {
-	// Figure out which number key is being pressed. Handles the possibility of multiple at once, which is unlikely but
-	// possible.
+	ui.toggleRuleNumbers(pressedNumbers(inpututil.IsKeyJustPressed))
+}
+
+// Returns the numbers 0 to 8 whose keys isPressed reports as pressed, in ascending order.
+func pressedNumbers(isPressed func(ebiten.Key) bool) []uint8 {
 	nums := []uint8{}
-	keys := []ebiten.Key{ebiten.Key0, ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4, ebiten.Key5, ebiten.Key6, ebiten.Key7, ebiten.Key8}
+	keys := []ebiten.Key{ebiten.Key0, ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4, ebiten.Key5, ebiten.Key6,
+		ebiten.Key7, ebiten.Key8}
 	for _, key := range keys {
-		if inpututil.IsKeyJustPressed(key) {
+		if isPressed(key) {
 			nums = append(nums, uint8(int(key)-int(ebiten.Key0)))
 		}
 	}
+	return nums
+}
 
+// Toggles each of the given neighbour counts in the rules being changed, in the order given.
+func (ui *UI) toggleRuleNumbers(nums []uint8) {
 	for _, num := range nums {
 		(*ui.rulesBeingChanged)[num] = !(*ui.rulesBeingChanged)[num]
 	}
@@ -214,35 +416,129 @@ func (ui *UI) Draw(screen *ebiten.Image, isGamePaused bool) {
 
 		return
 	} else if ui.shouldDisplayWritingToFileText {
-		drawTextUpperLeft(screen, "saving gif to file...", ui.fontFace)
+		str := "saving recording to file..."
+		if ui.recordingBlockedTicks > 0 {
+			ui.recordingBlockedTicks--
+			str += " (wait for it to finish before starting a new recording)"
+		}
+		drawTextUpperLeft(screen, str, ui.fontFace)
 	} else if ui.shouldDisplayRecordingText {
 		drawTextUpperLeft(screen, "recording...", ui.fontFace)
 	}
 
+	if ui.isCursorInfoVisible {
+		drawTextLowerRight(screen, ui.cursorInfoText, ui.fontFace)
+	}
+
+	if !isGamePaused {
+		ui.drawCue(screen)
+	}
+
+	upperRightLines := []string{}
 	if ui.isFpsVisible {
-		fpsText := fmt.Sprintf("%.2f FPS (%vx)", ebiten.ActualFPS(), ui.getSpeedup())
-		drawTextUpperRight(screen, fpsText, ui.fontFace)
+		fpsText := fmt.Sprintf("%.2f FPS (%.4gx, %.4g gen/s)", ebiten.ActualFPS(), ui.cappedSpeedup(),
+			ui.cappedSpeedup()*float64(ebiten.TPS()))
+		if ui.isAutoSpeed {
+			fpsText += " (auto)"
+		}
+		if ui.cappedSpeedup() < ui.currentSpeedup {
+			fpsText += " (capped)"
+		}
+		upperRightLines = append(upperRightLines, fpsText)
+	}
+	if ui.isGenerationVisible {
+		upperRightLines = append(upperRightLines, fmt.Sprintf("generation %v", ui.generation),
+			fmt.Sprintf("population: %v (%.1f%%)", ui.population, 100*ui.populationFraction))
+	}
+	if ui.complexityText != "" {
+		upperRightLines = append(upperRightLines, ui.complexityText)
 	}
+	if ui.stableText != "" {
+		upperRightLines = append(upperRightLines, ui.stableText)
+	}
+	if ui.recordingSlotsText != "" {
+		upperRightLines = append(upperRightLines, ui.recordingSlotsText)
+	}
+	drawLinesUpperRight(screen, upperRightLines, ui.fontFace)
 
 	if isGamePaused {
 		lines := []string{
 			"%vbirth rules: %v",
 			"%vsurvival rules: %v",
+			"rulestring: %v",
 			"inital percentage of live cells: %.1f",
 			"board resolution: %v (%vx zoom)",
+			"boundary: %v",
+			"neighbourhood: %v",
+			"initial symmetry: %v",
+			"closest preset: %v",
+			"random rules: %v",
 			"",
 			"use number keys to modify cell %v rules (press TAB to switch, C to clear)",
+			"press SHIFT+C to clear both the birth and survival rules or CTRL+C to reset them to Conway's Game of Life",
+			"press W to roll random rules within the limits above, Z to select a limit and , and . to change it",
+			"press SHIFT+P to cycle through the presets, such as HighLife, Seeds and Day & Night (R applies them)",
 			"use - and + to change initial live cell percentage (hold SHIFT/CTRL for smaller/smallest increment)",
-			"use [ and ] to change resolution",
+			"use [ and ] to change resolution (or just the zoom, for boards set with -board)",
+			"scroll the mouse wheel to zoom the view in or out around the cursor, and drag with the middle button to pan it",
+			"press B to change the boundary (dead cells, wrap around or mirror the edge cells)",
+			"press SHIFT+K to switch between the 8 cell Moore and 4 cell von Neumann neighbourhoods",
+			"press M to change the symmetry of the initial cells",
 			"use ← and → to change speed",
-			"press V to toggle FPS visibility",
+			"press S to toggle speeding up quiet boards and slowing down busy ones automatically",
+			"press V to toggle FPS visibility and G to toggle the generation counter and population",
+			"press SHIFT+V to toggle drawing the board like an old CRT monitor, and CTRL+V to change how strongly",
+			"press I to toggle showing the cell under the cursor",
+			"press SHIFT+G to measure how complex the board looks right now, as the entropy of its 3x3 tiles",
+			"press SHIFT+H to toggle drawing boxes around the spaceships, such as gliders, as they move",
+			"press N to toggle drawing just born cells in their own colour",
+			"press SHIFT+N to toggle colouring live cells by age",
+			"press SHIFT+T to toggle fading trails behind dying cells",
+			"press K to toggle showing the number of live neighbours of every cell",
+			"press P to toggle drawing cells at whole pixel sizes only, for boards set with -board",
+			"press E to print a string for sharing this run, which can be loaded with -config",
+			"press O to toggle showing the rules, seed and a QR code of the sharing string in the corner",
+			"press D to print the transition tables of the current rules",
 			"",
 			"press SPACE to pause/unpause or R to restart with new settings",
+			"press SHIFT+. to advance a single generation under the running rules, staying paused",
+			fmt.Sprintf("press SHIFT+, to step back a generation, up to the last %v", REWIND_GENERATIONS),
+			"press L while running to show this menu and apply rule changes immediately",
+			"press Q to restart with a new random board, keeping the current settings",
+			fmt.Sprintf("seed: %v (press ; and ' to step through the seeds, and R to restart from it)", ui.seed),
+			"hold the left mouse button to airbrush random live cells onto the board",
+			"hold ALT and the left mouse button to draw live cells, or ALT and the right mouse button to erase them",
+			"hold CTRL while painting to make cells immortal, or CTRL+SHIFT to make them mortal again",
+			"press Y to mirror the board left to right, or SHIFT+Y to mirror it top to bottom",
+			"press U to turn a square board clockwise, or SHIFT+U anticlockwise (while dragging, turns the square selected)",
+			"drag with the right mouse button to fill a rectangle with random cells at the starting percentage",
+			"press ENTER while dragging to make the rectangle follow the rules above, or SHIFT+ENTER to remove all such areas",
+			"press X to switch back to the previous rules (hold SHIFT to also restart the board)",
+		}
+
+		if LTL_RULE != nil {
+			lines = append(lines, fmt.Sprintf("running Larger than Life rule %v instead of the rules above", LTL_RULE))
+		}
+		if STATES > 2 {
+			lines = append(lines, fmt.Sprintf("running with %v Generations states: dying cells fade out over %v generations",
+				STATES, STATES-2))
+		}
+		if BACKGROUND_IMAGE != nil {
+			lines = append(lines, "press T to toggle showing the background image through the dead cells")
+		}
+		if GHOST_PATTERN != nil {
+			lines = append(lines, "press H to toggle showing the -ghost pattern and J to move it to the cursor")
 		}
 
 		if SAVING_ENABLED {
 			lines = append(lines, []string{
 				"to start recording, unpause with SHIFT+SPACE and then pause again with SPACE to stop",
+				"while running, press CTRL+1 to CTRL+9 to start and stop independent recordings in numbered slots",
+				fmt.Sprintf("press A to toggle cropping recordings to the live cells (currently %v)", onOff(ui.isAutoCropEnabled)),
+				fmt.Sprintf("press SHIFT+A to switch between recording GIFs and full colour APNGs (currently %v)", RECORD_FORMAT),
+				"press F11 to switch between fullscreen and windowed mode",
+				"press SHIFT+S to save the live cells to an RLE file in the output directory",
+				"press F12 to save a PNG screenshot of the board, one pixel per cell, or SHIFT+F12 at the scale it's drawn",
 				"",
 				"press ESC to quit",
 			}...)
@@ -280,14 +576,23 @@ func (ui *UI) Draw(screen *ebiten.Image, isGamePaused bool) {
 			}
 		}
 
+		// Show how far the rules being edited are from the closest known rule.
+		preset, distance := closestPreset(ui.selectedBRules, ui.selectedSRules)
+		presetInfo := fmt.Sprintf("%v, %v rules differ", preset.Name, distance)
+		if distance == 0 {
+			presetInfo = preset.Name
+		}
+
 		// Make a string showing the selected board resolution.
-		screenX, screenY := screen.Bounds().Dx(), screen.Bounds().Dy()
-		scaleFactor := ui.getScaleFactor()
-		resolution := fmt.Sprintf("%vx%v", screenX/scaleFactor, screenY/scaleFactor)
+		screenY := screen.Bounds().Dy()
+		boardX, boardY := boardSize(ui.getScaleFactor())
+		resolution := fmt.Sprintf("%vx%v", boardX, boardY)
 
 		// The pause menu UI is just this one formatted string.
 		infoString := fmt.Sprintf(infoFormatString, birthRulesIndicator, birthRules, survivalRulesIndicator, survivalRules,
-			ui.selectedLiveCellPercent, resolution, ui.getScaleFactor(), changeType)
+			FormatRulestring(ui.selectedBRules, ui.selectedSRules), ui.selectedLiveCellPercent, resolution,
+			ui.getScaleFactor(), ui.selectedBoundaryMode, ui.selectedNeighbourhood, ui.selectedSymmetry, presetInfo,
+			ui.ruleConstraints.describe(ui.constraintIndex), changeType)
 
 		// Because text.Draw() is weird about positioning, we use the height of the first line to offset the y position
 		// of the UI text.
@@ -297,6 +602,46 @@ func (ui *UI) Draw(screen *ebiten.Image, isGamePaused bool) {
 		infoY := screenY - boundsAllLines.Dy() - MARGIN + boundsFirstLine.Dy()
 
 		drawTextWithShadow(screen, infoString, ui.fontFace, infoX, infoY)
+		ui.drawRuleGrid(screen, infoX, infoY-boundsFirstLine.Dy()-MARGIN)
+	}
+}
+
+// Draws the birth and survival rules as two rows of boxes, one for each neighbour count, which are lit up for the
+// counts in the rules. The rules being edited are framed. The grid is drawn with its lower left corner at (x, y).
+func (ui *UI) drawRuleGrid(screen *ebiten.Image, x, y int) {
+	h := ui.fontFace.Metrics().Height.Round()
+	box, gap := h, h/3
+	labelWidth := text.BoundString(ui.fontFace, "S").Dx() + 2*gap
+	boxesX := x + labelWidth
+
+	rows := []struct {
+		label string
+		rules *Ruleset
+	}{{"B", &ui.selectedBRules}, {"S", &ui.selectedSRules}}
+
+	// The neighbour counts go above the boxes.
+	top := y - len(rows)*(box+gap) - h
+	for n := 0; n <= 8; n++ {
+		digitWidth := text.BoundString(ui.fontFace, strconv.Itoa(n)).Dx()
+		drawTextWithShadow(screen, strconv.Itoa(n), ui.fontFace, boxesX+n*(box+gap)+(box-digitWidth)/2, top+h*3/4)
+	}
+
+	for i, row := range rows {
+		rowY := top + h + i*(box+gap)
+		if row.rules == ui.rulesBeingChanged {
+			frame := image.Rect(boxesX-gap/2, rowY-gap/2, boxesX+9*(box+gap)-gap/2, rowY+box+gap/2)
+			screen.SubImage(frame).(*ebiten.Image).Fill(RULE_GRID_FRAME_COLOR)
+		}
+		drawTextWithShadow(screen, row.label, ui.fontFace, x, rowY+box*3/4)
+
+		for n := 0; n <= 8; n++ {
+			c := RULE_GRID_OFF_COLOR
+			if row.rules[n] {
+				c = RULE_GRID_ON_COLOR
+			}
+			boxX := boxesX + n*(box+gap)
+			screen.SubImage(image.Rect(boxX, rowY, boxX+box, rowY+box)).(*ebiten.Image).Fill(c)
+		}
 	}
 }
 
@@ -309,15 +654,35 @@ func drawTextUpperLeft(dst *ebiten.Image, str string, face font.Face) {
 	drawTextWithShadow(dst, str, face, textX, textY)
 }
 
-func drawTextUpperRight(dst *ebiten.Image, str string, face font.Face) {
-	bounds := text.BoundString(face, str)
+// Draws each line right-aligned in the upper right corner, one below the other.
+func drawLinesUpperRight(dst *ebiten.Image, lines []string, face font.Face) {
+	if len(lines) == 0 {
+		return
+	}
 
+	h := face.Metrics().Height.Round()
 	screenX, _ := dst.Size()
+	textY := text.BoundString(face, lines[0]).Dy() + MARGIN
+	for i, line := range lines {
+		bounds := text.BoundString(face, line)
+		textX := screenX - bounds.Dx() - MARGIN
+		drawTextWithShadow(dst, line, face, textX, textY+i*h)
+	}
+}
+
+func drawTextLowerLeft(dst *ebiten.Image, str string, face font.Face) {
+	_, screenY := dst.Size()
+	drawTextWithShadow(dst, str, face, MARGIN, screenY-MARGIN)
+}
+
+func drawTextLowerRight(dst *ebiten.Image, str string, face font.Face) {
+	bounds := text.BoundString(face, str)
+
+	screenX, screenY := dst.Size()
 	textX := screenX - bounds.Dx() - MARGIN
-	textY := bounds.Dy() + MARGIN
+	textY := screenY - MARGIN
 
 	drawTextWithShadow(dst, str, face, textX, textY)
-
 }
 
 func (ui *UI) getScaleFactor() int {
@@ -330,8 +695,55 @@ func drawTextWithShadow(dst *ebiten.Image, str string, face font.Face, x, y int)
 	text.Draw(dst, str, face, x, y, color.White)
 }
 
+// Returns the number of board updates per game update, which can be fractional when running slowed down. With
+// automatic speed on, this is the selected speed sped up or slowed down to suit the activity on the board.
 func (ui *UI) getSpeedup() float64 {
-	return math.Pow(2, float64(ui.speed))
+	res := math.Pow(2, float64(ui.speed))
+	if ui.exactSpeed > 0 {
+		res = ui.exactSpeed / float64(ebiten.TPS())
+	}
+	if ui.isAutoSpeed {
+		res *= ui.autoSpeedFactor
+	}
+	return res
+}
+
+// Returns the number of board updates actually run per game update: the current speedup, capped at
+// MAX_GENERATIONS_PER_FRAME so that the game stays responsive at extreme speeds.
+func (ui *UI) cappedSpeedup() float64 {
+	if MAX_GENERATIONS_PER_FRAME > 0 {
+		return math.Min(ui.currentSpeedup, float64(MAX_GENERATIONS_PER_FRAME))
+	}
+	return ui.currentSpeedup
+}
+
+// Moves the current speedup towards the selected one. Called once per game update.
+func (ui *UI) updateCurrentSpeedup() {
+	target := ui.getSpeedup()
+	if SPEED_RAMP <= 0 || ui.currentSpeedup <= 0 {
+		ui.currentSpeedup = target
+		return
+	}
+
+	// Speeds are picked in powers of two, so ramp in log space to make each doubling take equally long. Every update
+	// covers the same fraction of the remaining distance, which gets about 95% of the way there in SPEED_RAMP.
+	fraction := 1 - math.Exp(-3/(SPEED_RAMP.Seconds()*float64(ebiten.TPS())))
+	current := math.Log2(ui.currentSpeedup)
+	current += (math.Log2(target) - current) * fraction
+	ui.currentSpeedup = math.Pow(2, current)
+
+	// Snap to the target once close enough, so that the speed settles exactly.
+	if math.Abs(ui.currentSpeedup/target-1) < 0.01 {
+		ui.currentSpeedup = target
+	}
+}
+
+// Returns "on" or "off" for displaying the state of a toggle in the UI.
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
 }
 
 func intMin(a, b int) int {
@@ -341,6 +753,20 @@ func intMin(a, b int) int {
 	return b
 }
 
+func intMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
 func clamp[T int | float64](min, max, a T) T {
 	if a < min {
 		return min
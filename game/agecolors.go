@@ -0,0 +1,59 @@
+package game
+
+import (
+	"image/color"
+	"math"
+)
+
+// The number of colours live cells fade through with age, one per generation, starting from the live colour. Cells
+// older than that keep the last colour.
+const AGE_COLOR_STEPS = 64
+
+// The index in colors of the colour of a newborn cell when colouring by age. A cell which has survived n generations
+// has the colour after it, up to AGE_COLOR_STEPS-1 after.
+const FIRST_AGE_COLOR = 3
+
+// Fills the age colours in colors, fading from young for newborn cells to old for cells which have survived
+// AGE_COLOR_STEPS-1 generations or more.
+func fillAgeColors(young, old color.RGBA) {
+	for i := 0; i < AGE_COLOR_STEPS; i++ {
+		mix := func(a, b uint8) byte {
+			return byte((int(a)*(AGE_COLOR_STEPS-1-i) + int(b)*i) / (AGE_COLOR_STEPS - 1))
+		}
+		colors[FIRST_AGE_COLOR+i] = []byte{mix(young.R, old.R), mix(young.G, old.G), mix(young.B, old.B), 255}
+	}
+}
+
+// Sets whether live cells are coloured by the number of generations they've survived, redrawing them to match. Ages
+// are only counted while they're needed, so unless a lifespan is set they start from 0 when colouring is turned on.
+func (s *Simulation) SetAgeColored(ageColored bool) {
+	if ageColored && !s.isAgeColored && s.maxLifespan == 0 {
+		for i := range s.age {
+			s.age[i] = 0
+		}
+	}
+	s.isAgeColored = ageColored
+	for i := 1; i <= s.gridY; i++ {
+		for j := 1; j <= s.gridX; j++ {
+			if ind := i*(s.gridX+2) + j; s.worldGrid[ind]&1 == 1 {
+				setPixel(s.pixels, s.gridX, j-1, i-1, s.liveColor(ind))
+			}
+		}
+	}
+}
+
+// Returns the index in colors of the colour of the live cell at index ind, other than as a newborn in two-tone mode.
+func (s *Simulation) liveColor(ind int) int {
+	if !s.isAgeColored {
+		return 0
+	}
+	return FIRST_AGE_COLOR + intMin(int(s.age[ind]), AGE_COLOR_STEPS-1)
+}
+
+// Counts another generation survived by the cell at index ind. The count stops at the largest age there's room for,
+// which is also the longest lifespan.
+func (s *Simulation) growOlder(ind int) {
+	if s.age[ind] < math.MaxUint8 {
+		s.age[ind]++
+	}
+}
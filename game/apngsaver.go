@@ -0,0 +1,217 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"io"
+	"log"
+)
+
+// The 8 byte signature every PNG file starts with.
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// Saves frames of the simulation to an animated PNG. Unlike GIFs, APNGs aren't limited to a 256 color palette and are
+// compressed losslessly, so they're better suited for colourful renders. Go's image/png doesn't support animation, so
+// the APNG chunks are written by hand.
+type ApngSaver struct {
+	// The filename to which the ApngSaver will save the APNG file.
+	fileName string
+
+	// Full colour copies of each frame.
+	frames []*image.RGBA
+
+	// Whether the frames should be cropped to the bounding box of the live cells before writing the APNG.
+	crop bool
+
+	// Whether the APNG plays the frames forwards and then backwards, see boomerang.
+	boomerang bool
+}
+
+func newApngSaver(fileName string, crop bool) ApngSaver {
+	return ApngSaver{
+		fileName: fileName,
+		frames:   []*image.RGBA{},
+		crop:     crop,
+	}
+}
+
+func (as *ApngSaver) saveFrame(img image.Image) {
+	// Copy the image, since the simulation image is reused between frames.
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+
+	as.frames = append(as.frames, dst)
+}
+
+func (as *ApngSaver) writeToFile() {
+	if len(as.frames) == 0 {
+		return
+	}
+
+	frames := as.frames
+	if as.crop {
+		frames = cropRGBAToLiveCells(frames, CROP_MARGIN)
+	}
+	if as.boomerang {
+		frames = boomerang(frames)
+	}
+
+	f := createOutputFile(as.fileName)
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := encodeApng(w, frames, FRAME_DELAY); err != nil {
+		log.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Writes the frames as a looping APNG to w, showing each frame for delay hundredths of a second. All frames must have
+// the same size.
+func encodeApng(w io.Writer, frames []*image.RGBA, delay int) error {
+	width, height := frames[0].Bounds().Dx(), frames[0].Bounds().Dy()
+
+	if _, err := w.Write(pngSignature); err != nil {
+		return err
+	}
+
+	// 8 bit RGBA, default compression, filtering and no interlacing.
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(height))
+	ihdr[8] = 8
+	ihdr[9] = 6
+	if err := writePngChunk(w, "IHDR", ihdr); err != nil {
+		return err
+	}
+
+	// Number of frames, and 0 plays to loop forever.
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(frames)))
+	if err := writePngChunk(w, "acTL", actl); err != nil {
+		return err
+	}
+
+	// fcTL and fdAT chunks share one sequence number counter.
+	seq := uint32(0)
+	for i, frame := range frames {
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], seq)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(width))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(height))
+		// The x and y offsets stay 0, as does the dispose op (none) and blend op (source).
+		binary.BigEndian.PutUint16(fctl[20:], uint16(delay))
+		binary.BigEndian.PutUint16(fctl[22:], 100)
+		if err := writePngChunk(w, "fcTL", fctl); err != nil {
+			return err
+		}
+		seq++
+
+		data, err := compressRGBA(frame)
+		if err != nil {
+			return err
+		}
+
+		// The first frame is stored as regular image data so that viewers without APNG support still show something.
+		if i == 0 {
+			err = writePngChunk(w, "IDAT", data)
+		} else {
+			fdat := make([]byte, 4+len(data))
+			binary.BigEndian.PutUint32(fdat, seq)
+			copy(fdat[4:], data)
+			err = writePngChunk(w, "fdAT", fdat)
+			seq++
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return writePngChunk(w, "IEND", nil)
+}
+
+// Writes a PNG chunk: the data length, the chunk type, the data and a CRC of the type and data.
+func writePngChunk(w io.Writer, chunkType string, data []byte) error {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], chunkType)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	footer := binary.BigEndian.AppendUint32(nil, crc.Sum32())
+
+	for _, b := range [][]byte{header, data, footer} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the zlib compressed, filtered scanlines of an image. Every row uses the Sub filter, which turns the long runs
+// of identical cells typical of our boards into runs of zeroes that compress very well.
+func compressRGBA(img *image.RGBA) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+
+	b := img.Bounds()
+	rowLen := 4 * b.Dx()
+	filtered := make([]byte, 1+rowLen)
+	filtered[0] = 1 // Sub filter.
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):][:rowLen]
+		for i := 0; i < rowLen; i++ {
+			if i < 4 {
+				filtered[1+i] = row[i]
+			} else {
+				filtered[1+i] = row[i] - row[i-4]
+			}
+		}
+		if _, err := zw.Write(filtered); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Crops all frames to the bounding box of their live (non-black) cells, extended by margin pixels on each side. Works
+// like cropToLiveCells, but for full colour frames.
+func cropRGBAToLiveCells(frames []*image.RGBA, margin int) []*image.RGBA {
+	box := image.Rectangle{}
+	for _, frame := range frames {
+		b := frame.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				ind := frame.PixOffset(x, y)
+				if frame.Pix[ind] != 0 || frame.Pix[ind+1] != 0 || frame.Pix[ind+2] != 0 {
+					box = box.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+		}
+	}
+	if box.Empty() {
+		return frames
+	}
+	box = box.Inset(-margin).Intersect(frames[0].Bounds())
+
+	res := make([]*image.RGBA, len(frames))
+	for i, frame := range frames {
+		dst := image.NewRGBA(image.Rect(0, 0, box.Dx(), box.Dy()))
+		draw.Draw(dst, dst.Bounds(), frame, box.Min, draw.Src)
+		res[i] = dst
+	}
+	return res
+}
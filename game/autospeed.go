@@ -0,0 +1,37 @@
+package game
+
+const (
+	// The fraction of the board's cells changing per generation which runs at the selected speed when AUTO_SPEED is
+	// on. Quieter boards run faster than the selected speed and busier ones slower, in proportion.
+	AUTO_SPEED_ACTIVITY = 0.01
+
+	// The most the selected speed is sped up or slowed down by when AUTO_SPEED is on.
+	AUTO_SPEED_MAX_FACTOR = 16
+
+	// How much of the recent activity each generation's changes make up, so that a single busy or quiet generation
+	// doesn't jolt the speed.
+	AUTO_SPEED_SMOOTHING = 0.05
+)
+
+// Returns how much to multiply the selected speed by for a board where the given fraction of the cells change per
+// generation, between 1/AUTO_SPEED_MAX_FACTOR and AUTO_SPEED_MAX_FACTOR.
+func autoSpeedFactor(activity float64) float64 {
+	if activity <= 0 {
+		return AUTO_SPEED_MAX_FACTOR
+	}
+	return clamp(1.0/AUTO_SPEED_MAX_FACTOR, AUTO_SPEED_MAX_FACTOR, AUTO_SPEED_ACTIVITY/activity)
+}
+
+// Adds the births and deaths of the generation just computed to the recent activity, and sets the UI's automatic
+// speed factor to match. Called after every board update while automatic speed is on.
+func (g *Game) updateActivity() {
+	changed := float64(g.births+g.deaths) / float64(g.gridX*g.gridY)
+	g.activity += (changed - g.activity) * AUTO_SPEED_SMOOTHING
+	g.ui.autoSpeedFactor = autoSpeedFactor(g.activity)
+}
+
+// Starts measuring the activity afresh for a new board, at the activity which runs at the selected speed.
+func (g *Game) resetActivity() {
+	g.activity = AUTO_SPEED_ACTIVITY
+	g.ui.autoSpeedFactor = 1
+}
@@ -0,0 +1,97 @@
+package game
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	xdraw "golang.org/x/image/draw"
+)
+
+// Sets whether dead cells are drawn transparent so that BACKGROUND_IMAGE shows through them, and redraws the dead
+// cells of the board to match. Does nothing if no background image was given.
+func (g *Game) setBackgroundVisible(visible bool) {
+	if BACKGROUND_IMAGE == nil {
+		return
+	}
+	g.isBackgroundVisible = visible
+
+	colors[1][3] = 255
+	if visible {
+		colors[1][3] = 0
+	}
+	for y := 0; y < g.gridY; y++ {
+		for x := 0; x < g.gridX; x++ {
+			if g.worldGrid[(y+1)*(g.gridX+2)+x+1]&1 == 0 {
+				setPixel(g.pixels, g.gridX, x, y, 1)
+			}
+		}
+	}
+}
+
+// Draws the background image stretched over the board area of the screen.
+func (g *Game) drawBackground(screen *ebiten.Image) {
+	if g.backgroundImg == nil {
+		g.backgroundImg = ebiten.NewImageFromImage(BACKGROUND_IMAGE)
+	}
+
+	b := BACKGROUND_IMAGE.Bounds()
+	offsetX, offsetY := g.boardOffset()
+	options := &ebiten.DrawImageOptions{}
+	scale := g.drawScale()
+	options.GeoM.Scale(float64(g.gridX)*scale/float64(b.Dx()), float64(g.gridY)*scale/float64(b.Dy()))
+	options.GeoM.Translate(float64(offsetX), float64(offsetY))
+	options.Filter = ebiten.FilterLinear
+	screen.DrawImage(g.backgroundImg, options)
+}
+
+// Returns the board composited over the background image at board resolution, for saving in GIF recordings. GIF
+// transparency is all-or-nothing and players show transparent pixels in all sorts of ways, so the recording gets the
+// flattened image instead of the transparent board.
+func (g *Game) flattenedFrame() *image.RGBA {
+	bounds := image.Rect(0, 0, g.gridX, g.gridY)
+
+	// The background only needs scaling again when the board size has changed.
+	if g.smallBackground == nil || g.smallBackground.Bounds() != bounds {
+		g.smallBackground = image.NewRGBA(bounds)
+		xdraw.ApproxBiLinear.Scale(g.smallBackground, bounds, BACKGROUND_IMAGE, BACKGROUND_IMAGE.Bounds(), draw.Src, nil)
+	}
+
+	res := image.NewRGBA(bounds)
+	copy(res.Pix, g.smallBackground.Pix)
+	board := &image.RGBA{Pix: g.boardPixels(), Stride: 4 * g.gridX, Rect: bounds}
+	draw.Draw(res, bounds, board, image.Point{}, draw.Over)
+	return res
+}
+
+// Whether recorded frames are flattened against the background. APNGs handle transparency well, so they keep the
+// transparent dead cells instead.
+func (g *Game) isRecordingFlattened() bool {
+	return g.isBackgroundVisible && RECORD_FORMAT != "apng"
+}
+
+// Returns the palette GIF recordings should use. Boards only have the dead and live colours (and the born colour in
+// two-tone mode and the age colours when coloured by age), but flattened frames need the colours of the background
+// too, and the neighbour count field and the fading trails have colours of their own.
+func (g *Game) recordingPalette() color.Palette {
+	if g.isRecordingFlattened() || g.isFieldVisible || g.isTrailVisible {
+		return palette.Plan9
+	}
+	if g.layer != nil {
+		return layerPalette()
+	}
+	res := color.Palette{color.Black}
+	for _, i := range []int{0, 2} {
+		if i == 0 || g.isTwoTone {
+			res = append(res, color.RGBA{colors[i][0], colors[i][1], colors[i][2], 255})
+		}
+	}
+	if g.isAgeColored {
+		for _, c := range colors[FIRST_AGE_COLOR:] {
+			res = append(res, color.RGBA{c[0], c[1], c[2], 255})
+		}
+	}
+	return res
+}
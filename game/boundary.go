@@ -0,0 +1,117 @@
+package game
+
+import "fmt"
+
+// How cells at the edge of the board see the cells beyond it.
+type BoundaryMode int
+
+const (
+	// Everything beyond the edge is dead.
+	BOUNDARY_DEAD BoundaryMode = iota
+
+	// The board wraps around, so the cells beyond the right edge are the leftmost cells and so on.
+	BOUNDARY_WRAP
+
+	// The edge acts as a mirror, so the cells just beyond the edge are copies of the edge cells themselves. Patterns
+	// "bounce" off the walls.
+	BOUNDARY_REFLECT
+
+	// The number of boundary modes, for cycling through them.
+	NUM_BOUNDARY_MODES
+)
+
+func (m BoundaryMode) String() string {
+	switch m {
+	case BOUNDARY_WRAP:
+		return "wrap"
+	case BOUNDARY_REFLECT:
+		return "reflect"
+	default:
+		return "dead"
+	}
+}
+
+// Parses a boundary mode from its name, as returned by String.
+func ParseBoundaryMode(s string) (BoundaryMode, error) {
+	for m := BoundaryMode(0); m < NUM_BOUNDARY_MODES; m++ {
+		if m.String() == s {
+			return m, nil
+		}
+	}
+	return BOUNDARY_DEAD, fmt.Errorf("unknown boundary mode %q, should be dead, wrap or reflect", s)
+}
+
+// Sets how the cells at the edge of the board see the cells beyond it.
+func (s *Simulation) SetBoundaryMode(m BoundaryMode) {
+	s.boundaryMode = m
+	s.fixEdgeCounts()
+}
+
+// Returns whether the cell at (x, y) is alive. The coordinates may be outside the board, in which case the boundary
+// mode decides which cell is seen there.
+func (s *Simulation) isAliveWithBoundary(x, y int) bool {
+	switch s.boundaryMode {
+	case BOUNDARY_WRAP:
+		x = (x%s.gridX + s.gridX) % s.gridX
+		y = (y%s.gridY + s.gridY) % s.gridY
+	case BOUNDARY_REFLECT:
+		x = clamp(0, s.gridX-1, mirror(x, s.gridX))
+		y = clamp(0, s.gridY-1, mirror(y, s.gridY))
+	default:
+		if x < 0 || x >= s.gridX || y < 0 || y >= s.gridY {
+			return false
+		}
+	}
+	return s.worldGrid[(y+1)*(s.gridX+2)+x+1]&1 == 1
+}
+
+// The incremental neighbour counting in updateRange treats the border around the board as dead cells which never
+// change. For the other boundary modes this makes the neighbour counts of the cells along the edges wrong, so after
+// every update (and any other change to the board) we recount the neighbours of the edge cells from scratch. This
+// only touches the cells along the edges, which is cheap compared to the update itself.
+//
+// The recount adds 2 for each live neighbour as usual, with the neighbours beyond the edge found by
+// isAliveWithBoundary rather than read from the border, which stays dead. Corners need no special case: with the
+// reflect boundary a corner cell's diagonal neighbour beyond the board is mirrored in both coordinates, back onto the
+// corner cell itself, so a lone live corner cell sees three copies of itself and has a value of 1+2*3.
+func (s *Simulation) fixEdgeCounts() {
+	if s.boundaryMode == BOUNDARY_DEAD {
+		return
+	}
+
+	for x := 0; x < s.gridX; x++ {
+		s.recountNeighbours(x, 0)
+		s.recountNeighbours(x, s.gridY-1)
+	}
+	for y := 1; y < s.gridY-1; y++ {
+		s.recountNeighbours(0, y)
+		s.recountNeighbours(s.gridX-1, y)
+	}
+}
+
+// Sets the value of the cell at (x, y) to twice its number of live neighbours plus its own state, taking the
+// boundary mode into account.
+func (s *Simulation) recountNeighbours(x, y int) {
+	ind := (y+1)*(s.gridX+2) + x + 1
+	val := s.worldGrid[ind] & 1
+	for a := -1; a <= 1; a++ {
+		for b := -1; b <= 1; b++ {
+			if (a != 0 || b != 0) && s.isAliveWithBoundary(x+b, y+a) {
+				val += 2
+			}
+		}
+	}
+	s.worldGrid[ind] = val
+}
+
+// Mirrors a coordinate which is past either end of a board of the given size back onto it, so that -1 maps to 0, -2
+// to 1 and so on.
+func mirror(x, size int) int {
+	if x < 0 {
+		return -x - 1
+	}
+	if x >= size {
+		return 2*size - x - 1
+	}
+	return x
+}
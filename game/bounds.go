@@ -0,0 +1,71 @@
+package game
+
+// A rectangle of cells, given by its first and last columns and rows (inclusive and 1-indexed, as in worldGrid). A
+// rectangle with minX > maxX or minY > maxY holds no cells.
+type cellRect struct {
+	minX, minY, maxX, maxY int
+}
+
+func (r cellRect) isEmpty() bool {
+	return r.minX > r.maxX || r.minY > r.maxY
+}
+
+// Returns the rectangle grown by n cells on every side, cut down to a board of the given size.
+func (r cellRect) grow(n, gridX, gridY int) cellRect {
+	return cellRect{intMax(1, r.minX-n), intMax(1, r.minY-n), intMin(gridX, r.maxX+n), intMin(gridY, r.maxY+n)}
+}
+
+// Returns the rectangle grown just enough to hold the cell at (x, y).
+func (r cellRect) including(x, y int) cellRect {
+	if r.isEmpty() {
+		return cellRect{x, y, x, y}
+	}
+	return cellRect{intMin(r.minX, x), intMin(r.minY, y), intMax(r.maxX, x), intMax(r.maxY, y)}
+}
+
+// Sets whether each generation only updates the cells around the live cells rather than the whole board. This is
+// much faster for a few small patterns on a big board, and gives the same results.
+func (s *Simulation) SetBounded(bounded bool) {
+	s.isBounded = bounded
+}
+
+// Returns the cells which need updating this generation. With bounded updates these are the cells next to the
+// bounding box of the live cells, since under the usual neighbourhood no other cell can change. The box grows by at
+// most a cell on each side per generation, and is found again from the updated cells after every generation, so it
+// shrinks back as soon as the cells at its edges die.
+func (s *Simulation) updateRegion() cellRect {
+	whole := cellRect{1, 1, s.gridX, s.gridY}
+	// Under B0 rules dead cells with no live neighbours are born too, and dying cells change without being alive.
+	if !s.isBounded || s.hasBirthFromNothing() || s.states > 2 {
+		return whole
+	}
+
+	if !s.isLiveBoundsKnown {
+		s.liveBounds = s.findLiveBounds(whole)
+		s.isLiveBoundsKnown = true
+	}
+	if s.liveBounds.isEmpty() {
+		return s.liveBounds
+	}
+
+	r := s.liveBounds.grow(1, s.gridX, s.gridY)
+	// With the other boundary modes the cells along an edge see the cells beyond it, which for a wrapping board are
+	// at the far side, so a box touching an edge is no help.
+	if s.boundaryMode != BOUNDARY_DEAD && (r.minX == 1 || r.minY == 1 || r.maxX == s.gridX || r.maxY == s.gridY) {
+		return whole
+	}
+	return r
+}
+
+// Returns the bounding box of the live cells within the given rectangle, which is empty if there are none.
+func (s *Simulation) findLiveBounds(r cellRect) cellRect {
+	res := cellRect{1, 1, 0, 0}
+	for i := r.minY; i <= r.maxY; i++ {
+		for j := r.minX; j <= r.maxX; j++ {
+			if s.worldGrid[i*(s.gridX+2)+j]&1 == 1 {
+				res = res.including(j, i)
+			}
+		}
+	}
+	return res
+}
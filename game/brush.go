@@ -0,0 +1,152 @@
+package game
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Airbrushes random live cells around the cursor while the left mouse button is held. Each cell within BRUSH_RADIUS
+// cells of the cursor comes alive with a chance of BRUSH_DENSITY percent. The chance is rolled once per cell per
+// stroke, so that going over the same spot again doesn't keep filling it in. With CTRL held, every cell under the
+// brush is made immortal instead, or mortal again with SHIFT held too. With ALT held the left button draws instead, see
+// handleDrawing.
+func (g *Game) handleBrush() {
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) || ebiten.IsKeyPressed(ebiten.KeyAlt) {
+		g.brushStroke = nil
+		return
+	}
+	if g.brushStroke == nil {
+		g.brushStroke = map[int]bool{}
+	}
+
+	cx, cy, ok := g.screenToCell(ebiten.CursorPosition())
+	if !ok {
+		return
+	}
+
+	isPaintingImmortal := ebiten.IsKeyPressed(ebiten.KeyControl)
+	painted := 0
+	for y := cy - BRUSH_RADIUS; y <= cy+BRUSH_RADIUS; y++ {
+		for x := cx - BRUSH_RADIUS; x <= cx+BRUSH_RADIUS; x++ {
+			dx, dy := x-cx, y-cy
+			if !g.isOnBoard(x, y) || dx*dx+dy*dy > BRUSH_RADIUS*BRUSH_RADIUS {
+				continue
+			}
+
+			if isPaintingImmortal {
+				g.paintImmortal(x, y, !ebiten.IsKeyPressed(ebiten.KeyShift))
+				painted++
+				continue
+			}
+
+			ind := (y+1)*(g.gridX+2) + x + 1
+			if g.brushStroke[ind] {
+				continue
+			}
+			g.brushStroke[ind] = true
+
+			if !g.Get(x, y) && r.Float64()*100 < BRUSH_DENSITY {
+				g.paintCell(x, y, true)
+				painted++
+			}
+		}
+	}
+
+	if painted > 0 {
+		g.finishPainting()
+	}
+}
+
+// Draws live cells under the cursor while ALT and the left mouse button are held, or erases them with the right button.
+// The cursor can move several cells between frames, so the cells on the line from where it was on the last frame are
+// drawn too, keeping fast strokes unbroken.
+func (g *Game) handleDrawing() {
+	isDrawing := ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+	isErasing := ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight)
+	cx, cy, ok := g.screenToCell(ebiten.CursorPosition())
+	if !ebiten.IsKeyPressed(ebiten.KeyAlt) || isDrawing == isErasing || !ok {
+		g.drawFrom = nil
+		return
+	}
+
+	to := image.Pt(cx, cy)
+	from := to
+	if g.drawFrom != nil {
+		from = *g.drawFrom
+	}
+	for _, p := range cellsOnLine(from, to) {
+		g.paintCell(p.X, p.Y, isDrawing)
+	}
+	g.finishPainting()
+	g.drawFrom = &to
+}
+
+// Returns the cells on the line from one cell to another, both included, as drawn by Bresenham's algorithm. Each cell
+// touches the one before it, at least diagonally.
+func cellsOnLine(from, to image.Point) []image.Point {
+	dx, dy := abs(to.X-from.X), -abs(to.Y-from.Y)
+	stepX, stepY := 1, 1
+	if to.X < from.X {
+		stepX = -1
+	}
+	if to.Y < from.Y {
+		stepY = -1
+	}
+
+	res := []image.Point{from}
+	p, err := from, dx+dy
+	for p != to {
+		if 2*err >= dy {
+			err += dy
+			p.X += stepX
+		}
+		if 2*err <= dx {
+			err += dx
+			p.Y += stepY
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
+// Sets a cell alive or dead by hand, on the layer too if there is one. Call finishPainting once done painting cells.
+func (g *Game) paintCell(x, y int, alive bool) {
+	if g.Get(x, y) == alive {
+		return
+	}
+	g.setCell(x, y, alive)
+	if g.layer != nil {
+		g.layer.setCell(x, y, alive)
+	}
+
+	// The population in the stats is tracked from the births and deaths, which don't include painted cells.
+	if alive {
+		g.population++
+	} else {
+		g.population--
+	}
+}
+
+// Makes a cell immortal, bringing it to life if it's dead, or mortal again, on the layer too if there is one. Call
+// finishPainting once done painting cells.
+func (g *Game) paintImmortal(x, y int, immortal bool) {
+	if immortal && !g.Get(x, y) {
+		g.population++
+	}
+	g.SetImmortal(x, y, immortal)
+	if g.layer != nil {
+		g.layer.SetImmortal(x, y, immortal)
+	}
+}
+
+// Brings the neighbour counts up to date after painting cells with paintCell, since setCell only maintains the 8
+// cell neighbourhood.
+func (g *Game) finishPainting() {
+	if g.mask != nil {
+		g.recountMasked()
+		if g.layer != nil {
+			g.layer.recountMasked()
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"math"
+	"strings"
+)
+
+// Captures GIFs of sudden population changes without recording everything: the last CAPTURE_BEFORE generations are
+// always kept, and when the population changes by at least CAPTURE_SPIKE_PERCENT percent in one generation, they're
+// saved together with the next CAPTURE_AFTER generations. Each generation is one frame, however fast the game runs.
+type eventCapture struct {
+	// The frames of the last generations, as a ring buffer starting at start.
+	ring  []*image.Paletted
+	start int
+
+	// The frames of the capture in progress and how many generations it still needs, or nil if there is none.
+	frames    []*image.Paletted
+	afterLeft int
+
+	// The population in the last generation, or -1 at the start of a board.
+	population int
+}
+
+// Starts capturing afresh for a new board.
+func (g *Game) resetCapture() {
+	g.capture = eventCapture{population: -1}
+}
+
+// Captures the generation the board is at, and saves a capture if it's complete. Called after every board update
+// when CAPTURE_SPIKE_PERCENT is set.
+func (g *Game) captureGeneration() {
+	c := &g.capture
+	frame, population := g.captureFrame()
+
+	if c.frames != nil {
+		c.frames = append(c.frames, frame)
+		c.afterLeft--
+		if c.afterLeft <= 0 {
+			g.saveCapture()
+		}
+	} else {
+		// The ring keeps the trigger generation too, so that it's in the middle of the capture.
+		if len(c.ring) < CAPTURE_BEFORE+1 {
+			c.ring = append(c.ring, frame)
+		} else {
+			c.ring[c.start] = frame
+			c.start = (c.start + 1) % len(c.ring)
+		}
+
+		if c.population > 0 && math.Abs(float64(population-c.population)) >= CAPTURE_SPIKE_PERCENT/100*float64(c.population) {
+			log.Printf("generation %v: population went from %v to %v, capturing", g.generation, c.population, population)
+			c.frames = append(append([]*image.Paletted{}, c.ring[c.start:]...), c.ring[:c.start]...)
+			c.afterLeft = CAPTURE_AFTER
+			c.ring, c.start = nil, 0
+			if c.afterLeft == 0 {
+				g.saveCapture()
+			}
+		}
+	}
+	c.population = population
+}
+
+// Returns the board as a frame in the live and dead colours, along with the number of live cells.
+func (g *Game) captureFrame() (*image.Paletted, int) {
+	alive := color.RGBA{colors[0][0], colors[0][1], colors[0][2], 255}
+	frame := image.NewPaletted(image.Rect(0, 0, g.gridX, g.gridY), color.Palette{color.Black, alive})
+	population := 0
+	for y := 0; y < g.gridY; y++ {
+		for x := 0; x < g.gridX; x++ {
+			if g.worldGrid[(y+1)*(g.gridX+2)+x+1]&1 == 1 {
+				frame.Pix[y*frame.Stride+x] = 1
+				population++
+			}
+		}
+	}
+	return frame, population
+}
+
+// Writes the capture in progress to a GIF on another goroutine, so the game doesn't freeze. Close waits for it.
+func (g *Game) saveCapture() {
+	c := &g.capture
+	saver := GifSaver{
+		fileName: strings.TrimSuffix(recordingFileName(g.bRules, g.sRules, "gif"), ".gif") +
+			fmt.Sprintf("_gen%v.gif", g.generation-CAPTURE_AFTER),
+		palette: c.frames[0].Palette,
+		frames:  c.frames,
+		delays:  make([]int, len(c.frames)),
+	}
+	for i := range saver.delays {
+		saver.delays[i] = FRAME_DELAY
+	}
+	c.frames = nil
+
+	g.captureWrites.Add(1)
+	go func() {
+		defer g.captureWrites.Done()
+		saver.writeToFile()
+	}()
+}
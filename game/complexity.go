@@ -0,0 +1,50 @@
+package game
+
+import (
+	"fmt"
+	"math"
+)
+
+// The side of the square tiles the board is split into to measure its block entropy.
+const ENTROPY_TILE_SIZE = 3
+
+// Returns the block entropy of the board: the board is split into ENTROPY_TILE_SIZE by ENTROPY_TILE_SIZE tiles, and
+// the result is the Shannon entropy, in bits, of how often each arrangement of live cells shows up as a tile. An empty
+// or uniform board scores 0, and a board of random noise scores close to the maximum of ENTROPY_TILE_SIZE^2 bits.
+// Boards with a lot of structure score in between, and unlike the population this tells a rich board from a dull
+// one with as many live cells. Tiles which would stick out past the right or bottom edge are left out.
+func (s *Simulation) BlockEntropy() float64 {
+	counts := make([]int, 1<<(ENTROPY_TILE_SIZE*ENTROPY_TILE_SIZE))
+	numTiles := 0
+	for y := 1; y+ENTROPY_TILE_SIZE-1 <= s.gridY; y += ENTROPY_TILE_SIZE {
+		for x := 1; x+ENTROPY_TILE_SIZE-1 <= s.gridX; x += ENTROPY_TILE_SIZE {
+			tile := 0
+			for dy := 0; dy < ENTROPY_TILE_SIZE; dy++ {
+				row := s.worldGrid[(y+dy)*(s.gridX+2)+x:]
+				for dx := 0; dx < ENTROPY_TILE_SIZE; dx++ {
+					tile = tile<<1 | int(row[dx]&1)
+				}
+			}
+			counts[tile]++
+			numTiles++
+		}
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(numTiles)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+// Shows the block entropy of the board as it is now in the upper right corner, or hides it if it's being shown.
+func (g *Game) toggleComplexity() {
+	if g.ui.complexityText != "" {
+		g.ui.complexityText = ""
+		return
+	}
+	g.ui.complexityText = fmt.Sprintf("block entropy %.3f bits at generation %v", g.BlockEntropy(), g.generation)
+}
@@ -0,0 +1,50 @@
+package game
+
+import (
+	_ "embed"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// How much the strength of the CRT effect changes with each CTRL+V press.
+const CRT_INTENSITY_STEP = 0.25
+
+// The Kage shader drawing the CRT effect, see the shader for what it does.
+//
+//go:embed assets/crt.kage
+var crtShaderSrc []byte
+
+// Returns the image the board is drawn to before the CRT effect is applied, sized like the screen and cleared. The
+// shader is compiled the first time it's needed.
+func (g *Game) crtTarget(screen *ebiten.Image) *ebiten.Image {
+	if g.crtShader == nil {
+		shader, err := ebiten.NewShader(crtShaderSrc)
+		if err != nil {
+			log.Fatalf("could not compile the CRT shader: %v", err)
+		}
+		g.crtShader = shader
+	}
+	if g.crtImg == nil || g.crtImg.Bounds() != screen.Bounds() {
+		g.crtImg = ebiten.NewImage(screen.Bounds().Dx(), screen.Bounds().Dy())
+	}
+	g.crtImg.Clear()
+	return g.crtImg
+}
+
+// Draws the board drawn to crtTarget onto the screen with scanlines and a glow. Only the screen gets the effect, never
+// recordings.
+func (g *Game) drawCRT(screen *ebiten.Image) {
+	options := &ebiten.DrawRectShaderOptions{}
+	options.Images[0] = g.crtImg
+	options.Uniforms = map[string]any{"Intensity": float32(g.crtIntensity)}
+	screen.DrawRectShader(g.crtImg.Bounds().Dx(), g.crtImg.Bounds().Dy(), g.crtShader, options)
+}
+
+// Steps the strength of the CRT effect up by CRT_INTENSITY_STEP, going back to the weakest after the strongest.
+func (g *Game) stepCRTIntensity() {
+	g.crtIntensity += CRT_INTENSITY_STEP
+	if g.crtIntensity > 1+1e-9 {
+		g.crtIntensity = CRT_INTENSITY_STEP
+	}
+}
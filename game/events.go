@@ -0,0 +1,179 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// How many game updates the border flashes for after an event.
+	CUE_FLASH_TICKS = 45
+
+	// The width in screen pixels of the flashing border.
+	CUE_BORDER_WIDTH = 8
+
+	// The longest period of oscillation the board is recognized as stable with.
+	STABLE_MAX_PERIOD = 4
+)
+
+// The colour of the flashing border.
+var CUE_COLOR = color.RGBA{255, 200, 0, 255}
+
+// A notable change in the board, which can be signalled with a cue so that long runs can be watched with less
+// attention.
+type BoardEvent int
+
+const (
+	// Every cell has died.
+	EVENT_EXTINCT BoardEvent = iota
+
+	// The board has stopped changing, or only cycles through up to STABLE_MAX_PERIOD states.
+	EVENT_STABLE
+
+	// The population has gone above or below CUE_POPULATION.
+	EVENT_POPULATION_THRESHOLD
+)
+
+func (e BoardEvent) String() string {
+	switch e {
+	case EVENT_EXTINCT:
+		return "board went extinct"
+	case EVENT_STABLE:
+		return "board stabilized"
+	default:
+		return fmt.Sprintf("population crossed %v", CUE_POPULATION)
+	}
+}
+
+// What we know about the last few generations, for detecting events.
+type eventTracker struct {
+	// The population in the last generation.
+	population int
+
+	// Hashes of the live cells in the last STABLE_MAX_PERIOD generations, most recent first.
+	hashes [STABLE_MAX_PERIOD]uint64
+
+	// The period the board repeats with once it's stable, 1 for a still board.
+	period int
+
+	// Whether the board was already extinct or stable, so that each event is only signalled once.
+	isExtinct bool
+	isStable  bool
+}
+
+// Checks the board for events after an update and signals any new ones with a cue. Called after every board update
+// when CUES_ENABLED or PAUSE_WHEN_STABLE is set, since it has to look at the whole board. The board is stable if it
+// matches any of the last STABLE_MAX_PERIOD generations, the closest match giving the period, which is found by
+// comparing hashes so that only one small history has to be kept.
+func (g *Game) detectEvents() {
+	population, hash := g.boardSummary()
+	t := &g.events
+
+	isExtinct := population == 0
+	if isExtinct && !t.isExtinct {
+		g.cue(EVENT_EXTINCT)
+	}
+
+	// An extinct board is trivially stable, which isn't worth a second cue.
+	period := 0
+	for i, h := range t.hashes {
+		if !isExtinct && h == hash {
+			period = i + 1
+			break
+		}
+	}
+	isStable := period > 0
+	if isStable && !t.isStable {
+		t.period = period
+		g.cue(EVENT_STABLE)
+		g.pauseWhenStable()
+	}
+
+	if CUE_POPULATION > 0 && (t.population < CUE_POPULATION) != (population < CUE_POPULATION) {
+		g.cue(EVENT_POPULATION_THRESHOLD)
+	}
+
+	t.population, t.isExtinct, t.isStable = population, isExtinct, isStable
+	copy(t.hashes[1:], t.hashes[:])
+	t.hashes[0] = hash
+}
+
+// Pauses the game on the board becoming stable if PAUSE_WHEN_STABLE is set, so that a run which has settled doesn't
+// carry on unwatched. Not while recording, since pausing stops the recording.
+func (g *Game) pauseWhenStable() {
+	if PAUSE_WHEN_STABLE && !g.isRecording() {
+		g.isPaused = true
+		g.isLiveEditing = false
+	}
+}
+
+// Starts tracking events from the current board, without signalling anything about it.
+func (g *Game) resetEvents() {
+	population, hash := g.boardSummary()
+	g.events = eventTracker{population: population, hashes: [STABLE_MAX_PERIOD]uint64{hash}, isExtinct: population == 0}
+}
+
+// Returns what the UI shows about the board being stable, which is nothing unless it's being checked for and is stable.
+func (g *Game) stableText() string {
+	if !(CUES_ENABLED || PAUSE_WHEN_STABLE) || !g.events.isStable {
+		return ""
+	}
+	return fmt.Sprintf("stable (period %v)", g.events.period)
+}
+
+// Signals an event by flashing a border around the screen and, where possible, a beep.
+func (g *Game) cue(e BoardEvent) {
+	if !CUES_ENABLED {
+		return
+	}
+	text := e.String()
+	if e == EVENT_STABLE {
+		text = fmt.Sprintf("%v (period %v)", text, g.events.period)
+	}
+	fmt.Printf("generation %v: %v\n", g.generation, text)
+	if BEEP_ENABLED {
+		fmt.Print("\a") // The terminal bell.
+	}
+	g.ui.cueText = text
+	g.ui.cueTicksLeft = CUE_FLASH_TICKS
+}
+
+// Returns the number of live cells and a hash of which cells are alive.
+func (s *Simulation) boardSummary() (int, uint64) {
+	population := 0
+	hash := uint64(14695981039346656037) // FNV-1a offset basis.
+	for y := 1; y <= s.gridY; y++ {
+		for x := 1; x <= s.gridX; x++ {
+			alive := s.worldGrid[y*(s.gridX+2)+x] & 1
+			population += int(alive)
+			hash = (hash ^ uint64(alive)) * 1099511628211
+		}
+	}
+	return population, hash
+}
+
+// Draws the flashing border and the text saying what happened, while a cue is showing.
+func (ui *UI) drawCue(screen *ebiten.Image) {
+	if ui.cueTicksLeft == 0 {
+		return
+	}
+	ui.cueTicksLeft--
+
+	// Flash on and off a few times.
+	if (ui.cueTicksLeft/8)%2 == 0 {
+		b := screen.Bounds()
+		w := CUE_BORDER_WIDTH
+		for _, r := range []image.Rectangle{
+			image.Rect(b.Min.X, b.Min.Y, b.Max.X, b.Min.Y+w),
+			image.Rect(b.Min.X, b.Max.Y-w, b.Max.X, b.Max.Y),
+			image.Rect(b.Min.X, b.Min.Y, b.Min.X+w, b.Max.Y),
+			image.Rect(b.Max.X-w, b.Min.Y, b.Max.X, b.Max.Y),
+		} {
+			screen.SubImage(r).(*ebiten.Image).Fill(CUE_COLOR)
+		}
+	}
+	drawTextLowerLeft(screen, ui.cueText, ui.fontFace)
+}
@@ -0,0 +1,49 @@
+package game
+
+import "image/color"
+
+// The colour map for the neighbour count field, from no live neighbours to every neighbour alive. The colours in
+// between are interpolated. Roughly matplotlib's inferno.
+var FIELD_COLORMAP = []color.RGBA{
+	{0, 0, 4, 255},
+	{87, 16, 110, 255},
+	{188, 55, 84, 255},
+	{249, 142, 9, 255},
+	{252, 255, 164, 255},
+}
+
+// Fills fieldPixels with the neighbour count of every cell mapped to a colour, showing how crowded each part of the
+// board is, dead regions included. The counts are maintained for the update anyway, so this is just a lookup per cell.
+func (g *Game) updateFieldPixels() {
+	if len(g.fieldPixels) != len(g.pixels) {
+		g.fieldPixels = make([]byte, len(g.pixels))
+	}
+
+	maxCount := 8
+	if g.mask != nil {
+		maxCount = len(g.mask)
+	}
+	colorsByCount := make([][4]byte, maxCount+1)
+	for n := range colorsByCount {
+		c := interpolateColormap(FIELD_COLORMAP, float64(n)/float64(maxCount))
+		colorsByCount[n] = [4]byte{c.R, c.G, c.B, 255}
+	}
+
+	for y := 0; y < g.gridY; y++ {
+		for x := 0; x < g.gridX; x++ {
+			n := int(g.worldGrid[(y+1)*(g.gridX+2)+x+1] >> 1)
+			ind := 4 * (y*g.gridX + x)
+			copy(g.fieldPixels[ind:ind+4], colorsByCount[clamp(0, maxCount, n)][:])
+		}
+	}
+}
+
+// Returns the colour at position t (0.0 to 1.0) along a colour map, interpolating linearly between its stops.
+func interpolateColormap(stops []color.RGBA, t float64) color.RGBA {
+	pos := clamp(0, 1, t) * float64(len(stops)-1)
+	i := intMin(int(pos), len(stops)-2)
+	f := pos - float64(i)
+	lerp := func(a, b uint8) uint8 { return uint8(float64(a) + f*(float64(b)-float64(a)) + 0.5) }
+	a, b := stops[i], stops[i+1]
+	return color.RGBA{lerp(a.R, b.R), lerp(a.G, b.G), lerp(a.B, b.B), 255}
+}
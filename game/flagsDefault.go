@@ -6,4 +6,7 @@ import "runtime"
 
 const SAVING_ENABLED = true
 
+// Whether cues can beep, which needs a terminal.
+const BEEP_ENABLED = true
+
 var POOL_SIZE int = runtime.NumCPU() * 2
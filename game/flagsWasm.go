@@ -4,4 +4,7 @@ package game
 
 const SAVING_ENABLED = false
 
+// Whether cues can beep, which needs a terminal.
+const BEEP_ENABLED = false
+
 var POOL_SIZE int = 1
@@ -1,20 +1,40 @@
 package game
 
 import (
+	"fmt"
+	"image"
 	"image/color"
+	"log"
+	"math"
 	"math/rand"
 	"sync"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
-// Random number source for game board initialization.
+// Random number source for the seeds game boards are initialized from.
 var r *rand.Rand
 
+// The size of the window, as last reported to Layout. Only used in windowed mode.
+var windowX, windowY int
+
+// Whether FullscreenSize is returning the fallback size, because the size of the monitor isn't known yet.
+var isScreenSizeFallback bool
+
 const (
+	// How many game updates the window size has to stay unchanged before the board is resized to match it, so that
+	// dragging the window edge doesn't reallocate the board every frame.
+	RESIZE_DEBOUNCE_TICKS = 15
+
+	// The smallest monitor width or height which is taken as real, and the size used until a real one is reported.
+	MIN_SCREEN_SIZE                               = 64
+	FALLBACK_SCREEN_WIDTH, FALLBACK_SCREEN_HEIGHT = 1280, 720
+
 	// Seed for the random number source. r is seeded only once and is not reinitialized with the seed before every run, so
-	// the order in which simulation runs are started will affect their initial board states.
+	// the order in which simulation runs are started will affect their initial board states. r only picks the seed
+	// each board is randomized from, see InitializeBoard.
 	SEED = 0
 )
 
@@ -30,154 +50,349 @@ type Game struct {
 	// UI state, mostly for pause menu.
 	ui UI
 
-	// TODO: update this, it's not accurate anymore. In particular, cells no longer count themselves as neighbours.
-	// Grid state.
-	// Each uint8 value represents both the state of the cell at that position (dead or alive) and the number of living
-	// neighbours (from 0 to 9) of that cells.
-	//
-	// The last bit is 0 if the cell is dead and 1 if the cell is alive.
-	// The other bits (value>>1, i.e. all except the last) represent the number of living neighbours. This number of
-	// live neigbours INCLUDES that cell if it is alive, so a cell can have up to 9 live "neighbours". This lets us
-	// update the board state slightly more efficiently.
-	//
-	//
-	// The worldGrid slice is implicitly two dimensional. There is a one cell border around the grid filled with cells
-	// which are always dead. This allows us to skip index out of bounds checks.
-	//
-	// The grid size is dependent on the scale factor.
-	worldGrid    []int8
-	buffer       []int8
-	gridX, gridY int
+	// The board being simulated.
+	Simulation
 
 	// The image we draw to the screen during the draw step. Dead cells are black, live cells are white.
 	img *ebiten.Image
 
-	// The raw pixels of our image. Each image pixel is represented as 4 bytes in pixels (RGBA channels), so we must
-	// have len(pixels) = 4 * (img width) * (img height).
-	pixels []byte
-
 	// Semi-transparent image to cover and "dim" the simulation image when paused.
 	transparencyOverlay *ebiten.Image
 
-	// Game rules.
-	// A dead cell becomes alive iff bRules at the number of its living neighbours (out of 8) is true
-	// A living cell stays alive iff SRules at the number of its living neighbours (out of 8) is true
-	// These rules do NOT count a live cell as its own neighbour.
-	bRules Ruleset
-	sRules Ruleset
+	// The rules which were in use before the last rule change, so that the user can quickly switch back and forth
+	// between two rules.
+	prevBRules Ruleset
+	prevSRules Ruleset
 
-	becomesAliveTable [18]bool
-	becomesDeadTable  [18]bool
+	// The neighbourhood the rules count live cells in, which sets the mask. See mask.go.
+	neighbourhood Neighbourhood
 
 	// The degree to which the game is "zoomed in". For example, with a scale factor of 3, each game board cell is drawn
 	// as a 3x3 square on a fullscreen window. Note that each cell still corresponds to one pixel in pixels.
 	scaleFactor int
 
+	// How many times the view is zoomed in on the board with the mouse wheel, on top of the scale factor, and how far
+	// the board's top left corner is moved from where it's drawn unzoomed, in screen pixels. See view.go.
+	viewZoom     int
+	viewX, viewY float64
+
+	// Where the cursor was on the last frame while panning the view with the middle mouse button, or nil when not
+	// panning. See view.go.
+	panFrom *image.Point
+
 	// The percent (0.0 to 100.0) chance any given board cell will initialize as alive.
 	avgStartingLiveCellPercentage float64
 
+	// The seed the current board was randomized from, so that the run can be shared, and the seed chosen with UseSeed
+	// for the next board, if any.
+	boardSeed     int64
+	nextSeed      int64
+	isNextSeedSet bool
+
 	// When paused, the simulation doesn't run and a settings change UI is displayed.
 	isPaused bool
 
-	// Struct managing functionality related to saving frames of the simulation to a .gif file.
-	gifSaver GifSaver
-	isSaving bool
-
-	// Channel used to send tasks to worker pool.
+	// Whether cells are always drawn as whole squares of screen pixels, even if that means a fixed size board doesn't
+	// fit on the screen.
+	isPixelPerfect bool
+
+	// Whether the pause menu is shown while the simulation keeps running, with rule changes applied immediately.
+	isLiveEditing bool
+
+	// Whether dead cells are transparent and BACKGROUND_IMAGE is drawn behind the board, along with the background as
+	// an Ebiten image for drawing and scaled to the board size for flattening recorded frames. See background.go.
+	isBackgroundVisible bool
+	backgroundImg       *ebiten.Image
+	smallBackground     *image.RGBA
+
+	// The cells the current airbrush stroke has gone over, by index in worldGrid, or nil when not painting. See
+	// brush.go.
+	brushStroke map[int]bool
+
+	// The cell the cursor was on when the last cells were drawn with ALT held, or nil when not drawing. See brush.go.
+	drawFrom *image.Point
+
+	// The cell where the rectangle being selected with the mouse was started, or nil when not selecting. See
+	// selection.go.
+	selectionStart *image.Point
+
+	// Whether boxes are drawn around the spaceships on the board, the boxes for the generation they were found in, and
+	// which shapes are spaceships under the rules they were found with. See ships.go.
+	isShipHighlighting bool
+	ships              []image.Rectangle
+	shipsGeneration    int
+	isShipByShape      map[string]bool
+	shipRule           string
+
+	// A second board started from the same cells but running under LAYER_B_RULES and LAYER_S_RULES, drawn blended with
+	// the main one, or nil if there is none. See layers.go.
+	layer       *Simulation
+	layerPixels []byte
+
+	// Whether the neighbour count of every cell is drawn instead of the cells themselves, and the pixels for that. See
+	// field.go.
+	isFieldVisible bool
+	fieldPixels    []byte
+
+	// Whether dead cells show fading trails of the cells which recently died there, how much of a trail each cell has
+	// left, using the same indexing as pixels but with one value per cell, and the pixels with the trails drawn in. See
+	// trails.go.
+	isTrailVisible bool
+	trail          []uint8
+	trailPixels    []byte
+
+	// Whether the rules, seed and a QR code of the share string are drawn in a corner, and the QR code image with the
+	// share string it was made for. See share.go.
+	isShareOverlayVisible bool
+	shareQRImage          *ebiten.Image
+	shareQRString         string
+
+	// Whether GHOST_PATTERN is drawn over the board, its image, where its top left corner is on the board and whether
+	// it has been moved there by hand. See ghost.go.
+	isGhostVisible bool
+	ghostImg       *ebiten.Image
+	ghostX, ghostY int
+	isGhostMoved   bool
+
+	// Whether the board is drawn with the CRT effect and how strongly, and the shader and image used to draw it. See
+	// crt.go.
+	isCRTEnabled bool
+	crtIntensity float64
+	crtShader    *ebiten.Shader
+	crtImg       *ebiten.Image
+
+	// Struct managing functionality related to saving frames of the simulation to a .gif (or .png, for APNG) file.
+	gifSaver       GifSaverInterface
+	recordingState RecordingState
+
+	// Closed once the recording being written has been written. See recording.go.
+	writeDone chan struct{}
+
+	// The savers of the recordings in slots, by slot, the slot recordings still being written, and the number of
+	// recordings started in each slot, which numbers their files. See recording.go.
+	slotSavers map[int]GifSaverInterface
+	slotWrites sync.WaitGroup
+	slotTakes  map[int]int
+
+	// The last REWIND_GENERATIONS generations of the board, to step back through while paused. See rewind.go.
+	rewind rewindBuffer
+
+	// The number of frames captured so far by the main recording and by each slot recording, for MAX_RECORDING_FRAMES.
+	recordedFrames     int
+	slotRecordedFrames map[int]int
+
+	// Writes per generation stats to STATS_FILE if it's set, along with the population it keeps track of.
+	stats      *CSVWriter
+	population int
+
+	// The state of capturing GIFs of population spikes, and the captures still being written. See capture.go.
+	capture       eventCapture
+	captureWrites sync.WaitGroup
+
+	// Logs the rules of every board and every change to them to RULE_LOG_FILE if it's set. See rulelog.go.
+	ruleLog *CSVWriter
+
+	// The recent history of the board, for detecting events to cue. Only kept up to date when CUES_ENABLED or
+	// PAUSE_WHEN_STABLE is set.
+	events eventTracker
+
+	// Channel used to send tasks to worker pool. Nil once the workers have been stopped, so that no task can be sent
+	// after it's closed.
 	taskChannel chan Task
+	// Tracks the running workers, so that stopping them can wait for them to exit.
+	workers sync.WaitGroup
+
+	// When the game was started, for exiting after MAX_RUNTIME.
+	startTime time.Time
 
-	// WaitGroup used to wait until all tasks are done.
-	wg sync.WaitGroup
+	// When the current board was made or the pause menu was last shown, for restarting after IDLE_RESET.
+	lastActivityTime time.Time
 
-	// Keeps track of the update number we're to allow slowed down updates.
+	// Keeps track of the update number we're on.
 	updateCount int
+
+	// Game updates left until the board is resized to match the window, or 0 if no resize is pending.
+	resizeCountdown int
+
+	// Board updates which are owed but haven't been done yet, used to run at speeds which aren't a whole number of
+	// board updates per game update.
+	updateAccumulator float64
+
+	// The recent fraction of the cells changing per generation, which the speed follows when automatic speed is on.
+	activity float64
 }
 
-// Update board rows from minY to maxY inclusive.
-func (g *Game) updateRange(minY, maxY int) {
-	// Update the game board.
-	// We do this more efficiently by copying the board state into a buffer and modifying only those cells in the
-	// buffer which are changing state (becoming alive or dying).
-	for i := minY; i <= maxY; i++ {
-		for j := 1; j <= g.gridX; j++ {
-			// Getting the "2D g.worldGrid[i][j]" index from the 1D slice. +2 because of the board edge border.
-			val := g.worldGrid[i*(g.gridX+2)+j]
-			gridXPlusTwo := g.gridX + 2
-
-			if g.becomesAliveTable[val] { // Checking if the cell is becoming alive. val&1 == 0 ensures that
-				// this cell was dead previously, and val>>1 gets the number of live neighbours.
-
-				// g.buffer[ind] |= 1 // Set the last bit to 1 to indicate that this cell is now alive.
-				g.buffer[(i-1)*(gridXPlusTwo)+j-1] += 2
-				g.buffer[(i-1)*(gridXPlusTwo)+j] += 2
-				g.buffer[(i-1)*(gridXPlusTwo)+j+1] += 2
-				g.buffer[(i)*(gridXPlusTwo)+j-1] += 2
-				g.buffer[(i)*(gridXPlusTwo)+j] += 1
-				g.buffer[(i)*(gridXPlusTwo)+j+1] += 2
-				g.buffer[(i+1)*(gridXPlusTwo)+j-1] += 2
-				g.buffer[(i+1)*(gridXPlusTwo)+j] += 2
-				g.buffer[(i+1)*(gridXPlusTwo)+j+1] += 2
-				// -1 because i and j and 1-indexed due to the border, which the game board image doesn't have.
-				setPixel(g.pixels, g.gridX, j-1, i-1, 0)
-
-			} else if g.becomesDeadTable[val] { // Checking if the cell is becoming dead. val&1 == 1 ensures
-				// that this cell was alive previously. Since this cell is alive, val>>1 is the one more than the number
-				// of live neighbours, as this cell is also counted in val>1, so we check val>>1-1 in SRules.
-
-				// The rest of this case is analogous to the cell becoming alive case.
-				// g.buffer[ind] -= 1 // Set the last bit to 0 to indicate that this cell is now dead.
-				g.buffer[(i-1)*(gridXPlusTwo)+j-1] -= 2
-				g.buffer[(i-1)*(gridXPlusTwo)+j] -= 2
-				g.buffer[(i-1)*(gridXPlusTwo)+j+1] -= 2
-				g.buffer[(i)*(gridXPlusTwo)+j-1] -= 2
-				g.buffer[(i)*(gridXPlusTwo)+j] -= 1
-				g.buffer[(i)*(gridXPlusTwo)+j+1] -= 2
-				g.buffer[(i+1)*(gridXPlusTwo)+j-1] -= 2
-				g.buffer[(i+1)*(gridXPlusTwo)+j] -= 2
-				g.buffer[(i+1)*(gridXPlusTwo)+j+1] -= 2
-				setPixel(g.pixels, g.gridX, j-1, i-1, 1)
-			}
+func (g *Game) Update() error {
+	if MAX_RUNTIME > 0 && time.Since(g.startTime) >= MAX_RUNTIME {
+		return g.shutdown()
+	}
+
+	// Resize the board to the monitor once its real size is known, if it wasn't at the start.
+	if isScreenSizeFallback {
+		if x, y := ebiten.ScreenSizeInFullscreen(); x >= MIN_SCREEN_SIZE && y >= MIN_SCREEN_SIZE {
+			isScreenSizeFallback = false
+			g.resizeCountdown = RESIZE_DEBOUNCE_TICKS
 		}
 	}
 
-	g.wg.Done()
-}
+	// Resizing the board changes the size of the frames, so the resize waits until any recordings have stopped.
+	if g.resizeCountdown > 0 && !(g.resizeCountdown == 1 && g.isRecording()) {
+		g.resizeCountdown--
+		if g.resizeCountdown == 0 {
+			g.handleResize()
+		}
+	}
 
-func (g *Game) Update() error {
-	g.ui.handleInput(g.isPaused)
+	// Toggle live editing on L press, showing the pause menu while the simulation keeps running. Rule changes made in
+	// the menu then apply right away, to the running board. Pausing leaves live editing.
+	if inpututil.IsKeyJustPressed(ebiten.KeyL) && !g.isPaused {
+		g.isLiveEditing = !g.isLiveEditing
+	}
+
+	// Capture the board's block entropy on SHIFT+G press, or hide it if it's shown.
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) && ebiten.IsKeyPressed(ebiten.KeyShift) {
+		g.toggleComplexity()
+	}
+
+	g.handleWheelZoom()
+	g.handlePanning()
+
+	g.ui.handleInput(g.isMenuVisible())
+	g.pollRecording()
+
+	if g.isLiveEditing {
+		g.applySelectedRules()
+	}
+
+	// Switch between fullscreen and windowed mode on F11 press. The board is resized to the new screen size once the
+	// switch has gone through, the same way as when the window is resized.
+	if SAVING_ENABLED && inpututil.IsKeyJustPressed(ebiten.KeyF11) {
+		ebiten.SetFullscreen(!ebiten.IsFullscreen())
+		g.resizeCountdown = RESIZE_DEBOUNCE_TICKS
+	}
 
 	// Handle input not handled by the UI.
 	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
 		g.restart()
 	}
 
+	// Reroll the board on Q press: a fresh random board under the running settings, ignoring any changes made in the
+	// pause menu which haven't been applied with R yet.
+	if inpututil.IsKeyJustPressed(ebiten.KeyQ) {
+		g.InitializeBoard()
+	}
+
+	// Switch back to the previous rules on X press, keeping the current board unless SHIFT is held.
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) {
+		g.swapRules()
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			g.InitializeBoard()
+		}
+	}
+
+	// Toggle showing the background image through the dead cells on T press, and fading trails behind dying cells on
+	// SHIFT+T press. Not while recording, since the recording palette depends on both.
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) && !g.isRecording() {
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			g.setTrailVisible(!g.isTrailVisible)
+		} else {
+			g.setBackgroundVisible(!g.isBackgroundVisible)
+		}
+	}
+
+	// Toggle drawing just born cells in their own colour on N press, and colouring live cells by age on SHIFT+N press.
+	if inpututil.IsKeyJustPressed(ebiten.KeyN) && !g.isRecording() {
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			g.SetAgeColored(!g.isAgeColored)
+		} else {
+			g.SetTwoTone(!g.isTwoTone)
+		}
+	}
+
+	// Toggle the CRT effect on SHIFT+V press, and make it stronger on CTRL+V press.
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			g.isCRTEnabled = !g.isCRTEnabled
+		} else if ebiten.IsKeyPressed(ebiten.KeyControl) {
+			g.stepCRTIntensity()
+		}
+	}
+
+	// Toggle locking the scale to whole numbers on P press. SHIFT+P cycles through the presets in the pause menu.
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) && !ebiten.IsKeyPressed(ebiten.KeyShift) {
+		g.isPixelPerfect = !g.isPixelPerfect
+	}
+
+	// Toggle drawing the neighbour count field on K press.
+	if inpututil.IsKeyJustPressed(ebiten.KeyK) && !ebiten.IsKeyPressed(ebiten.KeyShift) && !g.isRecording() {
+		g.isFieldVisible = !g.isFieldVisible
+	}
+
+	// Toggle drawing the reference pattern ghost on H press, and move it to the cursor on J press.
+	if GHOST_PATTERN != nil && inpututil.IsKeyJustPressed(ebiten.KeyH) && !ebiten.IsKeyPressed(ebiten.KeyShift) {
+		g.isGhostVisible = !g.isGhostVisible
+	}
+	if GHOST_PATTERN != nil && inpututil.IsKeyJustPressed(ebiten.KeyJ) {
+		g.moveGhostToCursor()
+	}
+
+	// Toggle highlighting the spaceships on the board on SHIFT+H press.
+	if inpututil.IsKeyJustPressed(ebiten.KeyH) && ebiten.IsKeyPressed(ebiten.KeyShift) {
+		g.isShipHighlighting = !g.isShipHighlighting
+	}
+
+	// Toggle drawing the share overlay on O press.
+	if inpututil.IsKeyJustPressed(ebiten.KeyO) {
+		g.isShareOverlayVisible = !g.isShareOverlayVisible
+	}
+
+	// Print the transition tables on D press, for debugging rules.
+	if inpututil.IsKeyJustPressed(ebiten.KeyD) {
+		fmt.Print(g.DescribeTables())
+	}
+
+	// Start or stop the recording in slot n on CTRL+n while running, independently of the other recordings.
+	if SAVING_ENABLED && !g.isMenuVisible() && ebiten.IsKeyPressed(ebiten.KeyControl) {
+		for slot := 1; slot <= NUM_RECORDING_SLOTS; slot++ {
+			if inpututil.IsKeyJustPressed(ebiten.Key0 + ebiten.Key(slot)) {
+				g.toggleSlotRecording(slot)
+			}
+		}
+	}
+
+	// Save the board as it's shown to a PNG on F12 press, one pixel per cell, or at the scale it's drawn with SHIFT held.
+	if SAVING_ENABLED && inpututil.IsKeyJustPressed(ebiten.KeyF12) {
+		g.saveScreenshot(ebiten.IsKeyPressed(ebiten.KeyShift))
+	}
+
+	// Print a string describing the current run on E press, for sharing it. There's no clipboard access in Ebiten, so
+	// it goes to stdout, which is the browser console when running on the web.
+	if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+		fmt.Println(g.ShareString())
+	}
+
 	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
 		// After this frame, the user has entered/left the pause menu.
 		defer func() { g.isPaused = !g.isPaused }()
+		g.isLiveEditing = false
 
 		if SAVING_ENABLED {
-			// A SHIFT+SPACE press when paused, so we start saving.
-			if g.isPaused && ebiten.IsKeyPressed(ebiten.KeyShift) && !g.isSaving {
-				g.isSaving = true
-				g.ui.shouldDisplayRecordingText = true
-				g.gifSaver = newGifSaver(g.bRules, g.sRules)
-
-				// Return instead of doing an update step, since saving the frame happens in Draw() and so if we update
-				// before that we will skip one frame of the initial random board state.
-				return nil
+			// A SHIFT+SPACE press when paused, so we start saving. If the last recording is still being written, the UI
+			// says so and we just unpause.
+			if g.isPaused && ebiten.IsKeyPressed(ebiten.KeyShift) && g.recordingState != RECORDING_ACTIVE {
+				if g.recordingState == RECORDING_IDLE {
+					g.startRecording(newRecordingSaver(g.bRules, g.sRules, g.isCropping(), g.recordingPalette(), 0, 0))
+
+					// Return instead of doing an update step, since saving the frame happens in Draw() and so if we
+					// update before that we will skip one frame of the initial random board state.
+					return nil
+				}
+				g.ui.recordingBlockedTicks = RECORDING_BLOCKED_TICKS
 			}
 
 			// A SPACE press when not paused and saving, so we stop saving.
-			if !g.isPaused && g.isSaving {
-				g.isSaving = false
-				g.ui.shouldDisplayRecordingText = false
-				go func() {
-					// Write to file concurrently so as to not cause a freeze, as this can take a few seconds, and tell the
-					// UI to indicate that we're saving.
-					g.ui.shouldDisplayWritingToFileText = true
-					g.gifSaver.writeToFile()
-					g.ui.shouldDisplayWritingToFileText = false
-				}()
+			if !g.isPaused && g.recordingState == RECORDING_ACTIVE {
+				g.stopRecording()
 			}
 		}
 
@@ -185,94 +400,157 @@ func (g *Game) Update() error {
 		g.ui.shouldDisplaySlashScreen = false
 	}
 
+	if g.ui.isCursorInfoVisible {
+		g.ui.cursorInfoText = g.cursorInfo()
+	}
+
 	if g.isPaused {
-		if SAVING_ENABLED && inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-			return ebiten.Termination
+		g.lastActivityTime = time.Now()
+		g.handleBrush()
+		g.handleDrawing()
+		g.handleSelection()
+		g.handleRuleRegions()
+
+		// Turn the selection, or the whole board if nothing is being selected, clockwise on U press and anticlockwise on
+		// SHIFT+U.
+		if inpututil.IsKeyJustPressed(ebiten.KeyU) {
+			g.rotate(!ebiten.IsKeyPressed(ebiten.KeyShift))
 		}
-		return nil
-	}
 
-	// How we update depends on the speed we're running at, as set in the UI.
-	// If speed > 0 then we're doing speed-up, i.e. doing multiple board updates per game update.
-	// If speed < 0, we're slowing down and updating the board only every 1/2^speed game updates.
-	if g.ui.speed >= 0 {
-		for i := 0; i < int(g.ui.getSpeedup()); i++ {
-			g.updateBoard()
+		// Mirror the board left to right on Y press, or top to bottom on SHIFT+Y.
+		if inpututil.IsKeyJustPressed(ebiten.KeyY) {
+			if ebiten.IsKeyPressed(ebiten.KeyShift) {
+				g.flipBoard(FLIP_VERTICAL)
+			} else {
+				g.flipBoard(FLIP_HORIZONTAL)
+			}
 		}
-	} else {
-		if g.updateCount%int(1/g.ui.getSpeedup()) == 0 {
-			g.updateBoard()
+		// Advance the board by a single generation on SHIFT+. press, staying paused. This uses the running rules, not any
+		// rules edited in the menu which haven't been applied with R yet.
+		if inpututil.IsKeyJustPressed(ebiten.KeyPeriod) && ebiten.IsKeyPressed(ebiten.KeyShift) {
+			g.advance()
 		}
-	}
 
-	g.updateCount++
+		// Step back a generation on SHIFT+, press, as far back as the rewind buffer goes.
+		if inpututil.IsKeyJustPressed(ebiten.KeyComma) && ebiten.IsKeyPressed(ebiten.KeyShift) {
+			g.rewindGeneration()
+		}
 
-	return nil
-}
+		// Step the seed of the next board down on ; press and up on ' press. R restarts from it.
+		if inpututil.IsKeyJustPressed(ebiten.KeySemicolon) {
+			g.stepSeed(-1)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyQuote) {
+			g.stepSeed(1)
+		}
 
-// Update the game board. To do this efficiently we copy the board state into a buffer and modifying only those cells in
-//
-//	the buffer which are changing state (becoming alive or dying).
-var boardUpdates int = 0
+		// Switch between recording GIFs and APNGs on SHIFT+A press. Not while recording, since whether frames are
+		// flattened against the background depends on the format.
+		if SAVING_ENABLED && inpututil.IsKeyJustPressed(ebiten.KeyA) && ebiten.IsKeyPressed(ebiten.KeyShift) &&
+			!g.isRecording() {
+			if RECORD_FORMAT == "apng" {
+				RECORD_FORMAT = "gif"
+			} else {
+				RECORD_FORMAT = "apng"
+			}
+		}
 
-func (g *Game) updateBoard() error {
-	copy(g.buffer, g.worldGrid)
+		// Save the board to an RLE file on SHIFT+S press.
+		if SAVING_ENABLED && inpututil.IsKeyJustPressed(ebiten.KeyS) && ebiten.IsKeyPressed(ebiten.KeyShift) {
+			g.saveBoard()
+		}
 
-	// Divide the board into equal-sized parts and create tasks for each part.
-	numParts := POOL_SIZE
-	if g.gridY/numParts < 3 && g.gridY >= 3 { // Cap the number of parts on small boards.
-		numParts = g.gridY / 3
-	}
-	rowsPerPart := g.gridY / numParts
-	for i := 0; i < numParts; i++ {
-		minY := 1 + i*rowsPerPart
-		maxY := minY + rowsPerPart - 1
-		if i == numParts-1 {
-			maxY = g.gridY
+		if SAVING_ENABLED && inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			return g.shutdown()
 		}
+		return nil
+	}
 
-		g.wg.Add(1)
-		go g.updateRange(minY+1, maxY-1)
-		// We can't update the border regions of a part since that would lead to data races.
-		// g.taskChannel <- Task{minY: minY + 1, maxY: maxY - 1}
+	if g.isLiveEditing {
+		g.lastActivityTime = time.Now()
+	} else if IDLE_RESET > 0 && time.Since(g.lastActivityTime) >= IDLE_RESET && !g.isRecording() {
+		g.idleReset()
+	}
 
+	// How we update depends on the speed we're running at, as set in the UI.
+	// If the speedup is more than 1 then we're doing multiple board updates per game update. If it's less than 1 we're
+	// slowing down and only updating the board every few game updates. The accumulator keeps track of the fractional
+	// updates so that speeds which aren't a power of two also come out right on average. Past the cap on updates per
+	// game update, the updates are dropped rather than owed, so that the game doesn't fall further and further behind.
+	g.updateAccumulator += g.ui.cappedSpeedup()
+	// The board can pause itself on stabilizing, which stops the updates straight away.
+	for g.updateAccumulator >= 1 && !g.isPaused {
+		g.advance()
+		g.updateAccumulator--
 	}
-	g.wg.Wait()
 
-	// Update the border regions now that it's safe to do so.
-	g.wg.Add(2)
+	g.updateCount++
 
-	go g.updateRange(1, 1)
-	go g.updateRange(g.gridY, g.gridY)
-	for i := 1; i < numParts; i++ {
-		minY := 1 + i*rowsPerPart
+	return nil
+}
 
-		g.wg.Add(1)
-		go g.updateRange(minY-1, minY)
+// Runs the board, and the layer board if there is one, for one generation, along with everything which follows the
+// board from generation to generation.
+func (g *Game) advance() {
+	g.saveRewindSnapshot()
+	g.isCountingChanges = g.stats != nil || g.ui.isAutoSpeed
+	g.updateBoard()
+	if g.ui.isAutoSpeed {
+		g.updateActivity()
 	}
-	g.wg.Wait()
-
-	copy(g.worldGrid, g.buffer)
+	if g.layer != nil {
+		g.layer.updateBoard()
+	}
+	if CAPTURE_SPIKE_PERCENT > 0 {
+		g.captureGeneration()
+	}
+	if CUES_ENABLED || PAUSE_WHEN_STABLE {
+		g.detectEvents()
+	}
+	if g.stats != nil {
+		g.recordStats()
+	}
+}
 
-	boardUpdates++
+// Finishes any recording, waits for recordings to be written to file and stops the workers, then returns
+// ebiten.Termination so that the game exits.
+func (g *Game) shutdown() error {
+	g.finishRecording()
+	g.stopWorkers()
+	return ebiten.Termination
+}
 
-	return nil
+// Finishes writing any files the game has open. Called once the game has exited.
+func (g *Game) Close() {
+	g.finishRecording()
+	g.stopWorkers()
+	g.captureWrites.Wait()
+	if g.stats != nil {
+		g.stats.close()
+	}
+	if g.ruleLog != nil {
+		g.ruleLog.close()
+	}
 }
 
 func (g *Game) restart() {
-	// Change the rules, scale factor and initial live cell percentage to the ones selected in the UI.
-	g.bRules = g.ui.selectedBRules
-	g.sRules = g.ui.selectedSRules
-
+	// Change the rules, scale factor, initial live cell percentage, boundary mode, symmetry and neighbourhood to the
+	// ones selected in the UI. The transition tables are rebuilt even if the rules haven't changed, so that a restart
+	// always starts from tables matching the rules.
+	g.applySelectedRules()
 	g.updateTables()
 
 	g.scaleFactor = g.ui.getScaleFactor()
 	g.avgStartingLiveCellPercentage = g.ui.selectedLiveCellPercent
+	g.boundaryMode = g.ui.selectedBoundaryMode
+	g.symmetry = g.ui.selectedSymmetry
+	if g.neighbourhood != g.ui.selectedNeighbourhood {
+		g.neighbourhood = g.ui.selectedNeighbourhood
+		g.SetMask(g.neighbourhood.mask())
+	}
 
 	// Fix transparency overlay which could have been broken by a resize (if running in browser)
-	x, y := ebiten.ScreenSizeInFullscreen()
-	g.transparencyOverlay = ebiten.NewImage(x, y)
-	g.transparencyOverlay.Fill(color.RGBA{0, 0, 0, 255 * 3 / 4}) // black but not completely opaque
+	g.createTransparencyOverlay()
 
 	// Could be at new board res now so we need to generate possible zoom levels again
 	g.ui.initScaleFactors()
@@ -281,33 +559,164 @@ func (g *Game) restart() {
 	g.InitializeBoard()
 }
 
+// Switches to the rules selected in the UI, if they've changed, without touching the board.
+func (g *Game) applySelectedRules() {
+	if g.bRules == g.ui.selectedBRules && g.sRules == g.ui.selectedSRules {
+		return
+	}
+
+	// Remember the old rules so that we can switch back to them later.
+	g.prevBRules, g.prevSRules = g.bRules, g.sRules
+	g.bRules = g.ui.selectedBRules
+	g.sRules = g.ui.selectedSRules
+	g.updateTables()
+	g.logRules()
+}
+
+// Starts over with a fresh random board after IDLE_RESET, also switching to random rules if IDLE_RESET_RULES is set,
+// so that an unattended display doesn't sit on a dead board.
+func (g *Game) idleReset() {
+	if IDLE_RESET_RULES {
+		g.ui.selectedBRules, g.ui.selectedSRules = randomRules(r)
+		g.applySelectedRules()
+	}
+	g.InitializeBoard()
+}
+
+// Returns random birth and survival rules, each neighbour count being in each with an even chance. Births with no
+// neighbours are left out, since they make the whole board flash.
+func randomRules(rng RNG) (Ruleset, Ruleset) {
+	var bRules, sRules Ruleset
+	for i := range bRules {
+		bRules[i] = i > 0 && rng.Int63n(2) == 0
+		sRules[i] = rng.Int63n(2) == 0
+	}
+	return bRules, sRules
+}
+
+// Returns whether the pause menu is showing, which it also does while live editing.
+func (g *Game) isMenuVisible() bool {
+	return g.isPaused || g.isLiveEditing
+}
+
+// Returns a description of the cell under the cursor, for the cursor info readout.
+func (g *Game) cursorInfo() string {
+	x, y, ok := g.screenToCell(ebiten.CursorPosition())
+	if !ok {
+		return "cursor outside board"
+	}
+	state := "dead"
+	if g.Get(x, y) {
+		state = "alive"
+	}
+	return fmt.Sprintf("cell (%v, %v): %v", x, y, state)
+}
+
+// Switches the current rules with the previously used ones without touching the board. The UI selection is updated too,
+// so that the pause menu shows the rules which are actually running.
+func (g *Game) swapRules() {
+	g.bRules, g.prevBRules = g.prevBRules, g.bRules
+	g.sRules, g.prevSRules = g.prevSRules, g.sRules
+	g.updateTables()
+	g.logRules()
+
+	g.ui.selectedBRules = g.bRules
+	g.ui.selectedSRules = g.sRules
+}
+
 func (g *Game) Draw(screen *ebiten.Image) {
 	// We write our board pixels to our game image, and then draw this image scaled in (0, 0) scaling by the scale
 	// factor to fill the whole screen.
-	g.img.WritePixels(g.pixels)
+	if g.isFieldVisible {
+		g.updateFieldPixels()
+		g.img.WritePixels(g.fieldPixels)
+	} else if g.layer != nil {
+		g.updateLayerPixels()
+		g.img.WritePixels(g.layerPixels)
+	} else {
+		if g.isTrailVisible {
+			g.updateTrailPixels()
+		}
+		g.img.WritePixels(g.boardPixels())
+	}
 	options := &ebiten.DrawImageOptions{}
-	options.GeoM.Scale(float64(g.scaleFactor), float64(g.scaleFactor))
-	screen.DrawImage(g.img, options)
+	options.GeoM.Scale(g.drawScale(), g.drawScale())
+
+	// With the CRT effect on, the board is drawn to an image of its own first, which is then drawn to the screen with
+	// the effect.
+	target := screen
+	if g.isCRTEnabled {
+		target = g.crtTarget(screen)
+	}
 
-	// To dim the simulation in the background so that the pause menu UI is visible.
+	// If the simulation doesn't fill the whole screen, center it and fill the rest with the border colour.
+	if SIM_AREA_PERCENT < 100 || BOARD_WIDTH > 0 {
+		target.Fill(BORDER_COLOR)
+	}
+	if g.isBackgroundVisible {
+		g.drawBackground(target)
+	}
+	offsetX, offsetY := g.boardOffset()
+	options.GeoM.Translate(float64(offsetX), float64(offsetY))
+	g.viewTarget(target).DrawImage(g.img, options)
+	if g.isCRTEnabled {
+		g.drawCRT(screen)
+	}
+
+	// To dim the simulation in the background so that the pause menu UI is visible. While live editing it's dimmed
+	// less, since the point is to watch it.
 	if g.isPaused {
 		screen.DrawImage(g.transparencyOverlay, nil)
+		g.drawSelection(screen)
+	} else if g.isLiveEditing {
+		overlayOptions := &ebiten.DrawImageOptions{}
+		overlayOptions.ColorScale.ScaleAlpha(0.5)
+		screen.DrawImage(g.transparencyOverlay, overlayOptions)
 	}
 
-	if g.isSaving {
+	if g.isGhostVisible {
+		g.drawGhost(screen)
+	}
+	if g.isShipHighlighting {
+		g.drawShips(screen)
+	}
+	if SHOW_PARTITIONS {
+		g.drawPartitions(screen)
+	}
+	if g.isShareOverlayVisible {
+		g.drawShareOverlay(screen)
+	}
+
+	if g.isRecording() {
 		// This could also receive screen instead of g.img, to always save full resolution gifs, but saving higher
 		// resolution GIFs is slow and takes up a lot of space, so we save unscaled smaller GIFs. A user can always
 		// manually upscale them if desired.
-		g.gifSaver.saveFrame(g.img)
+		var frame image.Image = g.img
+		if g.isRecordingFlattened() {
+			frame = g.flattenedFrame()
+		}
+		g.recordFrame(frame)
 	}
 
 	// Draw UI text elements.
-	g.ui.Draw(screen, g.isPaused)
+	g.ui.generation = g.generation
+	g.ui.stableText = g.stableText()
+	if g.ui.isGenerationVisible {
+		g.ui.population = g.Population()
+		g.ui.populationFraction = float64(g.ui.population) / float64(g.gridX*g.gridY)
+	}
+	g.ui.seed = g.restartSeed()
+	g.ui.Draw(screen, g.isMenuVisible())
 }
 
-var colors [2][]byte = [2][]byte{{255, 255, 255, 255}, {0, 0, 0, 255}}
+// The colours of live cells, dead cells and just born cells, which are only used in two-tone mode, followed by the
+// colours live cells fade through with age when coloured by age. Dead cells become transparent when the background is
+// shown. The live and born colours can be changed with ALIVE_COLOR and BORN_COLOR, and the age colours fade from
+// ALIVE_COLOR to OLD_COLOR.
+var colors [FIRST_AGE_COLOR + AGE_COLOR_STEPS][]byte = [FIRST_AGE_COLOR + AGE_COLOR_STEPS][]byte{
+	{255, 255, 255, 255}, {0, 0, 0, 255}, {79, 195, 247, 255}}
 
-// Sets a pixel at a given index to either black or white.
+// Sets a pixel at a given index to the colour at index i of colors.
 func setPixel(pixels []byte, gridX, x, y int, i int) {
 	ind := 4 * (y*gridX + x)
 	copy(pixels[ind:ind+4], colors[i])
@@ -315,34 +724,230 @@ func setPixel(pixels []byte, gridX, x, y int, i int) {
 
 // Returns the size of the screen we want to be rendering to.
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	if !ebiten.IsFullscreen() {
+		// In windowed mode we render to the whole window. The board is resized to match once the window size has
+		// stopped changing, see Update.
+		if outsideWidth != windowX || outsideHeight != windowY {
+			windowX, windowY = outsideWidth, outsideHeight
+			g.resizeCountdown = RESIZE_DEBOUNCE_TICKS
+		}
+		return outsideWidth, outsideHeight
+	}
+	if SIM_AREA_PERCENT < 100 || BOARD_WIDTH > 0 {
+		// The simulation only covers part of the screen, so we need the whole screen to draw the border.
+		return FullscreenSize()
+	}
 	return g.gridX * g.scaleFactor, g.gridY * g.scaleFactor
-	// return ebiten.ScreenSizeInFullscreen()
+}
+
+// Returns the size of the screen the game is drawn on. This is the size of the monitor in fullscreen mode and the size
+// of the window in windowed mode.
+func screenSize() (int, int) {
+	if ebiten.IsFullscreen() || windowX == 0 || windowY == 0 {
+		// Before the first Layout call we don't know the window size yet, and the window starts out the size of the
+		// screen anyway.
+		return FullscreenSize()
+	}
+	return windowX, windowY
+}
+
+// Returns the size of the monitor. Some Linux compositors report a size of 0x0 until the window has been shown, in
+// which case this logs a warning and returns FALLBACK_SCREEN_WIDTH by FALLBACK_SCREEN_HEIGHT instead, and the board is
+// resized once the real size is known.
+func FullscreenSize() (int, int) {
+	x, y := ebiten.ScreenSizeInFullscreen()
+	if x >= MIN_SCREEN_SIZE && y >= MIN_SCREEN_SIZE {
+		return x, y
+	}
+	if !isScreenSizeFallback {
+		log.Printf("the screen size was reported as %vx%v, using %vx%v until the real size is known", x, y,
+			FALLBACK_SCREEN_WIDTH, FALLBACK_SCREEN_HEIGHT)
+		isScreenSizeFallback = true
+	}
+	return FALLBACK_SCREEN_WIDTH, FALLBACK_SCREEN_HEIGHT
+}
+
+// Adapts the board to a new window size, keeping as much of the current board as fits.
+func (g *Game) handleResize() {
+	// The screen size may only just be known, if it was reported wrong at first and FullscreenSize had to fall back.
+	g.ui.initScaleFactors()
+	g.ui.initFontFace()
+	g.scaleFactor = g.ui.getScaleFactor()
+	g.createTransparencyOverlay()
+	g.resetView()
+
+	// A fixed size board is just drawn at a different scale.
+	if BOARD_WIDTH > 0 {
+		return
+	}
+
+	// Keep the old board if the window has become too small to hold a sensible one.
+	x, y := boardSize(g.scaleFactor)
+	if x < 3 || y < 3 {
+		return
+	}
+	// The ghost moves along with the cells, which stay centered.
+	g.ghostX += (x - g.gridX) / 2
+	g.ghostY += (y - g.gridY) / 2
+	g.resize(x, y)
+	g.rewind.clear()
+	if g.layer != nil {
+		g.layer.resize(x, y)
+	}
+	g.img = ebiten.NewImage(g.gridX, g.gridY)
+
+	// Cells which didn't fit on the new board are gone.
+	if g.stats != nil {
+		g.recordStats()
+	}
+}
+
+// Creates the semi-transparent overlay used to dim the board, sized to cover the whole screen.
+func (g *Game) createTransparencyOverlay() {
+	x, y := screenSize()
+	g.transparencyOverlay = ebiten.NewImage(x, y)
+	g.transparencyOverlay.Fill(color.RGBA{0, 0, 0, 255 * 3 / 4}) // black but not completely opaque
+}
+
+// Returns the screen position of the top left corner of the board, moved by the view when it's zoomed in with the mouse
+// wheel. See view.go.
+func (g *Game) boardOffset() (int, int) {
+	x, y := g.unzoomedBoardOffset()
+	return x + int(g.viewX), y + int(g.viewY)
+}
+
+// Returns the screen position of the top left corner of the board when the view isn't zoomed in, which is only not
+// (0, 0) when the simulation area is smaller than the screen or the board has a fixed size.
+func (g *Game) unzoomedBoardOffset() (int, int) {
+	if SIM_AREA_PERCENT == 100 && BOARD_WIDTH == 0 {
+		return 0, 0
+	}
+	screenX, screenY := screenSize()
+	scale := g.unzoomedDrawScale()
+	return (screenX - int(float64(g.gridX)*scale)) / 2, (screenY - int(float64(g.gridY)*scale)) / 2
+}
+
+// Returns how many screen pixels wide each cell is drawn, including the zoom of the view. See unzoomedDrawScale.
+func (g *Game) drawScale() float64 {
+	return g.unzoomedDrawScale() * float64(intMax(1, g.viewZoom))
+}
+
+// Returns how many screen pixels wide each cell is drawn when the view isn't zoomed in. This is the scale factor,
+// except for a fixed size board, which is scaled to fit the simulation area instead. That's by a whole number when the
+// board fits, so that the cells stay sharp. A board which doesn't fit is shrunk, unless the scale is locked to whole
+// numbers with isPixelPerfect, in which case it's drawn at 1x and the edges are cut off. The scale factor then zooms in
+// on the fixed size board, keeping it centered, so that the display can be zoomed without changing the board.
+func (g *Game) unzoomedDrawScale() float64 {
+	if BOARD_WIDTH == 0 {
+		return float64(g.scaleFactor)
+	}
+	areaX, areaY := simulationAreaSize()
+	scale := math.Min(float64(areaX)/float64(g.gridX), float64(areaY)/float64(g.gridY))
+	if scale >= 1 || g.isPixelPerfect {
+		scale = math.Max(1, math.Floor(scale))
+	}
+	return scale * float64(g.scaleFactor)
+}
+
+// Maps a screen position, such as the cursor position, to the board cell drawn there. Returns false if the position is
+// not over the board. The returned coordinates are 0-indexed, i.e. they don't include the board edge border.
+func (g *Game) screenToCell(screenX, screenY int) (int, int, bool) {
+	offsetX, offsetY := g.boardOffset()
+	// Go's division rounds towards zero, so check for positions left of or above the board before dividing.
+	if screenX < offsetX || screenY < offsetY {
+		return 0, 0, false
+	}
+	x := int(float64(screenX-offsetX) / g.drawScale())
+	y := int(float64(screenY-offsetY) / g.drawScale())
+	if x >= g.gridX || y >= g.gridY {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// Returns the size in screen pixels of the area the simulation is drawn in. This is the whole screen unless
+// SIM_AREA_PERCENT is less than 100.
+func simulationAreaSize() (int, int) {
+	x, y := screenSize()
+	return x * SIM_AREA_PERCENT / 100, y * SIM_AREA_PERCENT / 100
+}
+
+// Returns the size of the board at the given scale factor: as many cells as fit in the simulation area, unless the
+// board has a fixed size.
+func boardSize(scaleFactor int) (int, int) {
+	if BOARD_WIDTH > 0 {
+		return BOARD_WIDTH, BOARD_HEIGHT
+	}
+	areaX, areaY := simulationAreaSize()
+	return areaX / scaleFactor, areaY / scaleFactor
 }
 
 // Initializes the initial simulation state. Called only once, before ebiten.runGame(g).
 func (g *Game) InitializeState() {
 	// Currently seed is always 0, kind of redundant.
 	r = rand.New(rand.NewSource(SEED))
+	if STABLE_RNG {
+		r = rand.New(NewStableRNG(SEED))
+	}
 
-	// Initial rule set is just Conway's Game of Life.
-	g.bRules = Ruleset{}
-	g.bRules[3] = true
-	g.sRules = Ruleset{}
-	g.sRules[2] = true
-	g.sRules[3] = true
+	g.bRules, g.sRules = INITIAL_B_RULES, INITIAL_S_RULES
 	g.updateTables()
+	g.SetStates(STATES)
+	g.maxLifespan = clamp(0, MAX_LIFESPAN, LIFESPAN)
+	g.SetProbabilities(BIRTH_PROBABILITY, SURVIVAL_PROBABILITY)
+	g.boundaryMode = BOUNDARY_MODE
+	g.symmetry = SYMMETRY
+	g.SetMask(NEIGHBOUR_MASK)
+	g.SetLtLRule(LTL_RULE)
+	if NEIGHBOUR_MASK != nil {
+		g.neighbourhood = NEIGHBOURHOOD_CUSTOM
+	}
+	g.isTwoTone = TWO_TONE
+	g.isBounded = BOUNDED_UPDATES
+	colors[0] = []byte{ALIVE_COLOR.R, ALIVE_COLOR.G, ALIVE_COLOR.B, 255}
+	colors[2] = []byte{BORN_COLOR.R, BORN_COLOR.G, BORN_COLOR.B, 255}
+	fillAgeColors(ALIVE_COLOR, OLD_COLOR)
+	g.isAgeColored = AGE_COLORED
+	g.setTrailVisible(TRAILS)
+
+	// There are no previous rules yet, so switching back just keeps the current ones.
+	g.prevBRules = g.bRules
+	g.prevSRules = g.sRules
 
 	g.avgStartingLiveCellPercentage = 50.0
 
 	g.isPaused = true
-	g.isSaving = false
+	g.isPixelPerfect = PIXEL_PERFECT
+	g.isCRTEnabled = CRT_ENABLED
+	g.crtIntensity = CRT_INTENSITY
+	g.isGhostVisible = GHOST_PATTERN != nil
+	g.recordingState = RECORDING_IDLE
+	g.startTime = time.Now()
+
+	if STATS_FILE != "" {
+		g.stats = newStatsWriter(STATS_FILE)
+		g.isCountingChanges = true
+	}
+	if RULE_LOG_FILE != "" {
+		g.ruleLog = newRuleLogWriter(RULE_LOG_FILE)
+	}
+
+	g.ui.exactSpeed = clamp(0, MAX_GENERATIONS_PER_SECOND, GENERATIONS_PER_SECOND)
 
 	// Start the simulation at the second smallest scale factor, i.e. slightly zoomed in. For most screen resolutions
 	// this will be a 2x zoom (since both screen height and width are usually even).
 	initialScaleIndex := 1
+	if BOARD_WIDTH > 0 {
+		// The scale factor zooms in on a fixed size board, which starts out fitting the screen.
+		initialScaleIndex = 0
+	}
 
 	// Initialize UI, get the chosen scale factor from it.
 	g.ui.initialize(g.bRules, g.sRules, g.avgStartingLiveCellPercentage, initialScaleIndex)
+	g.ui.selectedBoundaryMode = g.boundaryMode
+	g.ui.selectedSymmetry = g.symmetry
+	g.ui.selectedNeighbourhood = g.neighbourhood
+	g.ui.isAutoSpeed = AUTO_SPEED
 
 	if len(g.ui.possibleScaleFactors) == 1 {
 		// Sometimes the x and y res will end up relatively prime and defaulting to the second index will crash
@@ -352,83 +957,138 @@ func (g *Game) InitializeState() {
 
 	g.scaleFactor = g.ui.getScaleFactor()
 
-	x, y := ebiten.ScreenSizeInFullscreen()
-	g.gridX = x / g.scaleFactor
-	g.gridY = y / g.scaleFactor
+	g.gridX, g.gridY = boardSize(g.scaleFactor)
 
-	g.transparencyOverlay = ebiten.NewImage(x, y)
-	g.transparencyOverlay.Fill(color.RGBA{0, 0, 0, 255 * 3 / 4}) // black but not completely opaque
+	g.createTransparencyOverlay()
 
-	// Create buffered task channel and initialize workers.
+	g.startWorkers()
+}
+
+// Creates the buffered task channel and starts POOL_SIZE workers reading from it. Any workers already running are
+// stopped first.
+func (g *Game) startWorkers() {
+	g.stopWorkers()
 	g.taskChannel = make(chan Task, POOL_SIZE)
 	for i := 0; i < POOL_SIZE; i++ {
-		go g.worker()
+		g.workers.Add(1)
+		go g.worker(g.taskChannel)
 	}
 }
 
-// A worker constantly tries to get a task from the task channel and execute it.
-func (g *Game) worker() {
-	for task := range g.taskChannel {
-		g.updateRange(task.minY, task.maxY)
-		g.wg.Done() // To signal that the task is done.
+// Closes the task channel and waits for the workers to finish their remaining tasks and exit. Safe to call more than
+// once.
+func (g *Game) stopWorkers() {
+	if g.taskChannel == nil {
+		return
 	}
+	close(g.taskChannel)
+	g.taskChannel = nil
+	g.workers.Wait()
 }
 
-func (g *Game) updateTables() {
-	for i := 0; i < len(g.becomesAliveTable); i++ {
-		g.becomesAliveTable[i] = false
-		g.becomesDeadTable[i] = false
-	}
+// Makes the next board be generated from the given seed. Simulation.Randomize with the same seed produces the same
+// board, which is how the results of a seed search can be launched into.
+func (g *Game) UseSeed(seed int64) {
+	g.nextSeed = seed
+	g.isNextSeedSet = true
+}
 
-	for i := 0; i < len(g.bRules); i++ {
-		if g.bRules[i] {
-			g.becomesAliveTable[2*i] = true
-		}
+// Returns the seed the next board will be randomized from if one has been chosen, or else the current board's seed.
+func (g *Game) restartSeed() int64 {
+	if g.isNextSeedSet {
+		return g.nextSeed
 	}
-	for i := 0; i < len(g.sRules); i++ {
-		if !g.sRules[i] {
-			g.becomesDeadTable[1+2*i] = true
-		}
+	return g.boardSeed
+}
+
+// Chooses the seed delta away from restartSeed for the next board, so that nearby seeds can be stepped through.
+func (g *Game) stepSeed(delta int64) {
+	g.UseSeed(g.restartSeed() + delta)
+}
+
+// A worker constantly tries to get a task from the task channel and execute it.
+func (g *Game) worker(tasks <-chan Task) {
+	defer g.workers.Done()
+	for task := range tasks {
+		g.updateRange(task.minY, task.maxY)
+		g.wg.Done() // To signal that the task is done.
 	}
 }
 
 // Initializes the simulation board, filling it with cells randomly, and creates the corresponding initial simulation
 // image. The chance of a given cell being set to alive is given by g.avgStartingLiveCellPercentage.
 func (g *Game) InitializeBoard() {
-	x, y := ebiten.ScreenSizeInFullscreen()
-	g.gridX = x / g.scaleFactor
-	g.gridY = y / g.scaleFactor
+	// Every frame of a recording has to be the same size, so a new board of a different size ends them.
+	x, y := boardSize(g.scaleFactor)
+	if x != g.gridX || y != g.gridY {
+		g.stopRecordings()
+	}
+	g.allocate(x, y)
+	g.rewind.clear()
+	// The scale may have changed, which changes how far the view can move.
+	g.clampView()
 
 	g.img = ebiten.NewImage(g.gridX, g.gridY)
 	g.img.Fill(color.Black)
 
-	// RGBA channels, so 4 bytes per image pixel.
-	g.pixels = make([]byte, 4*g.gridX*g.gridY)
+	g.fillBoard()
+	g.initializeLayer()
+	g.flipBoard(FLIP)
+	g.placeGhost()
+	g.warmUp()
+
+	g.logRules()
+	g.lastActivityTime = time.Now()
+	g.resetActivity()
+
+	if CAPTURE_SPIKE_PERCENT > 0 {
+		g.resetCapture()
+	}
+	if CUES_ENABLED || PAUSE_WHEN_STABLE {
+		g.resetEvents()
+	}
+	if g.stats != nil {
+		g.recordStats()
+	}
+}
 
-	// Make all pixels black initially.
-	for i := 0; i < g.gridY; i++ {
-		for j := 0; j < g.gridX; j++ {
-			setPixel(g.pixels, g.gridX, j, i, 1)
+// Runs the first WARMUP_GENERATIONS generations of a new board straight away, so that it's first shown after them.
+// The generation counter counts them.
+func (g *Game) warmUp() {
+	for i := 0; i < WARMUP_GENERATIONS; i++ {
+		g.updateBoard()
+		if g.layer != nil {
+			g.layer.updateBoard()
 		}
 	}
+}
 
-	g.worldGrid = make([]int8, (g.gridX+2)*(g.gridY+2))
-	g.buffer = make([]int8, (g.gridX+2)*(g.gridY+2))
-	for i := 1; i <= g.gridY; i++ {
-		for j := 1; j <= g.gridX; j++ {
-			if int(r.Int63n(100000)) < int(1000*g.avgStartingLiveCellPercentage) { // Cell becomes alive.
-				g.worldGrid[i*(g.gridX+2)+j] |= 1
-				// g.pixels.Set(j-1, i-1, color.White)
-				setPixel(g.pixels, g.gridX, j-1, i-1, 0)
-				// Update live neighbour counts in the cells affected by this cell becoming alive.
-				for a := -1; a <= 1; a++ {
-					for b := -1; b <= 1; b++ {
-						if (a != 0) || (b != 0) {
-							g.worldGrid[(i+a)*(g.gridX+2)+j+b] += 2
-						}
-					}
-				}
-			}
+// Fills the newly allocated board with its starting cells: the starting pattern, copies of the tiled pattern or
+// random cells from a new seed.
+func (g *Game) fillBoard() {
+	// Each board gets its own seed so that it can be recreated from the seed alone.
+	g.boardSeed = r.Int63()
+	isSeedChosen := g.isNextSeedSet
+	if g.isNextSeedSet {
+		g.boardSeed = g.nextSeed
+		g.isNextSeedSet = false
+	}
+	if INIT_PATTERN != nil {
+		if w, h := INIT_PATTERN.size(); w > g.gridX || h > g.gridY {
+			log.Printf("the starting pattern is %vx%v cells, which doesn't fit on the %vx%v board, so it's cut off", w, h,
+				g.gridX, g.gridY)
+		}
+		g.center(INIT_PATTERN)
+		g.noiseSeed = uint64(g.boardSeed)
+	} else if TILE_PATTERN != nil {
+		g.tile(TILE_PATTERN, TILE_SPACING)
+		// Probabilistic rules still need their noise to be seeded.
+		g.noiseSeed = uint64(g.boardSeed)
+	} else {
+		g.Randomize(g.avgStartingLiveCellPercentage, g.boardSeed)
+		// A seed which was asked for, from a shared run or a seed search, is kept even if it dies out.
+		if MIN_SURVIVAL > 0 && !isSeedChosen {
+			g.rerollUntilSurviving()
 		}
 	}
 }
@@ -1,8 +1,15 @@
 package game
 
+import "errors"
+
 // For comparing performance in benchmarks
 func (g *Game) updateBoardAlt() error {
+	if g.taskChannel == nil {
+		return errors.New("workers have been stopped")
+	}
 	copy(g.buffer, g.worldGrid)
+	// The whole board is updated, rather than just the region around the live cells.
+	g.region = cellRect{1, 1, g.gridX, g.gridY}
 
 	// Divide the board into equal-sized parts and create tasks for each part.
 	numParts := POOL_SIZE
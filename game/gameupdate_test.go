@@ -2,7 +2,22 @@ package game
 
 import (
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"testing"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font/basicfont"
 )
 
 // func TestUpdate(t *testing.T) {
@@ -33,7 +48,7 @@ func BenchmarkUpdate(b *testing.B) {
 				g.updateBoard()
 			}
 		})
-		close(g.taskChannel)
+		g.stopWorkers()
 	}
 }
 
@@ -50,17 +65,65 @@ func BenchmarkUpdateAlt(b *testing.B) {
 			}
 			g.InitializeState()
 		})
-		close(g.taskChannel)
+		g.stopWorkers()
+	}
+}
+
+// Measures a complete generation as the game runs it: the board update, including the setPixel writes for every cell
+// which changes, followed by copying the pixels to an image as Draw does with WritePixels. The GPU side of drawing is
+// left out, so the copy goes to a CPU image instead. Uses a 1920x1080 screen at 2x zoom.
+func BenchmarkGeneration(b *testing.B) {
+	s := NewSimulation(960, 540, Ruleset{false, false, false, true}, Ruleset{false, false, true, true})
+	s.Randomize(50, SEED)
+	frame := image.NewRGBA(image.Rect(0, 0, s.gridX, s.gridY))
+
+	// Let the initial noise settle down first, since a freshly randomized board changes far more cells per generation
+	// than a typical running one.
+	for i := 0; i < 100; i++ {
+		s.Step()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Step()
+		copy(frame.Pix, s.pixels)
+	}
+}
+
+// Compares static partitioning (1 chunk per worker) with finer chunks on a board where all the activity starts in the
+// top tenth, which leaves most of the statically assigned goroutines with nothing changing in their rows.
+func BenchmarkSkewedUpdate(b *testing.B) {
+	defer func(old int) { CHUNKS_PER_WORKER = old }(CHUNKS_PER_WORKER)
+
+	for _, chunks := range []int{1, 4, 16} {
+		CHUNKS_PER_WORKER = chunks
+		b.Run(fmt.Sprintf("%2d chunks per worker", chunks), func(b *testing.B) {
+			s := NewSimulation(960, 540, Ruleset{false, false, false, true}, Ruleset{false, false, true, true})
+			rng := newRNG(SEED)
+			for y := 0; y < s.gridY/10; y++ {
+				for x := 0; x < s.gridX; x++ {
+					s.Set(x, y, rng.Int63n(2) == 0)
+				}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.Step()
+			}
+		})
 	}
 }
 
+// Checks that every cell's value is twice its number of live neighbours, plus one if it is alive itself.
 func verifyNeighbourCounts(gridX, gridY int, worldGrid []int8) error {
 	for i := 1; i <= gridY; i++ {
-		for j := 1; j <= gridY; j++ {
+		for j := 1; j <= gridX; j++ {
 			desiredVal := int8(0)
 			for a := -1; a <= 1; a++ {
 				for b := -1; b <= 1; b++ {
-					desiredVal += 2 * (worldGrid[(i+a)*(gridX+2)+j+b] & 1)
+					if a != 0 || b != 0 {
+						desiredVal += 2 * (worldGrid[(i+a)*(gridX+2)+j+b] & 1)
+					}
 				}
 			}
 			desiredVal |= (worldGrid[(i)*(gridX+2)+j] & 1)
@@ -75,3 +138,1844 @@ func verifyNeighbourCounts(gridX, gridY int, worldGrid []int8) error {
 
 	return nil
 }
+
+// Returns a small Game of Life simulation with the given cells set alive.
+func newTestSimulation(gridX, gridY int, cells [][2]int) *Simulation {
+	s := NewSimulation(gridX, gridY, Ruleset{3: true}, Ruleset{2: true, 3: true})
+	for _, c := range cells {
+		s.setCell(c[0], c[1], true)
+	}
+	return s
+}
+
+// Returns a small Game of Life game with the given cells set alive.
+func newTestGame(gridX, gridY int, cells [][2]int) *Game {
+	g := &Game{}
+	g.bRules, g.sRules = Ruleset{3: true}, Ruleset{2: true, 3: true}
+	g.updateTables()
+	g.SetProbabilities(1, 1)
+	g.allocate(gridX, gridY)
+	for _, c := range cells {
+		g.Set(c[0], c[1], true)
+	}
+	return g
+}
+
+// Returns the number of live cells on the board.
+func population(s *Simulation) int {
+	res := 0
+	for _, val := range s.worldGrid {
+		res += int(val & 1)
+	}
+	return res
+}
+
+func TestLifespan(t *testing.T) {
+	// A block is a still life, so it only dies if the cells die of old age.
+	block := [][2]int{{3, 3}, {3, 4}, {4, 3}, {4, 4}}
+	s := newTestSimulation(8, 8, block)
+	s.SetMaxLifespan(3)
+
+	for gen := 1; gen <= 4; gen++ {
+		s.Step()
+		if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+			t.Fatalf("generation %v: %v", gen, err)
+		}
+
+		want := 4
+		if gen == 4 {
+			want = 0
+		}
+		if got := population(s); got != want {
+			t.Fatalf("generation %v: population is %v, want %v", gen, got, want)
+		}
+	}
+
+	// Without a lifespan the block lives forever.
+	s = newTestSimulation(8, 8, block)
+	for gen := 1; gen <= 10; gen++ {
+		s.Step()
+	}
+	if got := population(s); got != 4 {
+		t.Fatalf("population without lifespan is %v, want 4", got)
+	}
+}
+
+func TestImmortalCells(t *testing.T) {
+	// Lone cells die under Conway's rules, and also of old age and at random with the rule modifiers, which are
+	// handled by the general update.
+	for _, lifespan := range []int{0, 1} {
+		s := newTestSimulation(12, 12, nil)
+		if lifespan > 0 {
+			s.SetMaxLifespan(lifespan)
+			s.SetProbabilities(1, 0.5)
+		}
+		s.SetImmortal(2, 2, true)
+		s.SetImmortal(9, 9, true)
+		s.SetImmortal(9, 9, false)
+		for gen := 0; gen < 10; gen++ {
+			s.Step()
+			if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+				t.Fatalf("lifespan %v, generation %v: %v", lifespan, gen, err)
+			}
+		}
+		if !s.Get(2, 2) || s.Get(9, 9) {
+			t.Errorf("lifespan %v: only the immortal cell should still be alive", lifespan)
+		}
+
+		// Setting an immortal cell dead makes it mortal again.
+		s.Set(2, 2, false)
+		s.Set(2, 2, true)
+		s.Step()
+		if s.Get(2, 2) {
+			t.Errorf("lifespan %v: a cell which was set dead should have lost its immortality", lifespan)
+		}
+	}
+}
+
+func TestProbabilisticRulesIndependentOfPoolSize(t *testing.T) {
+	defer func(poolSize int) { POOL_SIZE = poolSize }(POOL_SIZE)
+
+	var boards [][]int8
+	for _, poolSize := range []int{1, 8} {
+		POOL_SIZE = poolSize
+		s := newTestSimulation(64, 48, nil)
+		s.SetProbabilities(0.5, 0.9)
+		s.Randomize(40, 1)
+		for gen := 0; gen < 20; gen++ {
+			s.Step()
+		}
+		if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+			t.Fatalf("pool size %v: %v", poolSize, err)
+		}
+		boards = append(boards, s.worldGrid)
+	}
+
+	if !reflect.DeepEqual(boards[0], boards[1]) {
+		t.Fatal("boards differ between pool sizes")
+	}
+}
+
+func TestChunkedUpdate(t *testing.T) {
+	defer func(poolSize, chunks int) { POOL_SIZE, CHUNKS_PER_WORKER = poolSize, chunks }(POOL_SIZE, CHUNKS_PER_WORKER)
+
+	// Boards too small to split, boards just big enough and boards with chunks of uneven sizes.
+	for _, gridY := range []int{1, 2, 3, 4, 9, 50} {
+		var boards [][]int8
+		for _, c := range []struct{ poolSize, chunks int }{{1, 1}, {8, 1}, {8, 16}} {
+			POOL_SIZE, CHUNKS_PER_WORKER = c.poolSize, c.chunks
+			s := newTestSimulation(40, gridY, nil)
+			s.Randomize(40, 1)
+			for gen := 0; gen < 20; gen++ {
+				s.Step()
+			}
+			if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+				t.Fatalf("%v rows, pool size %v, %v chunks per worker: %v", gridY, c.poolSize, c.chunks, err)
+			}
+			boards = append(boards, s.worldGrid)
+		}
+		for _, board := range boards[1:] {
+			if !reflect.DeepEqual(board, boards[0]) {
+				t.Fatalf("%v rows: boards differ between chunkings", gridY)
+			}
+		}
+	}
+}
+
+// The cells of a glider heading down and to the right, with its top left corner at (x, y).
+func glider(x, y int) [][2]int {
+	return [][2]int{{x + 1, y}, {x + 2, y + 1}, {x, y + 2}, {x + 1, y + 2}, {x + 2, y + 2}}
+}
+
+func TestBoundedUpdate(t *testing.T) {
+	for m := BoundaryMode(0); m < NUM_BOUNDARY_MODES; m++ {
+		// A glider which runs into the bottom right edge and a random patch which grows and shrinks.
+		cells := glider(40, 20)
+		rng := newRNG(SEED)
+		for y := 8; y < 20; y++ {
+			for x := 8; x < 20; x++ {
+				if rng.Int63n(2) == 0 {
+					cells = append(cells, [2]int{x, y})
+				}
+			}
+		}
+
+		var sims [2]*Simulation
+		for i := range sims {
+			sims[i] = newTestSimulation(60, 40, cells)
+			sims[i].SetBoundaryMode(m)
+			sims[i].SetBounded(i == 1)
+			sims[i].isTwoTone = true
+			sims[i].isCountingChanges = true
+		}
+		for gen := 0; gen < 200; gen++ {
+			for _, s := range sims {
+				s.Step()
+			}
+			if !reflect.DeepEqual(sims[0].worldGrid, sims[1].worldGrid) || !reflect.DeepEqual(sims[0].pixels, sims[1].pixels) {
+				t.Fatalf("%v boundary, generation %v: bounded update differs from the full one", m, gen)
+			}
+			if sims[0].births != sims[1].births || sims[0].deaths != sims[1].deaths {
+				t.Fatalf("%v boundary, generation %v: bounded update counted %v births and %v deaths, want %v and %v", m,
+					gen, sims[1].births, sims[1].deaths, sims[0].births, sims[0].deaths)
+			}
+		}
+	}
+}
+
+func BenchmarkBoundedGlider(b *testing.B) {
+	for _, bounded := range []bool{false, true} {
+		b.Run(fmt.Sprintf("bounded %v", bounded), func(b *testing.B) {
+			s := newTestSimulation(1920, 1080, glider(0, 0))
+			s.SetBounded(bounded)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.Step()
+			}
+		})
+	}
+}
+
+func TestChunkBoundaries(t *testing.T) {
+	defer func(poolSize, chunks int) { POOL_SIZE, CHUNKS_PER_WORKER = poolSize, chunks }(POOL_SIZE, CHUNKS_PER_WORKER)
+	POOL_SIZE, CHUNKS_PER_WORKER = 4, 2
+
+	s := newTestSimulation(40, 50, nil)
+	if b := s.chunkBoundaries(); b != nil {
+		t.Errorf("chunk boundaries before any update are %v, want none", b)
+	}
+	s.Randomize(40, 1)
+	s.Step()
+	if got, want := s.chunkBoundaries(), []int{0, 6, 12, 18, 24, 30, 36, 42, 50}; !reflect.DeepEqual(got, want) {
+		t.Errorf("chunk boundaries are %v, want %v", got, want)
+	}
+
+	// Bounded updates only split the rows around the live cells.
+	s = newTestSimulation(40, 50, glider(10, 20))
+	s.SetBounded(true)
+	s.Step()
+	if got, want := s.chunkBoundaries(), []int{19, 24}; !reflect.DeepEqual(got, want) {
+		t.Errorf("bounded chunk boundaries are %v, want %v", got, want)
+	}
+}
+
+func TestBoundaryModeNeighbourCounts(t *testing.T) {
+	for m := BoundaryMode(0); m < NUM_BOUNDARY_MODES; m++ {
+		s := newTestSimulation(32, 24, nil)
+		s.SetBoundaryMode(m)
+		s.Randomize(40, 1)
+
+		for gen := 0; gen <= 20; gen++ {
+			for y := 0; y < s.gridY; y++ {
+				for x := 0; x < s.gridX; x++ {
+					got := s.worldGrid[(y+1)*(s.gridX+2)+x+1]
+					s.recountNeighbours(x, y)
+					if want := s.worldGrid[(y+1)*(s.gridX+2)+x+1]; got != want {
+						t.Fatalf("%v boundary, generation %v: cell (%v, %v) has value %v, want %v", m, gen, x, y,
+							got, want)
+					}
+				}
+			}
+			s.Step()
+		}
+	}
+}
+
+func TestFlip(t *testing.T) {
+	for f := FLIP_NONE; f <= FLIP_BOTH; f++ {
+		orig := newTestSimulation(23, 14, nil)
+		orig.Randomize(40, 1)
+		s := newTestSimulation(23, 14, nil)
+		s.Randomize(40, 1)
+		s.Flip(f)
+		if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+			t.Fatalf("%v flip: %v", f, err)
+		}
+
+		for y := 0; y < s.gridY; y++ {
+			for x := 0; x < s.gridX; x++ {
+				fx, fy := x, y
+				if f == FLIP_HORIZONTAL || f == FLIP_BOTH {
+					fx = s.gridX - 1 - x
+				}
+				if f == FLIP_VERTICAL || f == FLIP_BOTH {
+					fy = s.gridY - 1 - y
+				}
+				if s.Get(fx, fy) != orig.Get(x, y) {
+					t.Fatalf("%v flip: cell (%v, %v) didn't end up at (%v, %v)", f, x, y, fx, fy)
+				}
+				ind, find := 4*(y*s.gridX+x), 4*(fy*s.gridX+fx)
+				if !reflect.DeepEqual(s.pixels[find:find+4], orig.pixels[ind:ind+4]) {
+					t.Fatalf("%v flip: pixel (%v, %v) didn't end up at (%v, %v)", f, x, y, fx, fy)
+				}
+			}
+		}
+
+		// The flipped board must also keep evolving correctly.
+		for gen := 0; gen < 10; gen++ {
+			s.Step()
+		}
+		if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+			t.Fatalf("%v flip, after 10 generations: %v", f, err)
+		}
+	}
+}
+
+func TestRotateSquare(t *testing.T) {
+	orig := newTestSimulation(30, 20, nil)
+	orig.Randomize(40, 1)
+	s := newTestSimulation(30, 20, nil)
+	s.Randomize(40, 1)
+
+	// A square touching the top edge, so that the recounting has to stop at the edge.
+	rect := image.Rect(5, 0, 17, 12)
+	if err := s.RotateSquare(rect, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < s.gridY; y++ {
+		for x := 0; x < s.gridX; x++ {
+			// Where the cell at (x, y) went, relative to the top left corner of the square.
+			rx, ry := rect.Dx()-1-(y-rect.Min.Y), x-rect.Min.X
+			if !image.Pt(x, y).In(rect) {
+				rx, ry = x-rect.Min.X, y-rect.Min.Y
+			}
+			if s.Get(rx+rect.Min.X, ry+rect.Min.Y) != orig.Get(x, y) {
+				t.Fatalf("cell (%v, %v) didn't end up at (%v, %v)", x, y, rx+rect.Min.X, ry+rect.Min.Y)
+			}
+		}
+	}
+
+	// A quarter turn back gets the original board.
+	if err := s.RotateSquare(rect, false); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.pixels, orig.pixels) {
+		t.Error("turning back and forth changed the board")
+	}
+
+	if s.RotateSquare(image.Rect(0, 0, 30, 20), true) == nil || s.RotateSquare(image.Rect(25, 0, 35, 10), true) == nil {
+		t.Error("only square areas on the board should turn")
+	}
+}
+
+func TestMooreMaskMatchesDefaultNeighbourhood(t *testing.T) {
+	for m := BoundaryMode(0); m < NUM_BOUNDARY_MODES; m++ {
+		plain := newTestSimulation(40, 30, nil)
+		plain.SetBoundaryMode(m)
+		masked := plain.blankCopy()
+		masked.SetMask(MOORE_MASK)
+		plain.Randomize(40, 1)
+		masked.Randomize(40, 1)
+
+		for gen := 1; gen <= 30; gen++ {
+			plain.Step()
+			masked.Step()
+			// Only the cells on the board are compared, since the border values differ between the two update paths.
+			for y := 0; y < plain.gridY; y++ {
+				for x := 0; x < plain.gridX; x++ {
+					ind := (y+1)*(plain.gridX+2) + x + 1
+					if plain.worldGrid[ind] != masked.worldGrid[ind] {
+						t.Fatalf("%v boundary, generation %v: cell (%v, %v) is %v with the mask but %v without", m, gen,
+							x, y, masked.worldGrid[ind], plain.worldGrid[ind])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParseMask(t *testing.T) {
+	mask, err := ParseMask(".#.\n#.#\n.#.\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NeighbourMask{{0, -1}, {-1, 0}, {1, 0}, {0, 1}}
+	if !reflect.DeepEqual(mask, want) {
+		t.Fatalf("got mask %v, want %v", mask, want)
+	}
+
+	for _, bad := range []string{"", "#.\n.#", "...\n.#.\n...", "#.#\n...", ".#.\n#x#\n.#."} {
+		if _, err := ParseMask(bad); err == nil {
+			t.Errorf("mask %q should be rejected", bad)
+		}
+	}
+}
+
+// A recording saver whose writes block until released, for testing what happens while a recording is being written.
+type blockingSaver struct {
+	release chan struct{}
+	written bool
+}
+
+func (bs *blockingSaver) saveFrame(img image.Image) {}
+
+func (bs *blockingSaver) writeToFile() {
+	<-bs.release
+	bs.written = true
+}
+
+func TestRecordingCantStartWhileWriting(t *testing.T) {
+	g := &Game{}
+	first := &blockingSaver{release: make(chan struct{})}
+	if !g.startRecording(first) {
+		t.Fatal("couldn't start the first recording")
+	}
+	g.stopRecording()
+	if g.recordingState != RECORDING_WRITING || !g.ui.shouldDisplayWritingToFileText {
+		t.Fatalf("recording state is %v after stopping, want writing", g.recordingState)
+	}
+
+	// Starting again while the first recording is being written must fail and leave the first write alone.
+	second := &blockingSaver{release: make(chan struct{})}
+	g.pollRecording()
+	if g.startRecording(second) {
+		t.Fatal("started a recording while the last one was being written")
+	}
+
+	close(first.release)
+	g.finishRecording()
+	if !first.written || second.written {
+		t.Fatalf("first written: %v, second written: %v, want only the first", first.written, second.written)
+	}
+	if g.recordingState != RECORDING_IDLE || g.ui.shouldDisplayWritingToFileText {
+		t.Fatalf("recording state is %v after writing, want idle", g.recordingState)
+	}
+
+	if !g.startRecording(second) {
+		t.Fatal("couldn't start a recording after the last one was written")
+	}
+}
+
+func TestSlotRecordings(t *testing.T) {
+	a, b := &blockingSaver{release: make(chan struct{})}, &blockingSaver{release: make(chan struct{})}
+	g := &Game{slotSavers: map[int]GifSaverInterface{5: b, 2: a}}
+	g.updateRecordingText()
+	if !g.isRecording() || g.ui.recordingSlotsText != "recording in slots 2, 5" {
+		t.Fatalf("recording: %v, slots text %q, want slots 2 and 5 recording", g.isRecording(), g.ui.recordingSlotsText)
+	}
+
+	// Stopping one slot leaves the other recording.
+	g.toggleSlotRecording(2)
+	if g.ui.recordingSlotsText != "recording in slots 5" {
+		t.Fatalf("slots text is %q after stopping slot 2, want only slot 5", g.ui.recordingSlotsText)
+	}
+
+	close(a.release)
+	close(b.release)
+	g.finishRecording()
+	if !a.written || !b.written {
+		t.Fatalf("slot 2 written: %v, slot 5 written: %v, want both", a.written, b.written)
+	}
+	if g.isRecording() || g.ui.recordingSlotsText != "" {
+		t.Fatalf("still recording after finishing, slots text %q", g.ui.recordingSlotsText)
+	}
+}
+
+func TestSymmetricFill(t *testing.T) {
+	alive := func(s *Simulation, x, y int) bool { return s.worldGrid[(y+1)*(s.gridX+2)+x+1]&1 == 1 }
+
+	for sym := Symmetry(0); sym < NUM_SYMMETRIES; sym++ {
+		s := newTestSimulation(24, 16, nil)
+		s.symmetry = sym
+		s.Randomize(50, 1)
+		if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+			t.Fatalf("%v symmetry: %v", sym, err)
+		}
+
+		for y := 0; y < s.gridY; y++ {
+			for x := 0; x < s.gridX; x++ {
+				mx, my := s.gridX-1-x, s.gridY-1-y
+				// Diagonal mirror image through the center of the board, which is only on the board near the middle.
+				dx, dy := y+(s.gridX-s.gridY)/2, x-(s.gridX-s.gridY)/2
+				checks := map[Symmetry][][2]int{
+					SYMMETRY_LEFT_RIGHT: {{mx, y}},
+					SYMMETRY_TOP_BOTTOM: {{x, my}},
+					SYMMETRY_QUADRANTS:  {{mx, y}, {x, my}},
+					SYMMETRY_EIGHT_FOLD: {{mx, y}, {x, my}, {dx, dy}},
+				}
+				for _, c := range checks[sym] {
+					if c[0] < 0 || c[0] >= s.gridX || c[1] < 0 || c[1] >= s.gridY {
+						continue
+					}
+					if alive(s, x, y) != alive(s, c[0], c[1]) {
+						t.Fatalf("%v symmetry: cells (%v, %v) and (%v, %v) differ", sym, x, y, c[0], c[1])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTilePattern(t *testing.T) {
+	glider, err := ParsePattern("! A glider.\n.O.\n..O\nOOO\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestSimulation(20, 11, nil)
+	s.tile(glider, 2)
+	if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+		t.Fatal(err)
+	}
+
+	// Copies start every 5 cells, so 4 across and 3 down, the last row cut off after its first row of cells.
+	if got, want := population(s), 4*2*5+4*1; got != want {
+		t.Errorf("tiled board has %v live cells, want %v", got, want)
+	}
+	for _, c := range [][2]int{{1, 0}, {7, 1}, {15, 7}, {16, 10}} {
+		if s.worldGrid[(c[1]+1)*(s.gridX+2)+c[0]+1]&1 != 1 {
+			t.Errorf("cell %v should be alive", c)
+		}
+	}
+}
+
+func TestSimultaneousNumberKeys(t *testing.T) {
+	pressed := map[ebiten.Key]bool{ebiten.Key7: true, ebiten.Key2: true, ebiten.Key5: true}
+	nums := pressedNumbers(func(k ebiten.Key) bool { return pressed[k] })
+	if want := []uint8{2, 5, 7}; !reflect.DeepEqual(nums, want) {
+		t.Fatalf("pressed numbers are %v, want %v", nums, want)
+	}
+
+	ui := &UI{selectedBRules: Ruleset{2: true, 3: true}}
+	ui.rulesBeingChanged = &ui.selectedBRules
+	ui.toggleRuleNumbers(nums)
+	if want := (Ruleset{3: true, 5: true, 7: true}); ui.selectedBRules != want {
+		t.Errorf("rules after toggling are %v, want %v", ui.selectedBRules, want)
+	}
+}
+
+func TestCenterPattern(t *testing.T) {
+	blinker, err := ParsePattern("OOO\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestSimulation(9, 5, nil)
+	s.center(blinker)
+	if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < s.gridX; x++ {
+		if want := x >= 3 && x <= 5; s.Get(x, 2) != want {
+			t.Errorf("cell (%v, 2) alive is %v, want %v", x, s.Get(x, 2), want)
+		}
+	}
+
+	// A pattern wider than the board is cut off on both sides.
+	s = newTestSimulation(3, 3, nil)
+	s.center(Pattern{{true, false, true, true, false}})
+	if got, want := population(s), 2; got != want || !s.Get(1, 1) || !s.Get(2, 1) {
+		t.Errorf("cut off pattern has %v live cells, want %v on the right of the middle row", got, want)
+	}
+}
+
+func TestParseRLE(t *testing.T) {
+	want := Pattern{{false, true, false, false}, {false, false, true, false}, {true, true, true, false}, {}}
+	want[3] = make([]bool, 4)
+
+	// The header pads the pattern to 4x4, and the counts and line breaks can fall anywhere in the cells.
+	p, err := ParseRLE("#N Glider\n#C A comment.\nx = 4, y = 4, rule = B3/S23\nbo$2b\no$3o!\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("parsed pattern is %v, want %v", p, want)
+	}
+
+	if !isRLE("#C A comment.\nx=4,y=4\nbo$2bo$3o!") || isRLE("! A comment.\n.O.\n") {
+		t.Error("RLE patterns should be told apart from plaintext ones by the header")
+	}
+	for _, str := range []string{"x = 3, y = 3\nbxo!", "x = a, y = 3\nbo!", "x = 0, y = 0\n!"} {
+		if _, err := ParseRLE(str); err == nil {
+			t.Errorf("%q should fail to parse", str)
+		}
+	}
+}
+
+func TestCappedSpeedup(t *testing.T) {
+	defer func(old int) { MAX_GENERATIONS_PER_FRAME = old }(MAX_GENERATIONS_PER_FRAME)
+
+	for _, c := range []struct {
+		limit           int
+		speedup, capped float64
+	}{{1024, 0.25, 0.25}, {1024, 4096, 1024}, {0, 4096, 4096}} {
+		MAX_GENERATIONS_PER_FRAME = c.limit
+		ui := &UI{currentSpeedup: c.speedup}
+		if got := ui.cappedSpeedup(); got != c.capped {
+			t.Errorf("speedup %v with a cap of %v is %v, want %v", c.speedup, c.limit, got, c.capped)
+		}
+	}
+}
+
+func TestAutoSpeed(t *testing.T) {
+	for _, c := range []struct{ activity, factor float64 }{
+		{AUTO_SPEED_ACTIVITY, 1},
+		{AUTO_SPEED_ACTIVITY / 4, 4},
+		{AUTO_SPEED_ACTIVITY * 2, 0.5},
+		{0, AUTO_SPEED_MAX_FACTOR},
+		{1, 1.0 / AUTO_SPEED_MAX_FACTOR},
+	} {
+		if got := autoSpeedFactor(c.activity); got != c.factor {
+			t.Errorf("activity %v gives a speed factor of %v, want %v", c.activity, got, c.factor)
+		}
+	}
+
+	// The selected speed is multiplied by the factor only while automatic speed is on.
+	ui := &UI{speed: 2, autoSpeedFactor: 8}
+	if got := ui.getSpeedup(); got != 4 {
+		t.Errorf("speedup with automatic speed off is %v, want 4", got)
+	}
+	ui.isAutoSpeed = true
+	if got := ui.getSpeedup(); got != 32 {
+		t.Errorf("speedup with automatic speed on is %v, want 32", got)
+	}
+}
+
+func TestClearRules(t *testing.T) {
+	b, s := Ruleset{3: true, 6: true}, Ruleset{2: true, 3: true}
+	wants := map[ClearMode][2]Ruleset{
+		CLEAR_EDITED:     {b, {}},
+		CLEAR_BOTH:       {{}, {}},
+		CLEAR_TO_DEFAULT: {{3: true}, {2: true, 3: true}},
+	}
+	for mode, want := range wants {
+		ui := &UI{selectedBRules: b, selectedSRules: s}
+		ui.rulesBeingChanged = &ui.selectedSRules
+		ui.clearRules(mode)
+		if got := [2]Ruleset{ui.selectedBRules, ui.selectedSRules}; got != want {
+			t.Errorf("clear mode %v: rules are %v, want %v", mode, got, want)
+		}
+	}
+}
+
+func TestRandomRules(t *testing.T) {
+	rng := newRNG(SEED)
+	seen := map[[2]Ruleset]bool{}
+	for i := 0; i < 100; i++ {
+		b, s := randomRules(rng)
+		if b[0] {
+			t.Fatalf("random rules %v include births with no neighbours", FormatRulestring(b, s))
+		}
+		seen[[2]Ruleset{b, s}] = true
+	}
+	if len(seen) < 90 {
+		t.Errorf("only %v different rules in 100 tries", len(seen))
+	}
+}
+
+func TestRuleConstraints(t *testing.T) {
+	count := func(rs Ruleset) int {
+		n := 0
+		for _, on := range rs {
+			if on {
+				n++
+			}
+		}
+		return n
+	}
+
+	c := DEFAULT_RULE_CONSTRAINTS
+	rng := newRNG(SEED)
+	seen := map[[2]Ruleset]bool{}
+	for i := 0; i < 200; i++ {
+		b, s := c.random(rng)
+		if nb, ns := count(b), count(s); nb < 1 || nb > 3 || ns < 2 || ns > 4 || b[0] || b[1] {
+			t.Fatalf("random rules %v don't meet the constraints %+v", FormatRulestring(b, s), c)
+		}
+		seen[[2]Ruleset{b, s}] = true
+	}
+	if len(seen) < 150 {
+		t.Errorf("only %v different rules in 200 tries", len(seen))
+	}
+
+	// Raising a minimum past its maximum raises the maximum too, and there can't be more birth counts than neighbour
+	// counts births are allowed from.
+	c.adjust(0, 3)
+	if c.MinBirths != 4 || c.MaxBirths != 4 {
+		t.Errorf("births are %v to %v after raising the minimum past the maximum, want 4 to 4", c.MinBirths, c.MaxBirths)
+	}
+	c.adjust(4, 4)
+	if c.MinBirthNeighbours != 6 || c.MinBirths != 3 || c.MaxBirths != 3 {
+		t.Errorf("births are %v to %v from %v neighbours, want 3 to 3 from 6", c.MinBirths, c.MaxBirths,
+			c.MinBirthNeighbours)
+	}
+	if b, _ := c.random(rng); b != (Ruleset{6: true, 7: true, 8: true}) {
+		t.Errorf("births are %v, want the only possible B678", FormatRulestring(b, Ruleset{}))
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	s := newTestSimulation(10, 8, nil)
+	cells := [][2]int{{0, 0}, {9, 7}, {4, 3}, {5, 3}, {5, 4}}
+	for _, c := range cells {
+		s.Set(c[0], c[1], true)
+	}
+	s.Set(5, 3, false)
+	// Outside the board, so ignored.
+	s.Set(-1, 2, true)
+	s.Set(10, 2, true)
+
+	for y := 0; y < s.gridY; y++ {
+		for x := 0; x < s.gridX; x++ {
+			want := false
+			for _, c := range cells {
+				want = want || (c == [2]int{x, y} && c != [2]int{5, 3})
+			}
+			if got := s.Get(x, y); got != want {
+				t.Errorf("cell (%v, %v) is alive: %v, want %v", x, y, got, want)
+			}
+		}
+	}
+	if s.Get(-1, 2) || s.Get(10, 2) {
+		t.Error("cells outside the board should be dead")
+	}
+	if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGifMaxDim(t *testing.T) {
+	defer func(old int) { GIF_MAX_DIM = old }(GIF_MAX_DIM)
+	pal := color.Palette{color.Black, color.White}
+	img := image.NewRGBA(image.Rect(0, 0, 400, 100))
+
+	GIF_MAX_DIM = 0
+	if b := palettedFrame(img, pal).Bounds(); b.Dx() != 400 || b.Dy() != 100 {
+		t.Errorf("frame without a cap is %v, want 400x100", b.Size())
+	}
+	GIF_MAX_DIM = 100
+	if b := palettedFrame(img, pal).Bounds(); b.Dx() != 100 || b.Dy() != 25 {
+		t.Errorf("capped frame is %v, want 100x25", b.Size())
+	}
+}
+
+func TestGifDither(t *testing.T) {
+	defer func(old bool) { GIF_DITHER = old }(GIF_DITHER)
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.Gray{100}), image.Point{}, draw.Src)
+	countColors := func(pal color.Palette) int {
+		seen := map[uint8]bool{}
+		for _, ind := range palettedFrame(img, pal).Pix {
+			seen[ind] = true
+		}
+		return len(seen)
+	}
+
+	// Grey between the palette colours is dithered into a mix of them only when asked to, and only for more than two
+	// colours.
+	grey := color.Palette{color.Black, color.Gray{255}, color.Gray{128}}
+	bw := color.Palette{color.Black, color.White}
+	GIF_DITHER = false
+	if n := countColors(grey); n != 1 {
+		t.Errorf("undithered frame has %v colours, want 1", n)
+	}
+	GIF_DITHER = true
+	if n := countColors(grey); n < 2 {
+		t.Errorf("dithered frame has %v colours, want a mix", n)
+	}
+	if n := countColors(bw); n != 1 {
+		t.Errorf("black and white frame has %v colours with dithering set, want 1", n)
+	}
+}
+
+func TestStableRNGOutputs(t *testing.T) {
+	// The reference outputs of SplitMix64 seeded with 0. These must never change, or shared stable seeds break.
+	sr := NewStableRNG(0)
+	for i, want := range []uint64{0xe220a8397b1dcdaf, 0x6e789e6aa1b965f4, 0x06c45d188009454f, 0xf88bb8a8724c81ec} {
+		if got := sr.Uint64(); got != want {
+			t.Fatalf("output %v is %#x, want %#x", i, got, want)
+		}
+	}
+
+	// The numbers randomize draws to decide which cells are alive.
+	sr.Seed(42)
+	for i, want := range []int64{37706, 46145, 81929, 27882} {
+		if got := sr.Int63n(100000); got != want {
+			t.Fatalf("Int63n output %v is %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestDedupeFrames(t *testing.T) {
+	defer func(old bool) { DEDUPE_FRAMES = old }(DEDUPE_FRAMES)
+	DEDUPE_FRAMES = true
+
+	blank := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	dot := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	dot.Set(1, 2, color.White)
+
+	gs := newGifSaver(recordingFileName(Ruleset{}, Ruleset{}, "gif"), false, color.Palette{color.Black, color.White})
+	for _, img := range []image.Image{blank, blank, blank, dot, blank} {
+		gs.saveFrame(img)
+	}
+	if want := []int{3 * FRAME_DELAY, FRAME_DELAY, FRAME_DELAY}; !reflect.DeepEqual(gs.delays, want) ||
+		len(gs.frames) != len(want) {
+		t.Errorf("got %v frames with delays %v, want delays %v", len(gs.frames), gs.delays, want)
+	}
+}
+
+func TestBoomerang(t *testing.T) {
+	for _, c := range []struct{ frames, want []int }{
+		{[]int{}, []int{}},
+		{[]int{1}, []int{1}},
+		{[]int{1, 2}, []int{1, 2}},
+		{[]int{1, 2, 3, 4}, []int{1, 2, 3, 4, 3, 2}},
+	} {
+		if got := boomerang(c.frames); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("boomerang of %v is %v, want %v", c.frames, got, c.want)
+		}
+	}
+}
+
+func TestFontFallback(t *testing.T) {
+	if face := loadFontFace("does/not/exist.ttf", 72); face != basicfont.Face7x13 {
+		t.Error("a missing font file should fall back to the basic font")
+	}
+
+	bad := t.TempDir() + "/bad.ttf"
+	if err := os.WriteFile(bad, []byte("not a font"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if face := loadFontFace(bad, 72); face != basicfont.Face7x13 {
+		t.Error("an invalid font file should fall back to the basic font")
+	}
+
+	if face := loadFontFace("", 72); face == basicfont.Face7x13 {
+		t.Error("the embedded font should load")
+	}
+}
+
+func TestRandomizeIndependentOfPoolSize(t *testing.T) {
+	defer func(old int) { POOL_SIZE = old }(POOL_SIZE)
+
+	var boards []*Simulation
+	for _, poolSize := range []int{1, 16} {
+		POOL_SIZE = poolSize
+		s := newTestSimulation(100, 70, nil)
+		s.Randomize(40, 7)
+		if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+			t.Fatalf("pool size %v: %v", poolSize, err)
+		}
+		boards = append(boards, s)
+	}
+
+	if !reflect.DeepEqual(boards[0].worldGrid, boards[1].worldGrid) || !reflect.DeepEqual(boards[0].pixels, boards[1].pixels) {
+		t.Error("boards filled with 1 and 16 goroutines differ")
+	}
+	if p := population(boards[0]); p < 2400 || p > 3200 {
+		t.Errorf("board has %v live cells, expected about 40%% of 7000", p)
+	}
+}
+
+func TestStopWorkers(t *testing.T) {
+	defer func(old int) { POOL_SIZE = old }(POOL_SIZE)
+	POOL_SIZE = 8
+
+	before := runtime.NumGoroutine()
+	g := &Game{}
+	g.startWorkers()
+	if n := runtime.NumGoroutine(); n < before+POOL_SIZE {
+		t.Fatalf("%v goroutines running after starting workers, want at least %v", n, before+POOL_SIZE)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.stopWorkers()
+		g.stopWorkers() // Stopping twice must be harmless.
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stopping the workers didn't return")
+	}
+
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%v goroutines running after stopping workers, want at most %v", n, before)
+	}
+	if err := g.updateBoardAlt(); err == nil {
+		t.Error("sent tasks after the workers were stopped")
+	}
+}
+
+func TestQRCode(t *testing.T) {
+	// The error correction codewords of "HELLO WORLD" as a version 1-M code, from the worked example at
+	// thonky.com/qr-code-tutorial.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(10)); !reflect.DeepEqual(got, want) {
+		t.Errorf("error correction codewords are %v, want %v", got, want)
+	}
+
+	if got := qrFormatBits(5); got != 0b100000011001110 {
+		t.Errorf("format bits for mask 5 are %015b, want 100000011001110", got)
+	}
+	if got := 7<<12 | bchRemainder(7, 0x1F25, 12); got != 0x07C94 {
+		t.Errorf("version 7 information is %#x, want 0x7c94", got)
+	}
+
+	for _, tc := range []struct{ length, size int }{{14, 21}, {84, 37}, {85, 41}, {213, 57}} {
+		modules, err := encodeQR(make([]byte, tc.length))
+		if err != nil {
+			t.Fatalf("%v bytes: %v", tc.length, err)
+		}
+		if len(modules) != tc.size {
+			t.Errorf("%v bytes gave a %vx%[2]v code, want %vx%[3]v", tc.length, len(modules), tc.size)
+		}
+	}
+	if _, err := encodeQR(make([]byte, 214)); err == nil {
+		t.Error("encoded more bytes than a version 10 code holds")
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	// A 3x3 board drawn in full takes two lines, the second with only its top half used.
+	s := newTestSimulation(3, 3, [][2]int{{0, 0}, {1, 1}, {2, 1}, {2, 2}})
+	if got, want := s.renderText(80, 24), "▀▄▄\n  ▀"; got != want {
+		t.Errorf("3x3 board drawn as %q, want %q", got, want)
+	}
+
+	// Squeezed into a single character, each half stands for a 2x2 block, lit if any of its cells are alive.
+	s = newTestSimulation(3, 3, [][2]int{{2, 2}})
+	if got, want := s.renderText(2, 1), " ▄"; got != want {
+		t.Errorf("downscaled board drawn as %q, want %q", got, want)
+	}
+}
+
+func TestSweepSeeds(t *testing.T) {
+	template := NewSimulation(10, 6, Ruleset{3: true}, Ruleset{2: true, 3: true})
+	img := SweepSeeds(template, 50, 7, 5, 3)
+
+	// Five boards fit in a grid of 3 columns and 2 rows.
+	wantX, wantY := 3*(10+SWEEP_GAP)-SWEEP_GAP, 2*(6+SWEEP_LABEL_HEIGHT+SWEEP_GAP)-SWEEP_GAP
+	if b := img.Bounds(); b.Dx() != wantX || b.Dy() != wantY {
+		t.Fatalf("sweep image is %v, want %vx%v", b.Size(), wantX, wantY)
+	}
+
+	// The labels are wider than the boards, but stay inside their own tiles.
+	border := color.RGBAModel.Convert(BORDER_COLOR)
+	for y := 6; y < 6+SWEEP_LABEL_HEIGHT; y++ {
+		for x := 10; x < 10+SWEEP_GAP; x++ {
+			if c := img.At(x, y); c != border {
+				t.Fatalf("gap pixel (%v, %v) next to a label is %v, want the border colour", x, y, c)
+			}
+		}
+	}
+
+	// The last board, in the middle of the second row, is the one seed 11 gives.
+	s := template.blankCopy()
+	s.Randomize(50, 11)
+	for i := 0; i < 3; i++ {
+		s.Step()
+	}
+	x0, y0 := 10+SWEEP_GAP, 6+SWEEP_LABEL_HEIGHT+SWEEP_GAP
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 10; x++ {
+			if alive := img.RGBAAt(x0+x, y0+y).R != 0; alive != s.Get(x, y) {
+				t.Fatalf("cell (%v, %v) of seed 11 is drawn as alive %v, want %v", x, y, alive, s.Get(x, y))
+			}
+		}
+	}
+	if population(s) == 0 {
+		t.Error("seed 11 died out, so the test checks nothing")
+	}
+}
+
+func TestStepCRTIntensity(t *testing.T) {
+	g := &Game{crtIntensity: 0.5}
+	for _, want := range []float64{0.75, 1, 0.25, 0.5} {
+		g.stepCRTIntensity()
+		if g.crtIntensity != want {
+			t.Fatalf("CRT intensity is %v, want %v", g.crtIntensity, want)
+		}
+	}
+}
+
+func TestLoadGIFPattern(t *testing.T) {
+	// A full first frame, then a frame only covering the bottom right corner, which kills one cell and births another.
+	pal := color.Palette{color.Black, color.White, color.RGBA{255, 48, 48, 255}}
+	first := image.NewPaletted(image.Rect(0, 0, 4, 3), pal)
+	first.SetColorIndex(0, 0, 1)
+	first.SetColorIndex(3, 2, 1)
+	second := image.NewPaletted(image.Rect(2, 1, 4, 3), pal)
+	second.SetColorIndex(2, 1, 2)
+	second.SetColorIndex(3, 2, 0)
+
+	path := t.TempDir() + "/20230221_202457_B36S23.gif"
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.EncodeAll(f, &gif.GIF{Image: []*image.Paletted{first, second}, Delay: []int{2, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	p, err := LoadGIFPattern(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Pattern{{true, false, false, false}, {false, false, true, false}, {false, false, false, false}}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("pattern is %v, want %v", p, want)
+	}
+
+	if rule, ok := ruleFromFileName(path); !ok || rule != "B36/S23" {
+		t.Errorf("rule from the file name is %q, %v, want B36/S23", rule, ok)
+	}
+	for _, name := range []string{"board.gif", "B3S23_vs_B36S23.gif"} {
+		if rule, ok := ruleFromFileName(name); ok {
+			t.Errorf("file name %v gave rule %q, want none", name, rule)
+		}
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	defer func(old int) { WARMUP_GENERATIONS = old }(WARMUP_GENERATIONS)
+	WARMUP_GENERATIONS = 4
+
+	// After 4 generations a glider has moved one cell down and to the right.
+	g := newTestGame(12, 12, glider(2, 2))
+	g.warmUp()
+
+	if g.generation != 4 {
+		t.Errorf("generation is %v after warming up, want 4", g.generation)
+	}
+	for _, c := range glider(3, 3) {
+		if !g.Get(c[0], c[1]) {
+			t.Fatalf("cell %v of the moved glider is dead", c)
+		}
+	}
+	if population(&g.Simulation) != 5 {
+		t.Errorf("population is %v after warming up, want 5", population(&g.Simulation))
+	}
+}
+
+func TestRuleRegions(t *testing.T) {
+	// The right half of the board follows a rule where every live cell survives and nothing is born, so patterns there
+	// freeze. A blinker on each side, and one across the edge of the region.
+	frozen := Ruleset{0: true, 1: true, 2: true, 3: true, 4: true, 5: true, 6: true, 7: true, 8: true}
+	cells := [][2]int{{5, 4}, {6, 4}, {7, 4}, {30, 4}, {31, 4}, {32, 4}, {19, 12}, {20, 12}, {21, 12}}
+	s := newTestSimulation(40, 20, cells)
+	s.AddRuleRegion(image.Rect(20, 0, 40, 20), Ruleset{}, frozen)
+	s.Step()
+	if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][2]int{
+		// The blinker under the board's rules turns.
+		{6, 3}, {6, 4}, {6, 5},
+		// The blinker in the region stays as it is.
+		{30, 4}, {31, 4}, {32, 4},
+		// Across the edge, the cells in the region survive while the one outside dies with only one neighbour, and
+		// the cells either side of the middle aren't born since they're in the region.
+		{20, 12}, {21, 12},
+	}
+	if population(s) != len(want) {
+		t.Errorf("population is %v, want %v", population(s), len(want))
+	}
+	for _, c := range want {
+		if !s.Get(c[0], c[1]) {
+			t.Errorf("cell %v is dead", c)
+		}
+	}
+
+	// Without the region the frozen blinker turns like the other one, and the two cells left across the edge die.
+	s.ClearRuleRegions()
+	s.Step()
+	for _, c := range [][2]int{{5, 4}, {6, 4}, {7, 4}, {31, 3}, {31, 4}, {31, 5}} {
+		if !s.Get(c[0], c[1]) {
+			t.Errorf("cell %v is dead after removing the region", c)
+		}
+	}
+	if population(s) != 6 {
+		t.Errorf("population is %v after removing the region, want 6", population(s))
+	}
+}
+
+func TestFindStillLifes(t *testing.T) {
+	// The still lifes of Life in boxes of up to 9 cells.
+	want := []string{
+		"OO\nOO\n",
+		".O.\nO.O\n.O.\n",
+		".O.\nO.O\n.OO\n",
+		"O.OO\nOO.O\n",
+		".OO\nO.O\nOO.\n",
+	}
+	found := FindStillLifes(Ruleset{3: true}, Ruleset{2: true, 3: true}, 9)
+	if len(found) != len(want) {
+		t.Fatalf("found %v still lifes, want %v:\n%v", len(found), len(want), found)
+	}
+	for i, p := range found {
+		if _, key := canonical(p); key != want[i] {
+			t.Errorf("still life %v is\n%vwant\n%v", i, key, want[i])
+		}
+
+		// Each still life survives the trip through RLE.
+		parsed, err := ParseRLE(p.RLE("B3/S23"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(parsed, p) {
+			t.Errorf("still life %v is\n%vafter RLE, want\n%v", i, parsed, p)
+		}
+	}
+
+	if found := FindStillLifes(Ruleset{0: true}, Ruleset{}, 9); len(found) != 0 {
+		t.Errorf("found %v still lifes under B0, want none", len(found))
+	}
+}
+
+func TestBlockEntropy(t *testing.T) {
+	s := newTestSimulation(30, 30, nil)
+	if e := s.BlockEntropy(); e != 0 {
+		t.Errorf("entropy of an empty board is %v, want 0", e)
+	}
+
+	// Half the tiles with a block in the corner and half empty gives 1 bit.
+	for y := 0; y < 30; y += 3 {
+		for x := 0; x < 15; x += 3 {
+			s.stamp(Pattern{{true, true}, {true, true}}, x, y)
+		}
+	}
+	if e := s.BlockEntropy(); math.Abs(e-1) > 1e-9 {
+		t.Errorf("entropy of half block tiles is %v, want 1", e)
+	}
+
+	// Random noise is far more complex.
+	noise := newTestSimulation(90, 90, nil)
+	noise.Randomize(50, SEED)
+	if e := noise.BlockEntropy(); e < 7 {
+		t.Errorf("entropy of random noise is %v, want at least 7", e)
+	}
+}
+
+func TestFindShips(t *testing.T) {
+	// A glider and a block, which stays put, far apart, and a glider too close to a blinker to be sure of.
+	cells := append(glider(3, 3), [2]int{20, 20}, [2]int{21, 20}, [2]int{20, 21}, [2]int{21, 21})
+	cells = append(cells, glider(30, 5)...)
+	cells = append(cells, [2]int{34, 5}, [2]int{34, 6}, [2]int{34, 7})
+	s := newTestSimulation(40, 30, cells)
+
+	isShipByShape := map[string]bool{}
+	// The glider is recognized in every phase as it moves.
+	for gen := 0; gen < 8; gen++ {
+		ships := s.findShips(isShipByShape)
+		if len(ships) != 1 {
+			t.Fatalf("generation %v: found %v spaceships, want 1", gen, len(ships))
+		}
+		if ships[0].Dx() != 3 || ships[0].Dy() != 3 || !ships[0].In(image.Rect(3, 3, 9, 9)) {
+			t.Errorf("generation %v: spaceship is at %v, want the glider", gen, ships[0])
+		}
+		s.Step()
+	}
+
+	// Under rules where the glider shape isn't a spaceship, nothing is found.
+	s = NewSimulation(40, 30, Ruleset{3: true, 6: true}, Ruleset{2: true, 3: true, 4: true})
+	s.stamp(Pattern{{false, true, false}, {false, false, true}, {true, true, true}}, 3, 3)
+	if ships := s.findShips(map[string]bool{}); len(ships) != 0 {
+		t.Errorf("found %v spaceships under B36/S234, want none", len(ships))
+	}
+}
+
+func TestParseRulestring(t *testing.T) {
+	life := [2]Ruleset{{3: true}, {2: true, 3: true}}
+	highLife := [2]Ruleset{{3: true, 6: true}, {2: true, 3: true}}
+	for _, test := range []struct {
+		str  string
+		want [2]Ruleset
+	}{
+		{"B3/S23", life},
+		{"b3/s23", life},
+		{"S23/B3", life},
+		{"23/3", life},
+		{"B36/S23", highLife},
+		{"23/36", highLife},
+		{" B36/S23\n", highLife},
+		{"B/S", [2]Ruleset{}},
+		{"/3", [2]Ruleset{{3: true}, {}}},
+		{"B0/S012345678", [2]Ruleset{{0: true}, {true, true, true, true, true, true, true, true, true}}},
+	} {
+		b, s, err := ParseRulestring(test.str)
+		if err != nil {
+			t.Errorf("parsing %q: %v", test.str, err)
+		} else if [2]Ruleset{b, s} != test.want {
+			t.Errorf("parsing %q gives %v, want %v", test.str, [2]Ruleset{b, s}, test.want)
+		}
+	}
+
+	for _, str := range []string{"", "B3S23", "B9/S23", "B3/S2a", "B3/B23", "3/2/3", "Life"} {
+		if _, _, err := ParseRulestring(str); err == nil {
+			t.Errorf("parsing %q gives no error", str)
+		}
+	}
+}
+
+func TestFormatRulestring(t *testing.T) {
+	for _, test := range []struct {
+		bRules, sRules Ruleset
+		want           string
+	}{
+		{Ruleset{3: true}, Ruleset{2: true, 3: true}, "B3/S23"},
+		{Ruleset{6: true, 3: true}, Ruleset{3: true, 2: true}, "B36/S23"},
+		{Ruleset{}, Ruleset{}, "B/S"},
+		{Ruleset{0: true, 8: true}, Ruleset{}, "B08/S"},
+	} {
+		str := FormatRulestring(test.bRules, test.sRules)
+		if str != test.want {
+			t.Errorf("rules %v/%v are formatted as %q, want %q", test.bRules, test.sRules, str, test.want)
+		}
+		if b, s, err := ParseRulestring(str); err != nil || b != test.bRules || s != test.sRules {
+			t.Errorf("%q doesn't parse back to the same rules: %v/%v, %v", str, b, s, err)
+		}
+	}
+}
+
+func TestNextPreset(t *testing.T) {
+	// Cycling from rules which aren't a preset starts at the first, and goes through every preset before wrapping.
+	b, s := Ruleset{1: true, 2: true}, Ruleset{7: true}
+	for i := 0; i <= len(PRESETS); i++ {
+		p := nextPreset(b, s)
+		if want := PRESETS[i%len(PRESETS)]; p.Name != want.Name {
+			t.Fatalf("preset %v is %v, want %v", i, p.Name, want.Name)
+		}
+		b, s = p.BRules, p.SRules
+	}
+}
+
+func TestReflectCorners(t *testing.T) {
+	s := newTestSimulation(10, 8, [][2]int{{0, 0}, {9, 7}})
+	s.SetBoundaryMode(BOUNDARY_REFLECT)
+
+	// Each corner cell sees itself beyond both edges and beyond the corner, and its neighbours along the edges see it
+	// twice, once directly and once mirrored.
+	for _, c := range [][3]int{{0, 0, 7}, {9, 7, 7}, {1, 0, 4}, {0, 1, 4}, {1, 1, 2}, {8, 7, 4}, {9, 6, 4}} {
+		if val := s.worldGrid[(c[1]+1)*(s.gridX+2)+c[0]+1]; int(val) != c[2] {
+			t.Errorf("cell (%v, %v) has value %v, want %v", c[0], c[1], val, c[2])
+		}
+	}
+
+	// Under B3/S23 the corner cells survive, and nothing is born since no cell sees three live neighbours.
+	s.Step()
+	if !s.Get(0, 0) || !s.Get(9, 7) || population(s) != 2 {
+		t.Errorf("corner cells don't survive on their own, population %v", population(s))
+	}
+}
+
+func TestVonNeumann(t *testing.T) {
+	// Under B1/S a lone cell gives birth to the cells next to it, which are only the 4 orthogonal ones.
+	s := NewSimulation(9, 9, Ruleset{1: true}, Ruleset{})
+	s.SetMask(NEIGHBOURHOOD_VON_NEUMANN.mask())
+	s.Set(4, 4, true)
+	s.Step()
+	for _, c := range [][2]int{{4, 3}, {3, 4}, {5, 4}, {4, 5}} {
+		if !s.Get(c[0], c[1]) {
+			t.Errorf("cell %v wasn't born", c)
+		}
+	}
+	if population(s) != 4 {
+		t.Errorf("population is %v, want 4", population(s))
+	}
+
+	// The custom neighbourhood is only offered when a mask was loaded.
+	defer func(old NeighbourMask) { NEIGHBOUR_MASK = old }(NEIGHBOUR_MASK)
+	NEIGHBOUR_MASK = nil
+	if n := NEIGHBOURHOOD_VON_NEUMANN.next(); n != NEIGHBOURHOOD_MOORE {
+		t.Errorf("neighbourhood after von Neumann is %v without a mask, want Moore", n)
+	}
+	NEIGHBOUR_MASK = MOORE_MASK
+	if n := NEIGHBOURHOOD_VON_NEUMANN.next(); n != NEIGHBOURHOOD_CUSTOM {
+		t.Errorf("neighbourhood after von Neumann is %v with a mask, want custom", n)
+	}
+}
+
+func TestLtLRange1MatchesLife(t *testing.T) {
+	life, err := ParseLtLRule("R1,C0,M0,S2..3,B3,NM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for m := BoundaryMode(0); m < NUM_BOUNDARY_MODES; m++ {
+		want := newTestSimulation(37, 23, nil)
+		want.SetBoundaryMode(m)
+		want.Randomize(40, 1)
+		s := newTestSimulation(37, 23, nil)
+		s.SetBoundaryMode(m)
+		s.Randomize(40, 1)
+		s.SetLtLRule(life)
+
+		for gen := 1; gen <= 20; gen++ {
+			want.Step()
+			s.Step()
+			if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); m == BOUNDARY_DEAD && err != nil {
+				t.Fatalf("%v boundary, generation %v: %v", m, gen, err)
+			}
+			for y := 0; y < s.gridY; y++ {
+				for x := 0; x < s.gridX; x++ {
+					if s.Get(x, y) != want.Get(x, y) {
+						t.Fatalf("%v boundary, generation %v: cell (%v, %v) differs from Life", m, gen, x, y)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLtLLargeRange(t *testing.T) {
+	// Bosco's rule on a wrapping board, checked against counting every cell's neighbours directly.
+	rule, err := ParseLtLRule("R5,C0,M1,S34..58,B34..45,NM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestSimulation(30, 26, nil)
+	s.SetBoundaryMode(BOUNDARY_WRAP)
+	s.Randomize(50, 3)
+	s.SetLtLRule(rule)
+
+	for gen := 1; gen <= 5; gen++ {
+		want := make([]bool, s.gridX*s.gridY)
+		for y := 0; y < s.gridY; y++ {
+			for x := 0; x < s.gridX; x++ {
+				n := 0
+				for dy := -rule.Range; dy <= rule.Range; dy++ {
+					for dx := -rule.Range; dx <= rule.Range; dx++ {
+						if s.isAliveWithBoundary(x+dx, y+dy) {
+							n++
+						}
+					}
+				}
+				if s.Get(x, y) {
+					want[y*s.gridX+x] = n >= rule.SurvivalMin && n <= rule.SurvivalMax
+				} else {
+					want[y*s.gridX+x] = n >= rule.BirthMin && n <= rule.BirthMax
+				}
+			}
+		}
+
+		s.Step()
+		for y := 0; y < s.gridY; y++ {
+			for x := 0; x < s.gridX; x++ {
+				if s.Get(x, y) != want[y*s.gridX+x] {
+					t.Fatalf("generation %v: cell (%v, %v) is %v, want %v", gen, x, y, s.Get(x, y), want[y*s.gridX+x])
+				}
+			}
+		}
+	}
+}
+
+func TestParseLtLRule(t *testing.T) {
+	rule, err := ParseLtLRule("r5,c0,m1,s34..58,b34..45,nm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := LtLRule{Range: 5, BirthMin: 34, BirthMax: 45, SurvivalMin: 34, SurvivalMax: 58, IsCenterCounted: true}
+	if *rule != want {
+		t.Errorf("parsed %+v, want %+v", *rule, want)
+	}
+	if str := rule.String(); str != "R5,C0,M1,S34..58,B34..45,NM" {
+		t.Errorf("rule is written as %q", str)
+	}
+
+	for _, str := range []string{"", "R5", "R0,S1,B1", "R51,S1,B1", "R5,C3,S1,B1", "R5,S5..4,B1", "R5,S1,B1,NN",
+		"R5,S1,B1,X1", "R5,Sa,B1"} {
+		if _, err := ParseLtLRule(str); err == nil {
+			t.Errorf("parsing %q gives no error", str)
+		}
+	}
+}
+
+func TestGenerations(t *testing.T) {
+	for _, rule := range []string{"B2/S/3", "345/2/4", "B2/S/C8"} {
+		bRules, sRules, states, err := ParseGenerationsRule(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := NewSimulation(31, 27, bRules, sRules)
+		s.Randomize(35, 4)
+		s.SetStates(states)
+
+		// Checked against stepping a plain array of states directly, where 1 is alive, 0 is dead and anything higher
+		// is dying, counting up to states-1.
+		cells := make([]int, s.gridX*s.gridY)
+		for y := 0; y < s.gridY; y++ {
+			for x := 0; x < s.gridX; x++ {
+				if s.Get(x, y) {
+					cells[y*s.gridX+x] = 1
+				}
+			}
+		}
+		for gen := 1; gen <= 30; gen++ {
+			next := make([]int, len(cells))
+			for y := 0; y < s.gridY; y++ {
+				for x := 0; x < s.gridX; x++ {
+					n := 0
+					for dy := -1; dy <= 1; dy++ {
+						for dx := -1; dx <= 1; dx++ {
+							nx, ny := x+dx, y+dy
+							if (dx != 0 || dy != 0) && nx >= 0 && nx < s.gridX && ny >= 0 && ny < s.gridY &&
+								cells[ny*s.gridX+nx] == 1 {
+								n++
+							}
+						}
+					}
+					switch c := cells[y*s.gridX+x]; {
+					case c == 0 && bRules[n], c == 1 && sRules[n]:
+						next[y*s.gridX+x] = 1
+					case c >= 1 && c < states-1:
+						next[y*s.gridX+x] = c + 1
+					}
+				}
+			}
+			cells = next
+			s.Step()
+
+			if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+				t.Fatalf("%v, generation %v: %v", rule, gen, err)
+			}
+			for y := 0; y < s.gridY; y++ {
+				for x := 0; x < s.gridX; x++ {
+					c := cells[y*s.gridX+x]
+					dying := 0
+					if c > 1 {
+						dying = states - c
+					}
+					if s.Get(x, y) != (c == 1) || int(s.dying[(y+1)*(s.gridX+2)+x+1]) != dying {
+						t.Fatalf("%v, generation %v: cell (%v, %v) should be in state %v", rule, gen, x, y, c)
+					}
+					// Dying cells are drawn in greys between the alive and dead colours.
+					if grey := s.pixels[4*(y*s.gridX+x)]; c > 1 && (grey == 0 || grey == 255) {
+						t.Fatalf("%v, generation %v: dying cell (%v, %v) isn't drawn in grey", rule, gen, x, y)
+					}
+				}
+			}
+		}
+	}
+
+	for _, bad := range []string{"B2/S/1", "B2/S/x", "B2/S/257"} {
+		if _, _, _, err := ParseGenerationsRule(bad); err == nil {
+			t.Errorf("rule %q should be invalid", bad)
+		}
+	}
+	if _, _, states, err := ParseGenerationsRule("B3/S23"); err != nil || states != 2 {
+		t.Errorf("B3/S23 should have 2 states, got %v (%v)", states, err)
+	}
+}
+
+func TestCellsOnLine(t *testing.T) {
+	for _, c := range []struct{ from, to image.Point }{
+		{image.Pt(3, 3), image.Pt(3, 3)},
+		{image.Pt(0, 0), image.Pt(7, 2)},
+		{image.Pt(5, 9), image.Pt(1, 0)},
+		{image.Pt(2, 8), image.Pt(2, 1)},
+	} {
+		cells := cellsOnLine(c.from, c.to)
+		if cells[0] != c.from || cells[len(cells)-1] != c.to {
+			t.Fatalf("line from %v to %v goes from %v to %v", c.from, c.to, cells[0], cells[len(cells)-1])
+		}
+		// One cell per step along the longer side, each touching the last.
+		if want := intMax(abs(c.to.X-c.from.X), abs(c.to.Y-c.from.Y)) + 1; len(cells) != want {
+			t.Errorf("line from %v to %v has %v cells, want %v", c.from, c.to, len(cells), want)
+		}
+		for i := 1; i < len(cells); i++ {
+			if d := cells[i].Sub(cells[i-1]); abs(d.X) > 1 || abs(d.Y) > 1 {
+				t.Errorf("line from %v to %v jumps from %v to %v", c.from, c.to, cells[i-1], cells[i])
+			}
+		}
+	}
+}
+
+func TestZoomView(t *testing.T) {
+	g := &Game{scaleFactor: 2}
+	g.allocate(40, 30)
+	g.resetView()
+
+	// The cell under the zoom point stays there.
+	g.zoomView(21, 11, 4)
+	if x, y, _ := g.screenToCell(21, 11); x != 10 || y != 5 {
+		t.Errorf("cell (%v, %v) is under the zoom point after zooming in, want (10, 5)", x, y)
+	}
+	if g.drawScale() != 8 {
+		t.Errorf("draw scale is %v after zooming in 4x, want 8", g.drawScale())
+	}
+
+	// Zooming out around the bottom right would leave a gap at the top left, so the board's corner is held at the
+	// screen's.
+	g.zoomView(79, 59, 16)
+	g.zoomView(79, 59, 2)
+	if x, y := g.boardOffset(); x != 0 || y != 0 {
+		t.Errorf("board is drawn from (%v, %v) after zooming out, want (0, 0)", x, y)
+	}
+
+	// Zooming all the way out puts the board back where it was, wherever the zoom point.
+	g.zoomView(5, 50, 1)
+	if x, y := g.boardOffset(); x != 0 || y != 0 {
+		t.Errorf("board is drawn from (%v, %v) unzoomed, want (0, 0)", x, y)
+	}
+}
+
+func TestClampView(t *testing.T) {
+	g := &Game{scaleFactor: 2}
+	g.allocate(40, 30)
+	g.resetView()
+
+	// Unzoomed, the board can't be panned at all.
+	g.viewX, g.viewY = -15, 7
+	g.clampView()
+	if g.viewX != 0 || g.viewY != 0 {
+		t.Errorf("unzoomed view panned to (%v, %v), want (0, 0)", g.viewX, g.viewY)
+	}
+
+	// Zoomed in 2x, the board is twice the size of the area, so it can move by up to the area's size.
+	g.viewZoom = 2
+	g.viewX, g.viewY = -100, -50
+	g.clampView()
+	if g.viewX != -80 || g.viewY != -50 {
+		t.Errorf("view panned to (%v, %v), want (-80, -50)", g.viewX, g.viewY)
+	}
+}
+
+func TestSaveRLE(t *testing.T) {
+	g := newTestGame(20, 20, nil)
+	path := filepath.Join(t.TempDir(), "board.rle")
+
+	// An empty board still gives a valid header.
+	if err := g.SaveRLE(path); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "x = 0, y = 0, rule = B3/S23\n!\n" {
+		t.Errorf("empty board saved as %q", data)
+	}
+
+	// A glider and a cell well away from it, so that there are long runs and empty rows.
+	for _, c := range append(glider(2, 3), [2]int{17, 9}) {
+		g.Set(c[0], c[1], true)
+	}
+	if err := g.SaveRLE(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "x = 16, y = 7, rule = B3/S23\nbo$2bo$3o4$15bo!\n"; string(data) != want {
+		t.Errorf("board saved as %q, want %q", data, want)
+	}
+	p, err := LoadPattern(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, g.livePattern()) {
+		t.Errorf("saved board loads as\n%v", p)
+	}
+}
+
+func TestLoadRLE(t *testing.T) {
+	defer func(p Pattern, ltl *LtLRule, states int) { INIT_PATTERN, LTL_RULE, STATES = p, ltl, states }(INIT_PATTERN,
+		LTL_RULE, STATES)
+
+	path := filepath.Join(t.TempDir(), "brain.rle")
+	rle := "#N two dominoes\n#C with a comment\nx = 12, y = 2, rule = B2/S/3  \n2o10$\n\t2o8b2o!  \n"
+	if err := os.WriteFile(path, []byte(rle), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g := &Game{}
+	if err := g.LoadRLE(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatRulestring(g.bRules, g.sRules); got != "B2/S" || g.states != 3 {
+		t.Errorf("rule is %v with %v states, want B2/S with 3", got, g.states)
+	}
+	// The multi-digit run of row ends leaves 9 empty rows, past the height in the header.
+	if w, h := INIT_PATTERN.size(); w != 12 || h != 11 || !INIT_PATTERN[10][11] || INIT_PATTERN[5][0] {
+		t.Errorf("pattern loaded as\n%v", INIT_PATTERN)
+	}
+
+	// Larger than Life rules have commas, which mustn't be taken for more header fields.
+	if got := RLERule("x = 1, y = 1, rule = R5,C0,M1,S34..58,B34..45,NM\no!"); got != "R5,C0,M1,S34..58,B34..45,NM" {
+		t.Errorf("Larger than Life rule read as %q", got)
+	}
+	if got := RLERule("x = 1, y = 1\no!"); got != "" {
+		t.Errorf("header without a rule gives rule %q", got)
+	}
+}
+
+func TestSaveScreenshotPNG(t *testing.T) {
+	g := &Game{scaleFactor: 3}
+	g.allocate(10, 8)
+	g.Set(4, 2, true)
+	path := filepath.Join(t.TempDir(), "board.png")
+
+	for _, isUpscaled := range []bool{false, true} {
+		if err := g.SaveScreenshotPNG(path, isUpscaled); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		scale := 1
+		if isUpscaled {
+			scale = 3
+		}
+		if size := img.Bounds().Size(); size != image.Pt(10*scale, 8*scale) {
+			t.Errorf("screenshot is %v, want %vx%v", size, 10*scale, 8*scale)
+		}
+		// Every pixel of the live cell's square is in the live colour, and the ones around it in the dead colour.
+		if r, _, _, _ := img.At(4*scale+scale-1, 2*scale).RGBA(); r != 0xffff {
+			t.Errorf("live cell isn't drawn at scale %v", scale)
+		}
+		if r, _, _, _ := img.At(5*scale, 2*scale).RGBA(); r != 0 {
+			t.Errorf("dead cell next to the live one isn't black at scale %v", scale)
+		}
+	}
+}
+
+// A recording saver which counts the frames it's given.
+type countingSaver struct {
+	frames  int
+	written bool
+}
+
+func (cs *countingSaver) saveFrame(img image.Image) { cs.frames++ }
+
+func (cs *countingSaver) writeToFile() { cs.written = true }
+
+func TestMaxRecordingFrames(t *testing.T) {
+	defer func(old int) { MAX_RECORDING_FRAMES = old }(MAX_RECORDING_FRAMES)
+	MAX_RECORDING_FRAMES = 3
+
+	first, slot := &countingSaver{}, &countingSaver{}
+	g := &Game{slotSavers: map[int]GifSaverInterface{4: slot}}
+	g.startRecording(first)
+	frame := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	for i := 0; i < 5; i++ {
+		g.recordFrame(frame)
+	}
+	g.finishRecording()
+
+	// Both recordings stop at the limit and are written without being stopped by hand.
+	if first.frames != 3 || !first.written {
+		t.Errorf("main recording has %v frames, written: %v, want 3 frames written", first.frames, first.written)
+	}
+	if slot.frames != 3 || !slot.written {
+		t.Errorf("slot recording has %v frames, written: %v, want 3 frames written", slot.frames, slot.written)
+	}
+}
+
+func TestStepSeed(t *testing.T) {
+	g := &Game{boardSeed: 100}
+	g.stepSeed(1)
+	g.stepSeed(1)
+	g.stepSeed(-1)
+	if !g.isNextSeedSet || g.restartSeed() != 101 {
+		t.Fatalf("stepped to seed %v, want 101", g.restartSeed())
+	}
+
+	// The next board is filled from the stepped seed, the same way every time.
+	defer func(old *rand.Rand) { r = old }(r)
+	r = rand.New(rand.NewSource(SEED))
+	g.bRules, g.sRules = Ruleset{3: true}, Ruleset{2: true, 3: true}
+	g.updateTables()
+	g.SetProbabilities(1, 1)
+	g.avgStartingLiveCellPercentage = 30
+	g.allocate(20, 20)
+	g.fillBoard()
+	want := append([]int8{}, g.worldGrid...)
+	if g.boardSeed != 101 || g.isNextSeedSet {
+		t.Fatalf("board filled from seed %v, want 101 used up", g.boardSeed)
+	}
+
+	g.UseSeed(101)
+	g.allocate(20, 20)
+	g.fillBoard()
+	if !reflect.DeepEqual(g.worldGrid, want) {
+		t.Error("filling from the same seed gave a different board")
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	// A blinker, which a single step turns on its side.
+	g := newTestGame(12, 12, [][2]int{{4, 5}, {5, 5}, {6, 5}})
+	g.advance()
+
+	if g.generation != 1 {
+		t.Errorf("generation is %v after a single step, want 1", g.generation)
+	}
+	for y := 4; y <= 6; y++ {
+		if !g.Get(5, y) {
+			t.Fatalf("cell (5, %v) of the turned blinker is dead", y)
+		}
+	}
+	if err := verifyNeighbourCounts(g.gridX, g.gridY, g.worldGrid); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStablePeriod(t *testing.T) {
+	defer func(pause bool) { PAUSE_WHEN_STABLE = pause }(PAUSE_WHEN_STABLE)
+	PAUSE_WHEN_STABLE = true
+
+	for _, test := range []struct {
+		name   string
+		cells  [][2]int
+		period int
+	}{
+		{"block", [][2]int{{4, 4}, {5, 4}, {4, 5}, {5, 5}}, 1},
+		{"blinker", [][2]int{{4, 5}, {5, 5}, {6, 5}}, 2},
+	} {
+		g := newTestGame(12, 12, test.cells)
+		g.resetEvents()
+
+		for gen := 0; gen < test.period; gen++ {
+			if g.isPaused {
+				t.Fatalf("%v: paused after %v generations, before repeating", test.name, gen)
+			}
+			g.advance()
+		}
+		if !g.events.isStable || g.events.period != test.period {
+			t.Errorf("%v: stable is %v with period %v, want period %v", test.name, g.events.isStable, g.events.period, test.period)
+		}
+		if !g.isPaused {
+			t.Errorf("%v: not paused on stabilizing", test.name)
+		}
+	}
+}
+
+func TestAgeColors(t *testing.T) {
+	defer func(c [FIRST_AGE_COLOR + AGE_COLOR_STEPS][]byte) { colors = c }(colors)
+	young, old := color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 255, 255}
+	fillAgeColors(young, old)
+
+	// A block, which never changes, and a blinker, whose ends are born every generation.
+	s := newTestSimulation(20, 12, [][2]int{{2, 2}, {3, 2}, {2, 3}, {3, 3}, {10, 5}, {11, 5}, {12, 5}})
+	s.SetAgeColored(true)
+	pixel := func(x, y int) color.RGBA {
+		ind := 4 * (y*s.gridX + x)
+		return color.RGBA{s.pixels[ind], s.pixels[ind+1], s.pixels[ind+2], s.pixels[ind+3]}
+	}
+	if got := pixel(2, 2); got != young {
+		t.Errorf("new cell has colour %v, want %v", got, young)
+	}
+
+	for gen := 0; gen < AGE_COLOR_STEPS+5; gen++ {
+		s.Step()
+	}
+	if got := pixel(2, 2); got != old {
+		t.Errorf("long-lived cell has colour %v, want %v", got, old)
+	}
+	// The blinker's centre lives on, but its ends are born again every generation.
+	if got := pixel(11, 5); got != old {
+		t.Errorf("blinker centre has colour %v, want %v", got, old)
+	}
+	for _, y := range []int{4, 6} {
+		if got := pixel(11, y); got != young {
+			t.Errorf("just born blinker end has colour %v, want %v", got, young)
+		}
+	}
+	if err := verifyNeighbourCounts(s.gridX, s.gridY, s.worldGrid); err != nil {
+		t.Error(err)
+	}
+
+	s.SetAgeColored(false)
+	if got := pixel(2, 2); got != (color.RGBA{colors[0][0], colors[0][1], colors[0][2], colors[0][3]}) {
+		t.Errorf("cell has colour %v after turning off colouring by age, want the live colour", got)
+	}
+}
+
+func TestTrailPixels(t *testing.T) {
+	defer func(decay int) { TRAIL_DECAY = decay }(TRAIL_DECAY)
+	TRAIL_DECAY = 64
+
+	g := newTestGame(12, 12, [][2]int{{5, 5}})
+	g.setTrailVisible(true)
+	g.updateTrailPixels()
+	pixels := append([]byte{}, g.pixels...)
+
+	// The lone cell dies, leaving a trail which fades over the frames drawn after, but not while paused.
+	g.Step()
+	red := func() byte { return g.boardPixels()[4*(5*g.gridX+5)] }
+	var reds []byte
+	for frame := 0; frame < 5; frame++ {
+		g.updateTrailPixels()
+		reds = append(reds, red())
+	}
+	for i := 1; i < len(reds); i++ {
+		if reds[i] > reds[i-1] || (reds[i-1] > 0 && reds[i] == reds[i-1]) {
+			t.Fatalf("trail reds over frames are %v, want them to fade to 0", reds)
+		}
+	}
+	if reds[0] == 0 || reds[len(reds)-1] != 0 {
+		t.Errorf("trail reds over frames are %v, want them to start visible and fade out", reds)
+	}
+
+	g.Set(5, 5, true)
+	g.updateTrailPixels()
+	g.Set(5, 5, false)
+	g.isPaused = true
+	g.updateTrailPixels()
+	before := red()
+	g.updateTrailPixels()
+	if red() != before || before == 0 {
+		t.Errorf("trail red went from %v to %v while paused, want it to stay visible", before, red())
+	}
+
+	// The board itself is untouched by the trails.
+	g.Set(5, 5, true)
+	if !reflect.DeepEqual(g.pixels, pixels) {
+		t.Error("drawing the trails changed the board pixels")
+	}
+}
+
+func TestRewindBuffer(t *testing.T) {
+	s := newTestSimulation(30, 20, nil)
+	s.Randomize(40, 2)
+	var b rewindBuffer
+
+	var grids [][]int8
+	var pixels [][]byte
+	for gen := 0; gen < 8; gen++ {
+		grids = append(grids, append([]int8{}, s.worldGrid...))
+		pixels = append(pixels, append([]byte{}, s.pixels...))
+		b.push(s, 5)
+		s.Step()
+	}
+
+	// Only the last 5 generations are kept, each restored with its pixels and generation.
+	for gen := 7; gen >= 3; gen-- {
+		if !b.pop(s) {
+			t.Fatalf("no snapshot of generation %v", gen)
+		}
+		if s.generation != gen || !reflect.DeepEqual(s.worldGrid, grids[gen]) || !reflect.DeepEqual(s.pixels, pixels[gen]) {
+			t.Fatalf("board rewound to generation %v doesn't match", gen)
+		}
+	}
+	if b.pop(s) {
+		t.Error("rewound past the oldest snapshot")
+	}
+
+	// The rewound board carries on as it did the first time.
+	s.Step()
+	if !reflect.DeepEqual(s.worldGrid, grids[4]) {
+		t.Error("board run on from a rewound generation differs")
+	}
+}
+
+func TestPopulation(t *testing.T) {
+	for m := BoundaryMode(0); m < NUM_BOUNDARY_MODES; m++ {
+		s := newTestSimulation(41, 23, nil)
+		s.SetBoundaryMode(m)
+		s.Randomize(35, 6)
+		for gen := 0; gen < 10; gen++ {
+			if got, want := s.Population(), population(s); got != want {
+				t.Fatalf("%v boundary, generation %v: population is %v, want %v", m, gen, got, want)
+			}
+			s.Step()
+		}
+	}
+}
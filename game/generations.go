@@ -0,0 +1,65 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The most states a Generations rule can have: alive, dead and up to 254 dying states in between.
+const MAX_STATES = 256
+
+// Sets the number of states each cell can be in, as in the Generations rules like Brian's Brain (B2/S/3). Besides
+// alive and dead, a live cell which dies spends states-2 generations dying before it's dead. Dying cells aren't alive,
+// so they don't count as neighbours and the neighbour counts in worldGrid don't change, but they can't be born again
+// until they're dead. 2 states gives the usual rules. Only the usual 8 cell neighbourhood has dying states.
+func (s *Simulation) SetStates(states int) {
+	s.states = clamp(2, MAX_STATES, states)
+	for i := range s.dying {
+		if s.dying[i] > 0 {
+			s.dying[i] = 0
+			setPixel(s.pixels, s.gridX, i%(s.gridX+2)-1, i/(s.gridX+2)-1, 1)
+		}
+	}
+}
+
+// Sets the pixel of the dying cell at (x, y), 0-indexed, to a grey which darkens as the cell gets closer to dead, or
+// to the dead colour once it is, given the number of generations it has left.
+func (s *Simulation) setDyingPixel(x, y int, generationsLeft uint8) {
+	if generationsLeft == 0 {
+		setPixel(s.pixels, s.gridX, x, y, 1)
+		return
+	}
+	grey := byte(255 * int(generationsLeft) / (s.states - 1))
+	ind := 4 * (y*s.gridX + x)
+	s.pixels[ind], s.pixels[ind+1], s.pixels[ind+2], s.pixels[ind+3] = grey, grey, grey, 255
+}
+
+// Returns the rules in B/S notation, followed by the number of states for Generations rules as ParseGenerationsRule
+// reads them, e.g. B2/S/3 for Brian's Brain.
+func (s *Simulation) generationsRulestring() string {
+	rule := FormatRulestring(s.bRules, s.sRules)
+	if s.states > 2 {
+		rule += fmt.Sprintf("/%v", s.states)
+	}
+	return rule
+}
+
+// Parses a rule in any notation ParseRulestring reads, optionally followed by a third part giving the number of states
+// as in Golly's Generations rules, such as B2/S/3 or /2/3 for Brian's Brain. The number can also be written with a C in
+// front, as in B2/S/C3. Rules without a third part have the usual 2 states.
+func ParseGenerationsRule(str string) (bRules, sRules Ruleset, states int, err error) {
+	rule, states := str, 2
+	if strings.Count(str, "/") == 2 {
+		i := strings.LastIndex(str, "/")
+		rule = str[:i]
+		states, err = strconv.Atoi(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(str[i+1:])), "C"))
+		if err != nil || states < 2 || states > MAX_STATES {
+			return Ruleset{}, Ruleset{}, 0, fmt.Errorf("invalid number of states in rule %q, should be between 2 and %v",
+				str, MAX_STATES)
+		}
+	}
+
+	bRules, sRules, err = ParseRulestring(rule)
+	return bRules, sRules, states, err
+}
@@ -0,0 +1,58 @@
+package game
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// The colour the live cells of the reference pattern are drawn in over the board, and how opaque they are, from 0 to
+// 1. Faint enough that the cells under the ghost still show.
+var (
+	GHOST_COLOR = color.RGBA{255, 160, 0, 255}
+	GHOST_ALPHA = 0.4
+)
+
+// Centers the ghost of GHOST_PATTERN on the board, unless it has been moved by hand.
+func (g *Game) placeGhost() {
+	if GHOST_PATTERN == nil || g.isGhostMoved {
+		return
+	}
+	w, h := GHOST_PATTERN.size()
+	g.ghostX, g.ghostY = (g.gridX-w)/2, (g.gridY-h)/2
+}
+
+// Moves the ghost so that it's centered on the cell under the cursor.
+func (g *Game) moveGhostToCursor() {
+	x, y, ok := g.screenToCell(ebiten.CursorPosition())
+	if GHOST_PATTERN == nil || !ok {
+		return
+	}
+	w, h := GHOST_PATTERN.size()
+	g.ghostX, g.ghostY = x-w/2, y-h/2
+	g.isGhostMoved = true
+}
+
+// Draws the live cells of GHOST_PATTERN translucently over the board, for comparing the board against it. Only the
+// screen is drawn on, so the ghost doesn't show up in recordings.
+func (g *Game) drawGhost(screen *ebiten.Image) {
+	if g.ghostImg == nil {
+		w, h := GHOST_PATTERN.size()
+		g.ghostImg = ebiten.NewImage(w, h)
+		for y, row := range GHOST_PATTERN {
+			for x, alive := range row {
+				if alive {
+					g.ghostImg.Set(x, y, GHOST_COLOR)
+				}
+			}
+		}
+	}
+
+	offsetX, offsetY := g.boardOffset()
+	scale := g.drawScale()
+	options := &ebiten.DrawImageOptions{}
+	options.GeoM.Scale(scale, scale)
+	options.GeoM.Translate(float64(offsetX)+float64(g.ghostX)*scale, float64(offsetY)+float64(g.ghostY)*scale)
+	options.ColorScale.ScaleAlpha(float32(GHOST_ALPHA))
+	screen.DrawImage(g.ghostImg, options)
+}
@@ -0,0 +1,87 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Matches the rules in a recording's file name, as written by recordingFileName, e.g. B3S23 in
+// 20230221_202457_B3S23.gif.
+var fileNameRulePattern = regexp.MustCompile(`B([0-8]*)S([0-8]*)`)
+
+// Sets the game up to carry on from the last frame of the GIF at path, such as a recording. The frame becomes the
+// starting pattern, centered on the board and cut off if it doesn't fit. The rules are set to rule, or if rule is "",
+// to the rules in the GIF's file name. If the name doesn't give exactly one rule, the current rules are kept and a
+// warning is logged.
+func (g *Game) ResumeFromGIF(path, rule string) error {
+	p, err := LoadGIFPattern(path)
+	if err != nil {
+		return err
+	}
+	INIT_PATTERN = p
+
+	if rule == "" {
+		var ok bool
+		if rule, ok = ruleFromFileName(path); !ok {
+			log.Printf("can't tell the rules of %v from its name, so carrying on with %v", filepath.Base(path),
+				FormatRulestring(g.bRules, g.sRules))
+			return nil
+		}
+	}
+	bRules, sRules, err := ParseRulestring(rule)
+	if err != nil {
+		return err
+	}
+	g.useRules(bRules, sRules)
+	return nil
+}
+
+// Reads the last frame of the GIF at path as a pattern, taking the pixels which aren't dark to be live cells. Frames
+// only covering part of the GIF are drawn over the ones before them, as a GIF viewer would.
+func LoadGIFPattern(path string) (Pattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not read GIF %v: %v", path, err)
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, anim.Config.Width, anim.Config.Height))
+	for _, frame := range anim.Image {
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	}
+	return patternFromImage(canvas), nil
+}
+
+// Returns the pattern whose live cells are the pixels of img with a colour channel at least half way to full
+// brightness. That's any live or just born cell colour, even the reds and blues of a layered board, but not black.
+func patternFromImage(img image.Image) Pattern {
+	bounds := img.Bounds()
+	p := make(Pattern, bounds.Dy())
+	for y := range p {
+		p[y] = make([]bool, bounds.Dx())
+		for x := range p[y] {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			p[y][x] = intMax(int(r), intMax(int(g), int(b))) >= 0x8000
+		}
+	}
+	return p
+}
+
+// Returns the rules in the B/S notation which the file name at path gives, and false if it gives none or more than one.
+func ruleFromFileName(path string) (string, bool) {
+	matches := fileNameRulePattern.FindAllStringSubmatch(filepath.Base(path), -1)
+	if len(matches) != 1 {
+		return "", false
+	}
+	return "B" + matches[0][1] + "/S" + matches[0][2], true
+}
@@ -1,7 +1,7 @@
 package game
 
 import (
-	"errors"
+	"bytes"
 	"fmt"
 	"image"
 	"image/color"
@@ -9,23 +9,76 @@ import (
 	"image/gif"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
+
+	xdraw "golang.org/x/image/draw"
 )
 
 const (
-	// The folder to which GIFs will be saved.
-	IMAGE_FOLDER = "output"
-
 	// Delay between frames in hundredths of seconds, approximating the 1/60 * 100 ≈ 1.667 required for 60 FPS.
 	FRAME_DELAY = 2
+
+	// The longest delay a GIF frame can have, in hundredths of seconds.
+	MAX_FRAME_DELAY = 0xffff
+
+	// How many pixels of empty space to leave around the live cells when auto-cropping a recording.
+	CROP_MARGIN = 8
 )
 
+// Common interface of the recording savers, so that the game doesn't need to care which format is being recorded.
 type GifSaverInterface interface {
 	saveFrame(img image.Image)
 	writeToFile()
 }
 
+// Gives a recording a filename which combines a timestamp and a simulation ruleset string.
+func recordingFileName(bRules, sRules Ruleset, extension string) string {
+	bNums, sNums := "", ""
+	for i := 0; i <= 8; i++ {
+		numStr := strconv.Itoa(i)
+		if bRules[i] {
+			bNums += numStr
+		}
+		if sRules[i] {
+			sNums += numStr
+		}
+	}
+	// Example filename: 20230221_202457_B3S23.gif (where B3S23 represents the ruleset)
+	return fmt.Sprintf("%v_B%vS%v.%v", time.Now().Format("20060102_150405"), bNums, sNums, extension)
+}
+
+// Creates a saver for the recording format selected with RECORD_FORMAT and STREAM_RECORDINGS. GIFs are saved with the
+// given palette, while APNGs are always full colour. Recordings which aren't streamed play back and forth if
+// BOOMERANG_RECORDINGS is set. Recordings in a slot, i.e. with a slot above 0, have the slot and which take in the slot
+// they are in their file name, so that recordings from different slots, or started within a second in the same slot,
+// never share a name.
+func newRecordingSaver(bRules, sRules Ruleset, crop bool, palette color.Palette, slot, take int) GifSaverInterface {
+	extension := "gif"
+	if RECORD_FORMAT == "apng" {
+		extension = "png"
+	}
+	fileName := recordingFileName(bRules, sRules, extension)
+	if slot > 0 {
+		fileName = strings.TrimSuffix(fileName, "."+extension) + fmt.Sprintf("_slot%v_%v.%v", slot, take, extension)
+	}
+
+	if RECORD_FORMAT == "apng" {
+		saver := newApngSaver(fileName, crop)
+		saver.boomerang = BOOMERANG_RECORDINGS
+		return &saver
+	}
+	if STREAM_RECORDINGS {
+		saver := newStreamingGifSaver(fileName, palette)
+		return &saver
+	}
+	saver := newGifSaver(fileName, crop, palette)
+	saver.boomerang = BOOMERANG_RECORDINGS
+	return &saver
+}
+
 type GifSaver struct {
 	// The filename to which the GifSaver will save the GIF file.
 	fileName string
@@ -37,27 +90,19 @@ type GifSaver struct {
 
 	// The successive delay times, one per frame. In practice this is always FRAME_DELAY.
 	delays []int
-}
 
-func newGifSaver(bRules, sRules Ruleset) GifSaver {
-	res := GifSaver{}
+	// Whether the frames should be cropped to the bounding box of the live cells before writing the GIF.
+	crop bool
 
-	// Give the run a filename which combines a timestamp and a simulation ruleset string.
-	bNums, sNums := "", ""
-	for i := 0; i <= 8; i++ {
-		numStr := strconv.Itoa(i)
-		if bRules[i] {
-			bNums += numStr
-		}
-		if sRules[i] {
-			sNums += numStr
-		}
-	}
-	// Example filename: 20230221_202457_B3S23.gif (where B3S23 represents the ruleset)
-	res.fileName = fmt.Sprintf("%v_B%vS%v.gif", time.Now().Format("20060102_150405"), bNums, sNums)
+	// Whether the GIF plays the frames forwards and then backwards, see boomerang.
+	boomerang bool
+}
 
-	// The pallette for our GIFs is always black and white.
-	res.palette = color.Palette{color.Black, color.White}
+func newGifSaver(fileName string, crop bool, palette color.Palette) GifSaver {
+	res := GifSaver{fileName: fileName, crop: crop}
+
+	// The pallette for our GIFs is black and white, unless the frames have a background flattened into them.
+	res.palette = palette
 
 	res.frames = []*image.Paletted{}
 	res.delays = []int{}
@@ -68,9 +113,14 @@ func newGifSaver(bRules, sRules Ruleset) GifSaver {
 func (gs *GifSaver) saveFrame(img image.Image) {
 
 	// Created a paletted image from the simulation board image.
-	bounds := img.Bounds()
-	dst := image.NewPaletted(bounds, gs.palette)
-	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	dst := palettedFrame(img, gs.palette)
+
+	// A frame which is the same as the last one just shows the last one for longer.
+	if DEDUPE_FRAMES && len(gs.frames) > 0 && isSameFrame(gs.frames[len(gs.frames)-1], dst) &&
+		gs.delays[len(gs.delays)-1]+FRAME_DELAY <= MAX_FRAME_DELAY {
+		gs.delays[len(gs.delays)-1] += FRAME_DELAY
+		return
+	}
 
 	// Add the image to our frames.
 	gs.frames = append(gs.frames, dst)
@@ -78,29 +128,149 @@ func (gs *GifSaver) saveFrame(img image.Image) {
 }
 
 func (gs *GifSaver) writeToFile() {
-	// Create the image directory if it doesn't exist.
-	if _, err := os.Stat(IMAGE_FOLDER); errors.Is(err, os.ErrNotExist) {
-		err := os.Mkdir(IMAGE_FOLDER, os.ModePerm)
-		if err != nil {
-			log.Fatal(fmt.Errorf("could not create image directory: %v", err))
-		}
-	}
+	f := createOutputFile(gs.fileName)
+	defer f.Close()
 
-	// Open the file which we'll be writing to.
-	path := fmt.Sprintf("%v/%v", IMAGE_FOLDER, gs.fileName)
-	f, err := os.Create(path)
-	if err != nil {
-		log.Fatal(err)
+	frames, delays := gs.frames, gs.delays
+	if gs.crop {
+		frames = cropToLiveCells(frames, CROP_MARGIN)
+	}
+	if gs.boomerang {
+		frames, delays = boomerang(frames), boomerang(delays)
 	}
-	defer f.Close()
 
 	// Write the GIF to the opened file.
-	err = gif.EncodeAll(f, &gif.GIF{
-		Image:     gs.frames,
-		Delay:     gs.delays,
+	err := gif.EncodeAll(f, &gif.GIF{
+		Image:     frames,
+		Delay:     delays,
 		LoopCount: 0,
 	})
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// Returns the frames (or their delays) followed by the same frames in reverse, so that a looping animation plays
+// forwards then backwards and loops without a jump. The first and last frames aren't repeated, so that they're not
+// shown twice in a row.
+func boomerang[T any](frames []T) []T {
+	res := append([]T{}, frames...)
+	for i := len(frames) - 2; i > 0; i-- {
+		res = append(res, frames[i])
+	}
+	return res
+}
+
+// Creates the file with the given name in the output directory, creating the directory first if it doesn't exist.
+func createOutputFile(fileName string) *os.File {
+	if err := os.MkdirAll(OUTPUT_DIR, os.ModePerm); err != nil {
+		log.Fatal(fmt.Errorf("could not create output directory: %v", err))
+	}
+
+	f, err := os.Create(outputPath(fileName))
+	if err != nil {
+		log.Fatal(fmt.Errorf("could not create output file: %v", err))
+	}
+	return f
+}
+
+// Returns where a file with the given name is written: in the output directory, unless the name is an absolute path.
+func outputPath(fileName string) string {
+	if filepath.IsAbs(fileName) {
+		return fileName
+	}
+	return filepath.Join(OUTPUT_DIR, fileName)
+}
+
+// Creates the output directory if it doesn't exist and checks that files can be written to it, so that a bad directory
+// is reported at startup rather than when the first recording is saved.
+func PrepareOutputDir() error {
+	if err := os.MkdirAll(OUTPUT_DIR, os.ModePerm); err != nil {
+		return fmt.Errorf("could not create output directory: %v", err)
+	}
+	f, err := os.CreateTemp(OUTPUT_DIR, ".write-test-*")
+	if err != nil {
+		return fmt.Errorf("output directory %v is not writable: %v", OUTPUT_DIR, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Converts a captured frame to a paletted image for a GIF, first downscaling it if it's larger than GIF_MAX_DIM.
+// Downscaled frames have colours in between the palette colours, which are dithered so that areas of sparse cells
+// don't just vanish. Frames with more than two colours are dithered too if GIF_DITHER is set.
+func palettedFrame(img image.Image, palette color.Palette) *image.Paletted {
+	var drawer draw.Drawer = draw.Src
+	if GIF_DITHER && len(palette) > 2 {
+		drawer = draw.FloydSteinberg
+	}
+	if scaled, ok := capFrameSize(img); ok {
+		img, drawer = scaled, draw.FloydSteinberg
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewPaletted(image.Rect(0, 0, bounds.Dx(), bounds.Dy()), palette)
+	drawer.Draw(dst, dst.Bounds(), img, bounds.Min)
+	return dst
+}
+
+// Returns whether two paletted frames have the same size and pixels.
+func isSameFrame(a, b *image.Paletted) bool {
+	return a.Rect.Size() == b.Rect.Size() && bytes.Equal(a.Pix, b.Pix)
+}
+
+// Downscales a frame so that neither side is longer than GIF_MAX_DIM, keeping its aspect ratio. Returns false if the
+// frame is small enough already or there's no cap.
+func capFrameSize(img image.Image) (image.Image, bool) {
+	b := img.Bounds()
+	longest := intMax(b.Dx(), b.Dy())
+	if GIF_MAX_DIM <= 0 || longest <= GIF_MAX_DIM {
+		return nil, false
+	}
+
+	// Copy the frame first, since the scaler reads the source pixel by pixel and that's slow for an ebiten.Image.
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	w, h := intMax(1, b.Dx()*GIF_MAX_DIM/longest), intMax(1, b.Dy()*GIF_MAX_DIM/longest)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+	return dst, true
+}
+
+// Returns the smallest rectangle containing every live (white) pixel of every frame, or an empty rectangle if there are
+// no live pixels at all.
+func liveCellBounds(frames []*image.Paletted) image.Rectangle {
+	box := image.Rectangle{}
+	for _, frame := range frames {
+		b := frame.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if frame.ColorIndexAt(x, y) != 0 {
+					box = box.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+		}
+	}
+	return box
+}
+
+// Crops all frames to the bounding box of their live cells, extended by margin pixels on each side. The same crop is
+// applied to every frame so that the GIF keeps a fixed size. If no frame has any live cells the frames are returned
+// unchanged.
+func cropToLiveCells(frames []*image.Paletted, margin int) []*image.Paletted {
+	box := liveCellBounds(frames)
+	if box.Empty() {
+		return frames
+	}
+	box = box.Inset(-margin).Intersect(frames[0].Bounds())
+
+	res := make([]*image.Paletted, len(frames))
+	for i, frame := range frames {
+		// Copy into a new image so that the cropped frame starts at (0, 0), as the GIF encoder expects.
+		dst := image.NewPaletted(image.Rect(0, 0, box.Dx(), box.Dy()), frame.Palette)
+		draw.Draw(dst, dst.Bounds(), frame, box.Min, draw.Src)
+		res[i] = dst
+	}
+	return res
+}
@@ -0,0 +1,223 @@
+package game
+
+import (
+	"bufio"
+	"compress/lzw"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"log"
+	"os"
+)
+
+// How many captured frames can be waiting to be encoded before saveFrame blocks.
+const STREAM_BUFFER_FRAMES = 8
+
+// A GIF saver which writes each frame to disk as soon as it is captured instead of keeping all frames in memory until
+// the recording stops, so that the length of a recording isn't limited by memory. Go's image/gif can only encode a
+// whole GIF at once, so the GIF blocks are written by hand. The frames are encoded on a separate goroutine so that
+// capturing a frame doesn't stall drawing. Cropping to the live cells needs all frames up front, so it isn't supported.
+type StreamingGifSaver struct {
+	fileName string
+
+	palette color.Palette
+
+	// Frames waiting to be encoded, and a channel which is closed once the encoding goroutine has finished.
+	frames chan streamedFrame
+	done   chan struct{}
+
+	// With DEDUPE_FRAMES, the last captured frame is held back until a different one arrives, so that its delay can
+	// still be extended. It's nil until the first frame.
+	pending *streamedFrame
+}
+
+// A frame waiting to be encoded, along with how long it's shown for in hundredths of seconds.
+type streamedFrame struct {
+	img   *image.Paletted
+	delay int
+}
+
+func newStreamingGifSaver(fileName string, palette color.Palette) StreamingGifSaver {
+	res := StreamingGifSaver{
+		fileName: fileName,
+		palette:  palette,
+		frames:   make(chan streamedFrame, STREAM_BUFFER_FRAMES),
+		done:     make(chan struct{}),
+	}
+
+	f := createOutputFile(res.fileName)
+	go res.encodeFrames(f)
+
+	return res
+}
+
+func (gs *StreamingGifSaver) saveFrame(img image.Image) {
+	frame := streamedFrame{img: palettedFrame(img, gs.palette), delay: FRAME_DELAY}
+	if !DEDUPE_FRAMES {
+		gs.frames <- frame
+		return
+	}
+
+	if gs.pending != nil && isSameFrame(gs.pending.img, frame.img) && gs.pending.delay+FRAME_DELAY <= MAX_FRAME_DELAY {
+		gs.pending.delay += FRAME_DELAY
+		return
+	}
+	if gs.pending != nil {
+		gs.frames <- *gs.pending
+	}
+	gs.pending = &frame
+}
+
+func (gs *StreamingGifSaver) writeToFile() {
+	// The frames have already been written apart from any held back one, so we only need to let the encoder finish up.
+	if gs.pending != nil {
+		gs.frames <- *gs.pending
+	}
+	close(gs.frames)
+	<-gs.done
+}
+
+// Writes frames to f as they arrive until the frames channel is closed, then finishes the GIF and closes the file.
+func (gs *StreamingGifSaver) encodeFrames(f *os.File) {
+	defer close(gs.done)
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	headerWritten := false
+	for frame := range gs.frames {
+		if !headerWritten {
+			if err := writeGifHeader(w, frame.img.Bounds().Dx(), frame.img.Bounds().Dy(), gs.palette); err != nil {
+				log.Fatal(err)
+			}
+			headerWritten = true
+		}
+		if err := writeGifFrame(w, frame.img, len(gs.palette), frame.delay); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// A GIF needs at least the header, even if no frames were captured.
+	if !headerWritten {
+		if err := writeGifHeader(w, 1, 1, gs.palette); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := w.WriteByte(0x3b); err != nil { // Trailer.
+		log.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Returns how many bits are needed per pixel for a palette of the given size. GIFs need at least 1.
+func gifColorBits(paletteSize int) int {
+	bits := 1
+	for 1<<bits < paletteSize {
+		bits++
+	}
+	return bits
+}
+
+// Writes the GIF header, the global colour table and an application extension which makes the GIF loop forever.
+func writeGifHeader(w io.Writer, width, height int, palette color.Palette) error {
+	bits := gifColorBits(len(palette))
+
+	header := []byte("GIF89a")
+	header = binary.LittleEndian.AppendUint16(header, uint16(width))
+	header = binary.LittleEndian.AppendUint16(header, uint16(height))
+	// Global colour table present, with 2^bits entries. No background colour or pixel aspect ratio.
+	header = append(header, 0x80|byte(bits-1)<<4|byte(bits-1), 0, 0)
+
+	for i := 0; i < 1<<bits; i++ {
+		c := color.RGBAModel.Convert(color.Black).(color.RGBA)
+		if i < len(palette) {
+			c = color.RGBAModel.Convert(palette[i]).(color.RGBA)
+		}
+		header = append(header, c.R, c.G, c.B)
+	}
+
+	// The NETSCAPE2.0 extension with a loop count of 0, i.e. loop forever.
+	header = append(header, 0x21, 0xff, 0x0b)
+	header = append(header, "NETSCAPE2.0"...)
+	header = append(header, 0x03, 0x01, 0x00, 0x00, 0x00)
+
+	_, err := w.Write(header)
+	return err
+}
+
+// Writes one frame: a graphic control extension holding the frame delay, followed by the image itself.
+func writeGifFrame(w io.Writer, frame *image.Paletted, paletteSize, delay int) error {
+	b := frame.Bounds()
+
+	block := []byte{0x21, 0xf9, 0x04, 0x00}
+	block = binary.LittleEndian.AppendUint16(block, uint16(delay))
+	block = append(block, 0x00, 0x00)
+
+	// Image descriptor at (0, 0) with no local colour table.
+	block = append(block, 0x2c, 0, 0, 0, 0)
+	block = binary.LittleEndian.AppendUint16(block, uint16(b.Dx()))
+	block = binary.LittleEndian.AppendUint16(block, uint16(b.Dy()))
+	block = append(block, 0x00)
+
+	// The LZW minimum code size, which can't be less than 2.
+	litWidth := gifColorBits(paletteSize)
+	if litWidth < 2 {
+		litWidth = 2
+	}
+	block = append(block, byte(litWidth))
+	if _, err := w.Write(block); err != nil {
+		return err
+	}
+
+	bw := &gifBlockWriter{w: w}
+	lw := lzw.NewWriter(bw, lzw.LSB, litWidth)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		if _, err := lw.Write(frame.Pix[frame.PixOffset(b.Min.X, y):][:b.Dx()]); err != nil {
+			return err
+		}
+	}
+	if err := lw.Close(); err != nil {
+		return err
+	}
+	return bw.close()
+}
+
+// Splits the LZW compressed image data into the sub-blocks of at most 255 bytes GIFs store it in.
+type gifBlockWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (bw *gifBlockWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		bw.buf = append(bw.buf, b)
+		if len(bw.buf) == 255 {
+			if err := bw.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (bw *gifBlockWriter) flush() error {
+	if len(bw.buf) == 0 {
+		return nil
+	}
+	if _, err := bw.w.Write(append([]byte{byte(len(bw.buf))}, bw.buf...)); err != nil {
+		return err
+	}
+	bw.buf = bw.buf[:0]
+	return nil
+}
+
+// Writes any remaining data and the zero-length block which ends the image data.
+func (bw *gifBlockWriter) close() error {
+	if err := bw.flush(); err != nil {
+		return err
+	}
+	_, err := bw.w.Write([]byte{0})
+	return err
+}
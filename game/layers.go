@@ -0,0 +1,73 @@
+package game
+
+import "image/color"
+
+// Sets up the second layer for a new board, if LAYER_ENABLED is set: a board starting from the same cells as the main
+// one but evolving under LAYER_B_RULES and LAYER_S_RULES, drawn over it in its own colour. Must be called after the
+// main board has been filled.
+func (g *Game) initializeLayer() {
+	if !LAYER_ENABLED {
+		g.layer = nil
+		return
+	}
+
+	g.layer = g.blankCopy()
+	g.layer.bRules, g.layer.sRules = LAYER_B_RULES, LAYER_S_RULES
+	g.layer.updateTables()
+	g.layer.isTwoTone = false
+
+	// Copying the cells rather than refilling from the seed also covers tiled and painted boards.
+	for y := 0; y < g.gridY; y++ {
+		for x := 0; x < g.gridX; x++ {
+			if g.Get(x, y) {
+				g.layer.setCell(x, y, true)
+			}
+		}
+	}
+	if g.layer.mask != nil {
+		g.layer.recountMasked()
+	}
+	g.layer.noiseSeed = g.noiseSeed
+}
+
+// Returns the colour of a cell given whether it's alive on the main board and on the layer. Cells alive on both get
+// the sum of the two layer colours, so that red and blue overlap in purple.
+func layerColor(isAlive, isLayerAlive bool) color.RGBA {
+	res := color.RGBA{0, 0, 0, 255}
+	for i, alive := range []bool{isAlive, isLayerAlive} {
+		if alive {
+			c := LAYER_COLORS[i]
+			res.R = uint8(intMin(255, int(res.R)+int(c.R)))
+			res.G = uint8(intMin(255, int(res.G)+int(c.G)))
+			res.B = uint8(intMin(255, int(res.B)+int(c.B)))
+		}
+	}
+	return res
+}
+
+// Fills layerPixels with the main board and the layer blended together.
+func (g *Game) updateLayerPixels() {
+	if len(g.layerPixels) != len(g.pixels) {
+		g.layerPixels = make([]byte, len(g.pixels))
+	}
+
+	var blend [4][4]byte
+	for i := range blend {
+		c := layerColor(i&1 == 1, i&2 == 2)
+		blend[i] = [4]byte{c.R, c.G, c.B, 255}
+	}
+
+	for y := 0; y < g.gridY; y++ {
+		for x := 0; x < g.gridX; x++ {
+			ind := (y+1)*(g.gridX+2) + x + 1
+			i := g.worldGrid[ind]&1 | (g.layer.worldGrid[ind]&1)<<1
+			copy(g.layerPixels[4*(y*g.gridX+x):], blend[i][:])
+		}
+	}
+}
+
+// Returns the palette for recordings of the blended layers: black for cells dead on both boards and one colour for
+// each of the other combinations.
+func layerPalette() color.Palette {
+	return color.Palette{layerColor(false, false), layerColor(true, false), layerColor(false, true), layerColor(true, true)}
+}
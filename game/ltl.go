@@ -0,0 +1,167 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The largest radius a Larger than Life rule can have.
+const MAX_LTL_RANGE = 50
+
+// A Larger than Life rule, where each cell counts the live cells within Range cells of it in either direction (a
+// (2*Range+1) by (2*Range+1) square), and is born or survives when that count is within a range. Range 1 gives the
+// usual Life-like rules, with interval birth and survival conditions.
+type LtLRule struct {
+	Range int
+
+	// The inclusive ranges of counts for which a dead cell is born and a live cell survives.
+	BirthMin, BirthMax       int
+	SurvivalMin, SurvivalMax int
+
+	// Whether a cell counts itself, as in Golly's M1.
+	IsCenterCounted bool
+}
+
+// Parses a Larger than Life rule in Golly's notation, such as R5,C0,M1,S34..58,B34..45,NM for Bosco's rule: R gives
+// the range, M1 or M0 whether a cell counts itself, and S and B the ranges of counts for survival and birth. Only 2
+// states (C0 or C2) and the square neighbourhood (NM) are supported. C, M and N can be left out.
+func ParseLtLRule(str string) (*LtLRule, error) {
+	rule := &LtLRule{}
+	isSet := map[byte]bool{}
+	for _, part := range strings.Split(strings.ToUpper(strings.TrimSpace(str)), ",") {
+		if len(part) < 2 {
+			return nil, fmt.Errorf("invalid Larger than Life rule %q, should look like R5,C0,M1,S34..58,B34..45,NM", str)
+		}
+		key, value := part[0], part[1:]
+		isSet[key] = true
+
+		var err error
+		switch key {
+		case 'R':
+			rule.Range, err = strconv.Atoi(value)
+			if err == nil && (rule.Range < 1 || rule.Range > MAX_LTL_RANGE) {
+				err = fmt.Errorf("range %v should be between 1 and %v", rule.Range, MAX_LTL_RANGE)
+			}
+		case 'C':
+			if value != "0" && value != "2" {
+				err = fmt.Errorf("only 2 states are supported, so C should be C0 or C2")
+			}
+		case 'M':
+			if value != "0" && value != "1" {
+				err = fmt.Errorf("M should be M0 or M1")
+			}
+			rule.IsCenterCounted = value == "1"
+		case 'S':
+			rule.SurvivalMin, rule.SurvivalMax, err = parseCountRange(value)
+		case 'B':
+			rule.BirthMin, rule.BirthMax, err = parseCountRange(value)
+		case 'N':
+			if value != "M" {
+				err = fmt.Errorf("only the square neighbourhood is supported, so N should be NM")
+			}
+		default:
+			err = fmt.Errorf("unknown part %q", part)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Larger than Life rule %q: %v", str, err)
+		}
+	}
+
+	for _, key := range []byte("RSB") {
+		if !isSet[key] {
+			return nil, fmt.Errorf("invalid Larger than Life rule %q, missing %c", str, key)
+		}
+	}
+	return rule, nil
+}
+
+// Parses an inclusive range of counts like 34..58, or a single count like 3.
+func parseCountRange(str string) (int, int, error) {
+	minStr, maxStr, ok := strings.Cut(str, "..")
+	if !ok {
+		maxStr = minStr
+	}
+	min, err := strconv.Atoi(minStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid count range %q", str)
+	}
+	max, err := strconv.Atoi(maxStr)
+	if err != nil || min < 0 || max < min {
+		return 0, 0, fmt.Errorf("invalid count range %q", str)
+	}
+	return min, max, nil
+}
+
+// Returns the rule in Golly's notation, as parsed by ParseLtLRule.
+func (r LtLRule) String() string {
+	m := 0
+	if r.IsCenterCounted {
+		m = 1
+	}
+	return fmt.Sprintf("R%v,C0,M%v,S%v..%v,B%v..%v,NM", r.Range, m, r.SurvivalMin, r.SurvivalMax, r.BirthMin,
+		r.BirthMax)
+}
+
+// Makes the simulation follow the given Larger than Life rule instead of its birth and survival rules and mask, or go
+// back to them if rule is nil.
+func (s *Simulation) SetLtLRule(rule *LtLRule) {
+	s.ltl = rule
+	s.ltlSums = nil
+}
+
+// Updates the board under the Larger than Life rule. The incremental counting in updateRange only works for the cells
+// directly around a cell, and recounting every cell's neighbours from scratch as with a mask would take (2*Range+1)^2
+// steps per cell. Instead we build a summed-area table of the live cells, where each entry is the number of live cells
+// above and to the left of it, from which the count for any square is found with 4 lookups whatever the range.
+//
+// The table covers the board plus a margin of Range cells on every side, filled in by the boundary mode, which plays
+// the part of the border around worldGrid. worldGrid itself keeps its 1 cell border and holds the usual 8 cell
+// neighbour counts, recomputed after each generation, so the rest of the game (and verifyNeighbourCounts) sees a
+// consistent board.
+func (s *Simulation) updateBoardLtL() {
+	r := s.ltl.Range
+	width, height := s.gridX+2*r, s.gridY+2*r
+	// The table has an extra row and column of zeros at the top and left, so that sums starting at the edge of the
+	// padded board don't need special cases.
+	rowLen := width + 1
+	if len(s.ltlSums) != rowLen*(height+1) {
+		s.ltlSums = make([]int32, rowLen*(height+1))
+	}
+
+	// First the sums along each row, which are independent of each other, then down each column.
+	s.forRanges(height, func(minY, maxY int) {
+		for y := minY; y <= maxY; y++ {
+			sum := int32(0)
+			for x := 0; x < width; x++ {
+				if s.isAliveWithBoundary(x-r, y-r) {
+					sum++
+				}
+				s.ltlSums[(y+1)*rowLen+x+1] = sum
+			}
+		}
+	})
+	s.forRanges(width, func(minX, maxX int) {
+		for y := 1; y < height; y++ {
+			for x := minX + 1; x <= maxX+1; x++ {
+				s.ltlSums[(y+1)*rowLen+x] += s.ltlSums[y*rowLen+x]
+			}
+		}
+	})
+
+	// Each cell's new state only depends on the table, which the update doesn't change.
+	s.updateInPlace(func(ind, i, j int, isAlive bool) (born, survives bool) {
+		// The square from (j-1-r, i-1-r) to (j-1+r, i-1+r) on the board is from (j-1, i-1) to (j-1+2r, i-1+2r) on the
+		// padded board, and so between these corners in the table.
+		top, bottom := (i-1)*rowLen, (i+2*r)*rowLen
+		left, right := j-1, j+2*r
+		n := int(s.ltlSums[bottom+right] - s.ltlSums[top+right] - s.ltlSums[bottom+left] + s.ltlSums[top+left])
+		if isAlive && !s.ltl.IsCenterCounted {
+			n--
+		}
+		return n >= s.ltl.BirthMin && n <= s.ltl.BirthMax, n >= s.ltl.SurvivalMin && n <= s.ltl.SurvivalMax
+	})
+
+	s.recountWith(MOORE_MASK)
+	s.generation++
+}
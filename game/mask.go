@@ -0,0 +1,281 @@
+package game
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// A custom neighbourhood, given as the offsets (dx, dy) from a cell of the cells which count as its neighbours.
+type NeighbourMask [][2]int
+
+// The most neighbours a mask can have, since a cell's value 2*neighbours+1 has to fit in an int8.
+const MAX_MASK_NEIGHBOURS = 63
+
+// The usual 8 cell neighbourhood as a mask. Running with it gives the same results as running without a mask, only
+// slower.
+var MOORE_MASK = NeighbourMask{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}, {1, 0}, {-1, 1}, {0, 1}, {1, 1}}
+
+// The von Neumann neighbourhood of the 4 orthogonally adjacent cells. Rules then only use neighbour counts 0 to 4.
+var VON_NEUMANN_MASK = NeighbourMask{{0, -1}, {-1, 0}, {1, 0}, {0, 1}}
+
+// The neighbourhoods which can be picked in the pause menu.
+type Neighbourhood int
+
+const (
+	// The usual 8 surrounding cells.
+	NEIGHBOURHOOD_MOORE Neighbourhood = iota
+
+	// The 4 orthogonally adjacent cells.
+	NEIGHBOURHOOD_VON_NEUMANN
+
+	// The neighbourhood loaded with -mask, which can only be picked if one was loaded.
+	NEIGHBOURHOOD_CUSTOM
+
+	// The number of neighbourhoods, for cycling through them.
+	NUM_NEIGHBOURHOODS
+)
+
+func (n Neighbourhood) String() string {
+	switch n {
+	case NEIGHBOURHOOD_VON_NEUMANN:
+		return "von Neumann (4 cells)"
+	case NEIGHBOURHOOD_CUSTOM:
+		return fmt.Sprintf("custom (%v cells)", len(NEIGHBOUR_MASK))
+	default:
+		return "Moore (8 cells)"
+	}
+}
+
+// Returns the mask for the neighbourhood, or nil for the usual 8 cell one, which is faster without a mask.
+func (n Neighbourhood) mask() NeighbourMask {
+	switch n {
+	case NEIGHBOURHOOD_VON_NEUMANN:
+		return VON_NEUMANN_MASK
+	case NEIGHBOURHOOD_CUSTOM:
+		return NEIGHBOUR_MASK
+	default:
+		return nil
+	}
+}
+
+// Returns the neighbourhood after this one, skipping the custom one if no mask was loaded.
+func (n Neighbourhood) next() Neighbourhood {
+	n = (n + 1) % NUM_NEIGHBOURHOODS
+	if n == NEIGHBOURHOOD_CUSTOM && NEIGHBOUR_MASK == nil {
+		n = (n + 1) % NUM_NEIGHBOURHOODS
+	}
+	return n
+}
+
+// Loads a neighbour mask from a file, see ParseMask for the format.
+func LoadMask(path string) (NeighbourMask, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMask(string(data))
+}
+
+// Parses a neighbour mask drawn as a grid with an odd number of rows and columns, where the middle of the grid is the
+// cell itself, # marks a neighbour and . marks a cell which isn't one. For example, the von Neumann neighbourhood is
+//
+//	.#.
+//	#.#
+//	.#.
+//
+// Blank lines and leading and trailing whitespace are ignored.
+func ParseMask(str string) (NeighbourMask, error) {
+	rows := []string{}
+	for _, line := range strings.Split(str, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			rows = append(rows, line)
+		}
+	}
+	if len(rows)%2 == 0 {
+		return nil, fmt.Errorf("mask has %v rows, should have an odd number", len(rows))
+	}
+
+	mask := NeighbourMask{}
+	cy, cx := len(rows)/2, len(rows[0])/2
+	for y, row := range rows {
+		if len(row) != len(rows[0]) || len(row)%2 == 0 {
+			return nil, fmt.Errorf("mask row %q should have the same odd number of cells as the other rows", row)
+		}
+		for x, c := range row {
+			switch {
+			case c == '#' && x == cx && y == cy:
+				return nil, fmt.Errorf("the middle of the mask is the cell itself and can't be a neighbour")
+			case c == '#':
+				mask = append(mask, [2]int{x - cx, y - cy})
+			case c != '.':
+				return nil, fmt.Errorf("mask row %q contains %q, should only contain # and .", row, c)
+			}
+		}
+	}
+
+	if len(mask) == 0 || len(mask) > MAX_MASK_NEIGHBOURS {
+		return nil, fmt.Errorf("mask has %v neighbours, should have between 1 and %v", len(mask), MAX_MASK_NEIGHBOURS)
+	}
+	return mask, nil
+}
+
+// Makes the simulation use the given neighbourhood, or the usual 8 cell neighbourhood if mask is nil. The rules are
+// applied to the number of live neighbours in the mask.
+func (s *Simulation) SetMask(mask NeighbourMask) {
+	s.mask = mask
+	s.fillTables(s.birthRule, s.survivalRule)
+	if mask != nil {
+		s.recountMasked()
+	} else {
+		s.recountMoore()
+	}
+}
+
+// Updates the board when a mask is set. The incremental neighbour counting in updateRange relies on a cell only
+// affecting the cells directly around it, which lets the parts of the board be updated in parallel with only the rows
+// where they meet needing care. With a mask, a cell can affect cells several rows away, so instead the neighbour
+// counts are recomputed from scratch for every generation. This works for any mask, symmetric or not.
+func (s *Simulation) updateBoardMasked() {
+	// Counts can go stale between generations, since setCell only maintains the 8 cell neighbourhood.
+	s.recountMasked()
+
+	s.updateInPlace(func(ind, i, j int, isAlive bool) (born, survives bool) {
+		n := int(s.worldGrid[ind] >> 1)
+		return s.maskBirthTable[n], s.maskSurvivalTable[n]
+	})
+
+	s.recountMasked()
+	s.generation++
+}
+
+// Updates every cell of the board in place given whether the rules have it born or surviving, as returned by rules for
+// the cell at index ind, row i and column j. Each cell's new state only depends on its own value and the rules, so the
+// rows can be updated in parallel. Each range counts its own births and deaths, which are added up at the end. The
+// neighbour counts are left for the caller to recompute.
+func (s *Simulation) updateInPlace(rules func(ind, i, j int, isAlive bool) (born, survives bool)) {
+	var mu sync.Mutex
+	s.births, s.deaths = 0, 0
+	s.forRowRanges(func(minY, maxY int) {
+		births, deaths := 0, 0
+		defer func() {
+			mu.Lock()
+			s.births, s.deaths = s.births+births, s.deaths+deaths
+			mu.Unlock()
+		}()
+
+		for i := minY; i <= maxY; i++ {
+			for j := 1; j <= s.gridX; j++ {
+				ind := i*(s.gridX+2) + j
+				born, survives := rules(ind, i, j, s.worldGrid[ind]&1 == 1)
+				birth, death := s.applyTransition(ind, i, j, born, survives)
+				births += birth
+				deaths += death
+			}
+		}
+	})
+}
+
+// Sets the alive bit and pixel of the cell at index ind, row i and column j for the next generation, given whether the
+// rules have it born if it's dead or surviving if it's alive, and applies the rule modifiers: birth and survival
+// chances, lifespans and immortal cells. Returns 1 for birth or death if the cell was born or died.
+func (s *Simulation) applyTransition(ind, i, j int, born, survives bool) (birth, death int) {
+	if s.worldGrid[ind]&1 == 0 {
+		if born && (s.birthProbability == 1 || s.chance(ind, s.birthProbability)) {
+			s.worldGrid[ind] |= 1
+			s.age[ind] = 0
+			colorIndex := 0
+			if s.isTwoTone {
+				colorIndex = 2
+			}
+			setPixel(s.pixels, s.gridX, j-1, i-1, colorIndex)
+			return 1, 0
+		}
+		return 0, 0
+	}
+
+	if !s.immortal[ind] && (!survives ||
+		(s.maxLifespan > 0 && int(s.age[ind]) >= s.maxLifespan) ||
+		(s.survivalProbability < 1 && !s.chance(ind, s.survivalProbability))) {
+		s.worldGrid[ind] &^= 1
+		setPixel(s.pixels, s.gridX, j-1, i-1, 1)
+		return 0, 1
+	}
+	if s.maxLifespan > 0 || s.isAgeColored {
+		s.growOlder(ind)
+	}
+	if s.isTwoTone || s.isAgeColored {
+		setPixel(s.pixels, s.gridX, j-1, i-1, s.liveColor(ind))
+	}
+	return 0, 0
+}
+
+// Recomputes every cell's value from the alive bits of the board, counting the neighbours in the mask and taking the
+// boundary mode into account.
+func (s *Simulation) recountMasked() {
+	s.recountWith(s.mask)
+}
+
+// Recomputes every cell's value from the alive bits of the board, counting the neighbours in the given mask and taking
+// the boundary mode into account.
+func (s *Simulation) recountWith(mask NeighbourMask) {
+	s.forRowRanges(func(minY, maxY int) {
+		for y := minY - 1; y < maxY; y++ {
+			for x := 0; x < s.gridX; x++ {
+				ind := (y+1)*(s.gridX+2) + x + 1
+				val := s.worldGrid[ind] & 1
+				for _, o := range mask {
+					if s.isAliveWithBoundary(x+o[0], y+o[1]) {
+						val += 2
+					}
+				}
+				s.buffer[ind] = val
+			}
+		}
+	})
+	copy(s.worldGrid, s.buffer)
+}
+
+// Recomputes every cell's value for the usual 8 cell neighbourhood, for when a mask is removed.
+func (s *Simulation) recountMoore() {
+	for y := 0; y < s.gridY; y++ {
+		for x := 0; x < s.gridX; x++ {
+			s.recountNeighbours(x, y)
+		}
+	}
+}
+
+// Splits the board rows (1-indexed, as in worldGrid) into POOL_SIZE ranges and calls f on each in parallel, returning
+// once all calls are done.
+func (s *Simulation) forRowRanges(f func(minY, maxY int)) {
+	s.forRanges(s.gridY, func(min, max int) {
+		f(min+1, max+1)
+	})
+}
+
+// Splits the numbers from 0 to n-1 into POOL_SIZE ranges and calls f on each (inclusive) in parallel, returning once
+// all calls are done.
+func (s *Simulation) forRanges(n int, f func(min, max int)) {
+	numParts := intMin(POOL_SIZE, n)
+	if numParts < 1 {
+		return
+	}
+	perPart := n / numParts
+
+	var wg sync.WaitGroup
+	for i := 0; i < numParts; i++ {
+		min := i * perPart
+		max := min + perPart - 1
+		if i == numParts-1 {
+			max = n - 1
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(min, max)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,25 @@
+package game
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// The colour of the lines drawn between the chunks of rows the board is split into for updating.
+var PARTITION_COLOR = color.RGBA{255, 64, 64, 255}
+
+// Draws a line at the top of every chunk of rows the board was split into for the last update, and at the bottom of
+// the last one, across the columns which were updated. A debugging aid for seams between the chunks, which would show
+// up along these lines.
+func (g *Game) drawPartitions(screen *ebiten.Image) {
+	offsetX, offsetY := g.boardOffset()
+	scale := g.drawScale()
+	minX := offsetX + int(float64(g.region.minX-1)*scale)
+	maxX := offsetX + int(float64(g.region.maxX)*scale)
+	for _, y := range g.chunkBoundaries() {
+		screenY := offsetY + int(float64(y)*scale)
+		screen.SubImage(image.Rect(minX, screenY, maxX, screenY+1)).(*ebiten.Image).Fill(PARTITION_COLOR)
+	}
+}
@@ -0,0 +1,304 @@
+package game
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A small pattern of cells, as rows of which cells are alive.
+type Pattern [][]bool
+
+// Loads a pattern from a file, or from stdin if path is "-", either in RLE format (see ParseRLE) or in plaintext format
+// (see ParsePattern). Files with an RLE header line are read as RLE.
+func LoadPattern(path string) (Pattern, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isRLE(string(data)) {
+		return ParseRLE(string(data))
+	}
+	return ParsePattern(string(data))
+}
+
+// Returns whether the first line which isn't blank or a comment is an RLE header like "x = 3, y = 3".
+func isRLE(str string) bool {
+	for _, line := range strings.Split(str, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		return strings.HasPrefix(strings.ReplaceAll(line, " ", ""), "x=")
+	}
+	return false
+}
+
+// Parses a pattern in the run length encoded format used by LifeWiki and Golly. After comment lines starting with #
+// comes a header line giving the size, like "x = 3, y = 3, rule = B3/S23", and then the cells: b for a dead cell, o
+// for a live one and $ for the end of a row, each optionally preceded by a repeat count, with ! at the end. For
+// example, a glider is
+//
+//	x = 3, y = 3
+//	bob$2bo$3o!
+//
+// The rule in the header is ignored, see RLERule. The pattern is padded with dead cells to the size in the header.
+func ParseRLE(str string) (Pattern, error) {
+	width, height := 0, 0
+	isHeaderRead := false
+	var body strings.Builder
+	for _, line := range strings.Split(str, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !isHeaderRead {
+			for _, field := range strings.Split(strings.ReplaceAll(line, " ", ""), ",") {
+				key, value, _ := strings.Cut(field, "=")
+				var err error
+				switch key {
+				case "x":
+					width, err = strconv.Atoi(value)
+				case "y":
+					height, err = strconv.Atoi(value)
+				}
+				if err != nil || width < 0 || height < 0 {
+					return nil, fmt.Errorf("invalid RLE header %q", line)
+				}
+			}
+			isHeaderRead = true
+			continue
+		}
+		body.WriteString(line)
+	}
+
+	p := Pattern{{}}
+	count := 0
+	for _, c := range body.String() {
+		if unicode.IsDigit(c) {
+			count = 10*count + int(c-'0')
+			continue
+		}
+		n := intMax(1, count)
+		count = 0
+
+		switch c {
+		case 'b', 'o':
+			row := &p[len(p)-1]
+			for i := 0; i < n; i++ {
+				*row = append(*row, c == 'o')
+			}
+		case '$':
+			for i := 0; i < n; i++ {
+				p = append(p, []bool{})
+			}
+		case '!':
+			return p.padded(width, height)
+		default:
+			if !unicode.IsSpace(c) {
+				return nil, fmt.Errorf("RLE pattern contains %q, should only contain b, o, $, ! and counts", c)
+			}
+		}
+	}
+	return p.padded(width, height)
+}
+
+// Returns the rule in the header of an RLE pattern, or "" if it doesn't give one. The rule runs to the end of the header
+// line, since Larger than Life rules have commas in them.
+func RLERule(str string) string {
+	for _, line := range strings.Split(str, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		_, rule, ok := strings.Cut(strings.ReplaceAll(line, " ", ""), "rule=")
+		if !ok {
+			return ""
+		}
+		return rule
+	}
+	return ""
+}
+
+// Returns the pattern with its rows padded with dead cells to the width of the longest row, and at least to the given
+// width and height. Dead rows at the bottom beyond the given height are dropped.
+func (p Pattern) padded(width, height int) (Pattern, error) {
+	for len(p) > intMax(height, 1) && len(p[len(p)-1]) == 0 {
+		p = p[:len(p)-1]
+	}
+	for len(p) < height {
+		p = append(p, []bool{})
+	}
+	for _, row := range p {
+		width = intMax(width, len(row))
+	}
+	if width == 0 {
+		return nil, fmt.Errorf("pattern is empty")
+	}
+
+	for y, row := range p {
+		p[y] = append(row, make([]bool, width-len(row))...)
+	}
+	return p, nil
+}
+
+// Parses a pattern in the plaintext format used by LifeWiki, where O marks a live cell and . a dead one, and lines
+// starting with ! are comments. For example, a glider is
+//
+//	.O.
+//	..O
+//	OOO
+//
+// Rows shorter than the longest one are padded with dead cells. * is also accepted for live cells.
+func ParsePattern(str string) (Pattern, error) {
+	p := Pattern{}
+	width := 0
+	for _, line := range strings.Split(str, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+
+		row := make([]bool, len(line))
+		for x, c := range line {
+			switch c {
+			case 'O', '*':
+				row[x] = true
+			case '.':
+			default:
+				return nil, fmt.Errorf("pattern row %q contains %q, should only contain O, * and .", line, c)
+			}
+		}
+		p = append(p, row)
+		width = intMax(width, len(row))
+	}
+
+	// Blank lines at the end aren't part of the pattern, but blank lines in the middle are empty rows.
+	for len(p) > 0 && len(p[len(p)-1]) == 0 {
+		p = p[:len(p)-1]
+	}
+	if len(p) == 0 || width == 0 {
+		return nil, fmt.Errorf("pattern is empty")
+	}
+
+	for y, row := range p {
+		p[y] = append(row, make([]bool, width-len(row))...)
+	}
+	return p, nil
+}
+
+// Returns the pattern in RLE format (see ParseRLE), with the given rule in the header if it isn't empty. Lines are
+// kept to at most 70 characters, as other programs expect.
+func (p Pattern) RLE(rule string) string {
+	w, h := p.size()
+	header := fmt.Sprintf("x = %v, y = %v", w, h)
+	if rule != "" {
+		header += ", rule = " + rule
+	}
+
+	var runs []string
+	addRun := func(count int, tag byte) {
+		if count == 1 {
+			runs = append(runs, string(tag))
+		} else if count > 1 {
+			runs = append(runs, strconv.Itoa(count)+string(tag))
+		}
+	}
+	emptyRows := 0
+	for y, row := range p {
+		// Dead cells at the end of a row are left out, and so are the ends of empty rows, which are added to the count
+		// of the next row end instead.
+		end := len(row)
+		for end > 0 && !row[end-1] {
+			end--
+		}
+		if end == 0 && y < h-1 {
+			emptyRows++
+			continue
+		}
+		addRun(emptyRows, '$')
+		emptyRows = 1
+
+		for x := 0; x < end; {
+			n := 1
+			for x+n < end && row[x+n] == row[x] {
+				n++
+			}
+			tag := byte('b')
+			if row[x] {
+				tag = 'o'
+			}
+			addRun(n, tag)
+			x += n
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(header + "\n")
+	lineLen := 0
+	for _, run := range append(runs, "!") {
+		if lineLen+len(run) > 70 {
+			sb.WriteString("\n")
+			lineLen = 0
+		}
+		sb.WriteString(run)
+		lineLen += len(run)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// Returns the width and height of the pattern.
+func (p Pattern) size() (int, int) {
+	if len(p) == 0 {
+		return 0, 0
+	}
+	return len(p[0]), len(p)
+}
+
+// Sets the live cells of the pattern alive with its top left corner at (x, y), 0-indexed. Cells which fall off the
+// board are left out. The masked neighbour counts aren't maintained, see tile.
+func (s *Simulation) stamp(p Pattern, x, y int) {
+	for dy, row := range p {
+		for dx, alive := range row {
+			if alive {
+				s.Set(x+dx, y+dy, true)
+			}
+		}
+	}
+}
+
+// Places the pattern in the middle of the board, which must be empty. Cells which don't fit on the board are cut off
+// evenly on both sides.
+func (s *Simulation) center(p Pattern) {
+	w, h := p.size()
+	s.stamp(p, (s.gridX-w)/2, (s.gridY-h)/2)
+
+	if s.mask != nil {
+		s.recountMasked()
+	}
+}
+
+// Fills the board, which must be empty, with copies of the pattern repeated in a grid, with spacing dead cells
+// between neighbouring copies. Copies at the right and bottom edges are cut off where they don't fit.
+func (s *Simulation) tile(p Pattern, spacing int) {
+	w, h := p.size()
+	for y := 0; y < s.gridY; y += h + spacing {
+		for x := 0; x < s.gridX; x += w + spacing {
+			s.stamp(p, x, y)
+		}
+	}
+
+	if s.mask != nil {
+		s.recountMasked()
+	}
+}
@@ -0,0 +1,75 @@
+package game
+
+// A well-known rule, for comparing the rules being edited against.
+type Preset struct {
+	Name   string
+	BRules Ruleset
+	SRules Ruleset
+}
+
+// Named rules, mostly from the LifeWiki list of Life-like cellular automata.
+var PRESETS = []Preset{
+	newPreset("Conway's Game of Life", "B3/S23"),
+	newPreset("HighLife", "B36/S23"),
+	newPreset("Day & Night", "B3678/S34678"),
+	newPreset("Seeds", "B2/S"),
+	newPreset("Life without Death", "B3/S012345678"),
+	newPreset("Maze", "B3/S12345"),
+	newPreset("Mazectric", "B3/S1234"),
+	newPreset("Coral", "B3/S45678"),
+	newPreset("34 Life", "B34/S34"),
+	newPreset("2x2", "B36/S125"),
+	newPreset("Move", "B368/S245"),
+	newPreset("Replicator", "B1357/S1357"),
+	newPreset("Gnarl", "B1/S1"),
+	newPreset("Long Life", "B345/S5"),
+	newPreset("Diamoeba", "B35678/S5678"),
+	newPreset("Amoeba", "B357/S1358"),
+	newPreset("Anneal", "B4678/S35678"),
+	newPreset("Walled Cities", "B45678/S2345"),
+	newPreset("Stains", "B3678/S235678"),
+	newPreset("Assimilation", "B345/S4567"),
+}
+
+// Creates a preset from rules in B/S notation. Panics if the rules are invalid, since presets are only defined above.
+func newPreset(name, rule string) Preset {
+	bRules, sRules, err := ParseRulestring(rule)
+	if err != nil {
+		panic(err)
+	}
+	return Preset{Name: name, BRules: bRules, SRules: sRules}
+}
+
+// Returns the number of neighbour counts for which the two rulesets differ.
+func (rs Ruleset) distance(other Ruleset) int {
+	res := 0
+	for i := range rs {
+		if rs[i] != other[i] {
+			res++
+		}
+	}
+	return res
+}
+
+// Returns the preset closest to the given rules and the number of birth and survival rules which differ from it. Ties
+// go to the preset listed first.
+func closestPreset(bRules, sRules Ruleset) (Preset, int) {
+	best, bestDistance := PRESETS[0], len(bRules)+len(sRules)+1
+	for _, p := range PRESETS {
+		if d := bRules.distance(p.BRules) + sRules.distance(p.SRules); d < bestDistance {
+			best, bestDistance = p, d
+		}
+	}
+	return best, bestDistance
+}
+
+// Returns the preset after the one with exactly the given rules, wrapping around to the first after the last, or the
+// first preset if none has exactly these rules.
+func nextPreset(bRules, sRules Ruleset) Preset {
+	for i, p := range PRESETS {
+		if p.BRules == bRules && p.SRules == sRules {
+			return PRESETS[(i+1)%len(PRESETS)]
+		}
+	}
+	return PRESETS[0]
+}
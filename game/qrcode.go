@@ -0,0 +1,439 @@
+package game
+
+import "fmt"
+
+// A small QR code encoder, enough to encode share strings for the share overlay. It only supports byte mode, error
+// correction level M and versions 1 to 10, which fits up to 213 bytes. See ISO/IEC 18004 for the details.
+
+// The error correction codewords per block, number of blocks and total number of codewords of each version at error
+// correction level M, indexed by version.
+var qrVersions = [...]struct{ ecPerBlock, numBlocks, totalCodewords int }{
+	{}, {10, 1, 26}, {16, 1, 44}, {26, 1, 70}, {18, 2, 100}, {24, 2, 134}, {16, 4, 172}, {18, 4, 196}, {22, 4, 242},
+	{22, 5, 292}, {26, 5, 346},
+}
+
+// The centre coordinates of the alignment patterns of each version, indexed by version.
+var qrAlignmentPositions = [...][]int{
+	{}, {}, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34}, {6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50},
+}
+
+const QR_MAX_VERSION = len(qrVersions) - 1
+
+// A QR code being built. modules[y][x] is true for dark modules, isFunction marks the finder, timing, alignment,
+// format and version modules which data can't be placed in.
+type qrCode struct {
+	version    int
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+// Encodes data as a QR code, using the smallest version it fits in. Returns the modules, true for dark ones, without
+// the quiet zone around the code.
+func encodeQR(data []byte) ([][]bool, error) {
+	version := 1
+	for ; version <= QR_MAX_VERSION; version++ {
+		if len(data) <= qrCapacity(version) {
+			break
+		}
+	}
+	if version > QR_MAX_VERSION {
+		return nil, fmt.Errorf("%v bytes is too long for a QR code, the maximum is %v", len(data),
+			qrCapacity(QR_MAX_VERSION))
+	}
+
+	qr := newQRCode(version)
+	qr.drawFunctionPatterns()
+	qr.drawCodewords(qr.addErrorCorrection(qrDataCodewords(data, version)))
+
+	// Use the mask which gives the lowest penalty, as the standard requires.
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		qr.applyMask(mask)
+		qr.drawFormatBits(mask)
+		if penalty := qr.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		qr.applyMask(mask) // Masking twice undoes it.
+	}
+	qr.applyMask(bestMask)
+	qr.drawFormatBits(bestMask)
+
+	return qr.modules, nil
+}
+
+func newQRCode(version int) *qrCode {
+	qr := &qrCode{version: version, size: 17 + 4*version}
+	qr.modules = make([][]bool, qr.size)
+	qr.isFunction = make([][]bool, qr.size)
+	for y := range qr.modules {
+		qr.modules[y] = make([]bool, qr.size)
+		qr.isFunction[y] = make([]bool, qr.size)
+	}
+	return qr
+}
+
+// Returns the number of data codewords of a version, the ones left over after error correction.
+func qrNumDataCodewords(version int) int {
+	v := qrVersions[version]
+	return v.totalCodewords - v.ecPerBlock*v.numBlocks
+}
+
+// Returns the most bytes a version can hold in byte mode, after the 4 bit mode indicator and the length.
+func qrCapacity(version int) int {
+	return (qrNumDataCodewords(version)*8 - 4 - qrLengthBits(version)) / 8
+}
+
+// Returns the number of bits used to store the byte mode data length.
+func qrLengthBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// Returns the data codewords of the code: the byte mode indicator, the length, the data itself, a terminator and
+// then padding up to the capacity of the version.
+func qrDataCodewords(data []byte, version int) []byte {
+	var bits []bool
+	appendBits := func(val, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, (val>>i)&1 == 1)
+		}
+	}
+	appendBits(0b0100, 4)
+	appendBits(len(data), qrLengthBits(version))
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+
+	capacityBits := qrNumDataCodewords(version) * 8
+	terminatorBits := capacityBits - len(bits)
+	if terminatorBits > 4 {
+		terminatorBits = 4
+	}
+	appendBits(0, terminatorBits)
+	appendBits(0, (8-len(bits)%8)%8)
+
+	res := make([]byte, len(bits)/8, qrNumDataCodewords(version))
+	for i, bit := range bits {
+		if bit {
+			res[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	for pad := byte(0xEC); len(res) < cap(res); pad ^= 0xEC ^ 0x11 {
+		res = append(res, pad)
+	}
+	return res
+}
+
+// Splits the data codewords into blocks, computes the error correction codewords of each block and interleaves them
+// all in the order they're placed in the code.
+func (qr *qrCode) addErrorCorrection(data []byte) []byte {
+	v := qrVersions[qr.version]
+	shortLen := len(data) / v.numBlocks
+	numLong := len(data) % v.numBlocks // The long blocks, with one more codeword, come last.
+	divisor := reedSolomonDivisor(v.ecPerBlock)
+
+	dataBlocks := make([][]byte, v.numBlocks)
+	ecBlocks := make([][]byte, v.numBlocks)
+	start := 0
+	for i := range dataBlocks {
+		n := shortLen
+		if i >= v.numBlocks-numLong {
+			n++
+		}
+		dataBlocks[i] = data[start : start+n]
+		ecBlocks[i] = reedSolomonRemainder(dataBlocks[i], divisor)
+		start += n
+	}
+
+	res := make([]byte, 0, v.totalCodewords)
+	for _, blocks := range [][][]byte{dataBlocks, ecBlocks} {
+		for i := 0; i <= shortLen || i < v.ecPerBlock; i++ {
+			for _, block := range blocks {
+				if i < len(block) {
+					res = append(res, block[i])
+				}
+			}
+		}
+	}
+	return res
+}
+
+// Multiplies two elements of GF(256), using the QR code's field polynomial x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// Returns the coefficients of the Reed-Solomon generator polynomial of the given degree, from the highest power
+// down, leaving out the leading 1.
+func reedSolomonDivisor(degree int) []byte {
+	res := make([]byte, degree)
+	res[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range res {
+			res[j] = gfMultiply(res[j], root)
+			if j+1 < len(res) {
+				res[j] ^= res[j+1]
+			}
+		}
+		root = gfMultiply(root, 2)
+	}
+	return res
+}
+
+// Returns the error correction codewords for the data: the remainder of dividing it by the generator polynomial.
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	res := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ res[0]
+		copy(res, res[1:])
+		res[len(res)-1] = 0
+		for i, d := range divisor {
+			res[i] ^= gfMultiply(d, factor)
+		}
+	}
+	return res
+}
+
+func (qr *qrCode) setFunction(x, y int, isDark bool) {
+	qr.modules[y][x] = isDark
+	qr.isFunction[y][x] = true
+}
+
+// Draws the finder, timing and alignment patterns and the version information, and reserves the format modules,
+// which depend on the mask.
+func (qr *qrCode) drawFunctionPatterns() {
+	for i := 0; i < qr.size; i++ {
+		qr.setFunction(6, i, i%2 == 0)
+		qr.setFunction(i, 6, i%2 == 0)
+	}
+
+	// The finder patterns in three of the corners, with their light separators.
+	for _, c := range [][2]int{{3, 3}, {qr.size - 4, 3}, {3, qr.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x >= 0 && x < qr.size && y >= 0 && y < qr.size {
+					dist := qrDistance(dx, dy)
+					qr.setFunction(x, y, dist != 2 && dist != 4)
+				}
+			}
+		}
+	}
+
+	// Alignment patterns everywhere on the grid of positions, except where they'd overlap the finder patterns.
+	positions := qrAlignmentPositions[qr.version]
+	last := len(positions) - 1
+	for i, cy := range positions {
+		for j, cx := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					qr.setFunction(cx+dx, cy+dy, qrDistance(dx, dy) != 1)
+				}
+			}
+		}
+	}
+
+	qr.drawFormatBits(0)
+
+	if qr.version >= 7 {
+		bits := qr.version<<12 | bchRemainder(qr.version, 0x1F25, 12)
+		for i := 0; i < 18; i++ {
+			isDark := (bits>>i)&1 == 1
+			a, b := qr.size-11+i%3, i/3
+			qr.setFunction(a, b, isDark)
+			qr.setFunction(b, a, isDark)
+		}
+	}
+}
+
+// Returns the distance of a module from the centre of a square pattern, as the number of rings out it is.
+func qrDistance(dx, dy int) int {
+	if abs(dx) > abs(dy) {
+		return abs(dx)
+	}
+	return abs(dy)
+}
+
+// Returns the remainder of the BCH code used for the format and version information.
+func bchRemainder(data, generator, degree int) int {
+	rem := data
+	for i := 0; i < degree; i++ {
+		rem = (rem << 1) ^ ((rem >> (degree - 1)) * generator)
+	}
+	return rem & (1<<degree - 1)
+}
+
+// Returns the 15 format bits for error correction level M and the given mask.
+func qrFormatBits(mask int) int {
+	data := 0b00<<3 | mask // 00 is level M.
+	return (data<<10 | bchRemainder(data, 0x537, 10)) ^ 0x5412
+}
+
+// Draws both copies of the format information, along with the dark module which is always next to it.
+func (qr *qrCode) drawFormatBits(mask int) {
+	bits := qrFormatBits(mask)
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	// The copy around the upper left finder pattern.
+	for i := 0; i <= 5; i++ {
+		qr.setFunction(8, i, bit(i))
+	}
+	qr.setFunction(8, 7, bit(6))
+	qr.setFunction(8, 8, bit(7))
+	qr.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		qr.setFunction(14-i, 8, bit(i))
+	}
+
+	// The copy split between the other two finder patterns.
+	for i := 0; i < 8; i++ {
+		qr.setFunction(qr.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		qr.setFunction(8, qr.size-15+i, bit(i))
+	}
+	qr.setFunction(8, qr.size-8, true)
+}
+
+// Places the codewords in the modules which aren't function modules, zigzagging up and down two columns at a time
+// from the right. Modules left over at the end stay light.
+func (qr *qrCode) drawCodewords(codewords []byte) {
+	i := 0
+	for right := qr.size - 1; right >= 1; right -= 2 {
+		if right == 6 { // Skip the vertical timing pattern.
+			right = 5
+		}
+		isUpward := (right+1)&2 == 0
+		for vert := 0; vert < qr.size; vert++ {
+			y := vert
+			if isUpward {
+				y = qr.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if !qr.isFunction[y][x] && i < len(codewords)*8 {
+					qr.modules[y][x] = (codewords[i/8]>>(7-i%8))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// Flips the data modules selected by the mask pattern.
+func (qr *qrCode) applyMask(mask int) {
+	for y := 0; y < qr.size; y++ {
+		for x := 0; x < qr.size; x++ {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip && !qr.isFunction[y][x] {
+				qr.modules[y][x] = !qr.modules[y][x]
+			}
+		}
+	}
+}
+
+// Scores how hard the code is to scan: long runs of one colour, 2x2 blocks of one colour, patterns which look like
+// finder patterns, and an unbalanced number of dark modules are all penalised.
+func (qr *qrCode) penalty() int {
+	res := 0
+	get := func(x, y int, isRow bool) bool {
+		if isRow {
+			return qr.modules[y][x]
+		}
+		return qr.modules[x][y]
+	}
+	finderLike := []bool{true, false, true, true, true, false, true}
+
+	for _, isRow := range []bool{true, false} {
+		for y := 0; y < qr.size; y++ {
+			run := 0
+			for x := 0; x < qr.size; x++ {
+				if x > 0 && get(x, y, isRow) == get(x-1, y, isRow) {
+					run++
+				} else {
+					run = 1
+				}
+				if run == 5 {
+					res += 3
+				} else if run > 5 {
+					res++
+				}
+			}
+
+			// A finder-like pattern with four light modules on either side. Outside the code counts as light.
+			isLight := func(x int) bool { return x < 0 || x >= qr.size || !get(x, y, isRow) }
+			for x := 0; x+len(finderLike) <= qr.size; x++ {
+				matches := true
+				for k, isDark := range finderLike {
+					if get(x+k, y, isRow) != isDark {
+						matches = false
+						break
+					}
+				}
+				if !matches {
+					continue
+				}
+				before, after := true, true
+				for k := 1; k <= 4; k++ {
+					before = before && isLight(x-k)
+					after = after && isLight(x+len(finderLike)-1+k)
+				}
+				if before {
+					res += 40
+				}
+				if after {
+					res += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < qr.size; y++ {
+		for x := 0; x < qr.size; x++ {
+			if qr.modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				c := qr.modules[y][x]
+				if qr.modules[y-1][x] == c && qr.modules[y][x-1] == c && qr.modules[y-1][x-1] == c {
+					res += 3
+				}
+			}
+		}
+	}
+	total := qr.size * qr.size
+	res += abs(dark*20-total*10) / total * 10
+
+	return res
+}
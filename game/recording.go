@@ -0,0 +1,185 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"sort"
+	"strings"
+)
+
+const (
+	// How many game updates the notice about a recording being blocked is shown for.
+	RECORDING_BLOCKED_TICKS = 120
+
+	// The number of recording slots, started and stopped with CTRL and the number keys from 1.
+	NUM_RECORDING_SLOTS = 9
+)
+
+// Where the game is in the life of a recording. Recordings go from idle to active to writing and back to idle, and a new
+// recording can't be started until the last one has been written, so that only one saver is ever in use.
+type RecordingState int
+
+const (
+	// Not recording.
+	RECORDING_IDLE RecordingState = iota
+
+	// Frames are being captured.
+	RECORDING_ACTIVE
+
+	// The last recording is being written to file on another goroutine.
+	RECORDING_WRITING
+)
+
+// Starts capturing frames with the given saver. Returns false and leaves the saver unused if the previous recording is
+// still being written.
+func (g *Game) startRecording(saver GifSaverInterface) bool {
+	if g.recordingState != RECORDING_IDLE {
+		return false
+	}
+	g.gifSaver = saver
+	g.recordedFrames = 0
+	g.recordingState = RECORDING_ACTIVE
+	g.updateRecordingText()
+	return true
+}
+
+// Stops capturing frames and writes the recording to file on another goroutine, as this can take a few seconds and
+// would otherwise freeze the game. pollRecording notices when the write has finished.
+func (g *Game) stopRecording() {
+	if g.recordingState != RECORDING_ACTIVE {
+		return
+	}
+
+	// The goroutine gets its own references, since the game's may change before it's done.
+	saver, done := g.gifSaver, make(chan struct{})
+	g.gifSaver, g.writeDone = nil, done
+	g.recordingState = RECORDING_WRITING
+	g.updateRecordingText()
+
+	go func() {
+		defer close(done)
+		saver.writeToFile()
+	}()
+}
+
+// Goes back to idle if the recording being written has been written. Called every game update.
+func (g *Game) pollRecording() {
+	if g.recordingState != RECORDING_WRITING {
+		return
+	}
+	select {
+	case <-g.writeDone:
+		g.recordingState = RECORDING_IDLE
+		g.updateRecordingText()
+	default:
+	}
+}
+
+// Writes any recordings in progress, including the ones in slots, and waits until they have been written, for when the
+// game is about to exit.
+func (g *Game) finishRecording() {
+	g.stopRecordings()
+	if g.recordingState == RECORDING_WRITING {
+		<-g.writeDone
+		g.recordingState = RECORDING_IDLE
+		g.updateRecordingText()
+	}
+	g.slotWrites.Wait()
+}
+
+// Stops every recording capturing frames, the main one and the ones in slots, and writes them to file on other
+// goroutines.
+func (g *Game) stopRecordings() {
+	g.stopRecording()
+	for slot := range g.slotSavers {
+		g.toggleSlotRecording(slot)
+	}
+}
+
+// Starts recording in the given slot, or if the slot is recording, stops and writes its recording to file on another
+// goroutine. Unlike the main recording, a slot can start a new recording while its last one is still being written, as
+// each has a file of its own. finishRecording waits for the writes.
+func (g *Game) toggleSlotRecording(slot int) {
+	if saver, ok := g.slotSavers[slot]; ok {
+		delete(g.slotSavers, slot)
+		delete(g.slotRecordedFrames, slot)
+		g.slotWrites.Add(1)
+		go func() {
+			defer g.slotWrites.Done()
+			saver.writeToFile()
+		}()
+	} else {
+		if g.slotSavers == nil {
+			g.slotSavers = map[int]GifSaverInterface{}
+		}
+		if g.slotTakes == nil {
+			g.slotTakes = map[int]int{}
+		}
+		g.slotTakes[slot]++
+		g.slotSavers[slot] = newRecordingSaver(g.bRules, g.sRules, g.isCropping(), g.recordingPalette(), slot,
+			g.slotTakes[slot])
+	}
+	g.updateRecordingText()
+}
+
+// Adds the frame to the main recording and the slot recordings. A recording which reaches MAX_RECORDING_FRAMES frames
+// is stopped and written to file, the same as if it had been stopped by hand, so that a forgotten recording can't use
+// up all the memory. Slot recordings carry on through pauses, but leave out the paused frames.
+func (g *Game) recordFrame(frame image.Image) {
+	isFull := func(frames int) bool { return MAX_RECORDING_FRAMES > 0 && frames >= MAX_RECORDING_FRAMES }
+
+	if g.recordingState == RECORDING_ACTIVE {
+		g.gifSaver.saveFrame(frame)
+		g.recordedFrames++
+		if isFull(g.recordedFrames) {
+			log.Printf("the recording reached %v frames, so it's been stopped and saved", MAX_RECORDING_FRAMES)
+			g.stopRecording()
+		}
+	}
+
+	if g.isPaused {
+		return
+	}
+	if g.slotRecordedFrames == nil {
+		g.slotRecordedFrames = map[int]int{}
+	}
+	for slot, saver := range g.slotSavers {
+		saver.saveFrame(frame)
+		g.slotRecordedFrames[slot]++
+		if isFull(g.slotRecordedFrames[slot]) {
+			log.Printf("the recording in slot %v reached %v frames, so it's been stopped and saved", slot,
+				MAX_RECORDING_FRAMES)
+			g.toggleSlotRecording(slot)
+		}
+	}
+}
+
+// Returns whether any recording, the main one or one in a slot, is capturing frames. The look of the board can't be
+// changed then, since the recording palettes depend on it.
+func (g *Game) isRecording() bool {
+	return g.recordingState == RECORDING_ACTIVE || len(g.slotSavers) > 0
+}
+
+// Returns whether new recordings should be cropped to the live cells. Cropping looks for cells which aren't black, so
+// it can't work once the background is flattened in or when drawing the neighbour count field.
+func (g *Game) isCropping() bool {
+	return g.ui.isAutoCropEnabled && !g.isRecordingFlattened() && !g.isFieldVisible
+}
+
+// Tells the UI which recording text to show. The UI is only ever touched from the game loop, never from the goroutine
+// writing the file.
+func (g *Game) updateRecordingText() {
+	g.ui.shouldDisplayRecordingText = g.recordingState == RECORDING_ACTIVE
+	g.ui.shouldDisplayWritingToFileText = g.recordingState == RECORDING_WRITING
+
+	slots := []string{}
+	for slot := range g.slotSavers {
+		slots = append(slots, fmt.Sprint(slot))
+	}
+	sort.Strings(slots)
+	g.ui.recordingSlotsText = ""
+	if len(slots) > 0 {
+		g.ui.recordingSlotsText = "recording in slots " + strings.Join(slots, ", ")
+	}
+}
@@ -0,0 +1,94 @@
+package game
+
+// The state of the board at one generation, enough to carry on from it exactly: the cells with their neighbour
+// counts, ages and dying states, and the generation, which seeds the noise of probabilistic rules.
+type boardSnapshot struct {
+	worldGrid  []int8
+	age, dying []uint8
+	generation int
+}
+
+// A ring buffer of the last few generations of the board, so that they can be stepped back through. Each snapshot
+// takes 3 bytes per cell, and their slices are reused once the buffer is full, so it doesn't allocate after filling up.
+type rewindBuffer struct {
+	snapshots []boardSnapshot
+	// The index of the newest snapshot and the number of snapshots kept.
+	newest, count int
+}
+
+// Saves the board as it is now, dropping the oldest snapshot if there are already size of them.
+func (b *rewindBuffer) push(s *Simulation, size int) {
+	if len(b.snapshots) != size {
+		b.snapshots = make([]boardSnapshot, size)
+		b.newest, b.count = 0, 0
+	}
+	if size == 0 {
+		return
+	}
+
+	b.newest = (b.newest + 1) % size
+	b.count = intMin(b.count+1, size)
+	snap := &b.snapshots[b.newest]
+	snap.worldGrid = append(snap.worldGrid[:0], s.worldGrid...)
+	snap.age = append(snap.age[:0], s.age...)
+	snap.dying = append(snap.dying[:0], s.dying...)
+	snap.generation = s.generation
+}
+
+// Puts the board back as it was in the newest snapshot and drops that snapshot. Returns false if there are none.
+func (b *rewindBuffer) pop(s *Simulation) bool {
+	if b.count == 0 {
+		return false
+	}
+	snap := &b.snapshots[b.newest]
+	copy(s.worldGrid, snap.worldGrid)
+	copy(s.age, snap.age)
+	copy(s.dying, snap.dying)
+	s.generation = snap.generation
+	b.newest = (b.newest + len(b.snapshots) - 1) % len(b.snapshots)
+	b.count--
+
+	s.isLiveBoundsKnown = false
+	s.redrawPixels()
+	return true
+}
+
+// Drops all the snapshots, for when the board is replaced or changes size.
+func (b *rewindBuffer) clear() {
+	b.count = 0
+}
+
+// Redraws every cell's pixel from its state, for after the cells have been changed wholesale. Just born cells in
+// two-tone mode come out in the live colour, as there's no telling them apart afterwards.
+func (s *Simulation) redrawPixels() {
+	for y := 0; y < s.gridY; y++ {
+		for x := 0; x < s.gridX; x++ {
+			ind := (y+1)*(s.gridX+2) + x + 1
+			if s.worldGrid[ind]&1 == 1 {
+				setPixel(s.pixels, s.gridX, x, y, s.liveColor(ind))
+			} else {
+				s.setDyingPixel(x, y, s.dying[ind])
+			}
+		}
+	}
+}
+
+// Saves the board before it's advanced, so that it can be rewound to. Nothing is saved while recording, to keep the
+// memory for the recording, or with a layer board, which would need rewinding in step.
+func (g *Game) saveRewindSnapshot() {
+	if REWIND_GENERATIONS == 0 || g.isRecording() || g.layer != nil {
+		return
+	}
+	g.rewind.push(&g.Simulation, REWIND_GENERATIONS)
+}
+
+// Steps the board back a generation, if there's a snapshot of it. The stats and cues carry on from the rewound board
+// rather than being rewound themselves.
+func (g *Game) rewindGeneration() {
+	if !g.rewind.pop(&g.Simulation) {
+		return
+	}
+	if g.stats != nil {
+		g.population, _ = g.boardSummary()
+	}
+}
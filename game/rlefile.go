@@ -0,0 +1,95 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"os"
+)
+
+// Returns the live cells of the board as a pattern, cut down to their bounding box. An empty board gives an empty
+// pattern.
+func (s *Simulation) livePattern() Pattern {
+	bounds := s.findLiveBounds(cellRect{1, 1, s.gridX, s.gridY})
+	if bounds.isEmpty() {
+		return Pattern{}
+	}
+	return s.patternAt(image.Rect(bounds.minX-1, bounds.minY-1, bounds.maxX, bounds.maxY))
+}
+
+// Returns the rule the board is running in the notation used in RLE headers: the Larger than Life rule if there is one,
+// otherwise the rulestring, with the number of states for Generations rules.
+func (g *Game) rleRule() string {
+	if g.ltl != nil {
+		return g.ltl.String()
+	}
+	return g.generationsRulestring()
+}
+
+// Writes the live cells of the board to the file at path in RLE format, with the rule in the header, so that it can
+// be loaded again with -init or opened in other programs. Only the bounding box of the live cells is saved, so an
+// empty board gives a header for a 0 by 0 pattern.
+func (g *Game) SaveRLE(path string) error {
+	return os.WriteFile(path, []byte(g.livePattern().RLE(g.rleRule())), 0644)
+}
+
+// Saves the board to an RLE file named after the time and the rules in the output directory. See SaveRLE.
+func (g *Game) saveBoard() {
+	if err := os.MkdirAll(OUTPUT_DIR, os.ModePerm); err != nil {
+		log.Printf("could not create output directory: %v", err)
+		return
+	}
+	path := outputPath(recordingFileName(g.bRules, g.sRules, "rle"))
+	if err := g.SaveRLE(path); err != nil {
+		log.Printf("could not save the board: %v", err)
+		return
+	}
+	log.Printf("saved the board to %v", path)
+}
+
+// Sets the game up to start from the RLE pattern at path, centered on an empty board and cut off if it doesn't fit, the
+// same way as the -init pattern. If the header gives a rule, which can be a Generations or Larger than Life rule as
+// written by SaveRLE, the game switches to it, otherwise the current rules are kept. A board which is already running
+// is replaced straight away.
+func (g *Game) LoadRLE(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	p, err := ParseRLE(string(data))
+	if err != nil {
+		return fmt.Errorf("could not read RLE pattern %v: %v", path, err)
+	}
+	if rule := RLERule(string(data)); rule != "" {
+		if err := g.useRuleString(rule); err != nil {
+			return fmt.Errorf("could not read RLE pattern %v: %v", path, err)
+		}
+	}
+
+	INIT_PATTERN = p
+	if g.img != nil {
+		g.InitializeBoard()
+	}
+	return nil
+}
+
+// Makes the game start with the given rule, which can be in any notation ParseGenerationsRule or ParseLtLRule reads.
+func (g *Game) useRuleString(rule string) error {
+	bRules, sRules, states, err := ParseGenerationsRule(rule)
+	if err != nil {
+		ltl, ltlErr := ParseLtLRule(rule)
+		if ltlErr != nil {
+			return fmt.Errorf("unknown rule %q", rule)
+		}
+		LTL_RULE = ltl
+		g.SetLtLRule(ltl)
+		return nil
+	}
+
+	g.useRules(bRules, sRules)
+	STATES = states
+	g.SetStates(states)
+	LTL_RULE = nil
+	g.SetLtLRule(nil)
+	return nil
+}
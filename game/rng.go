@@ -0,0 +1,95 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// The random numbers a board is filled from. Both *rand.Rand and *StableRNG provide them.
+type RNG interface {
+	Int63() int64
+	Int63n(n int64) int64
+}
+
+// A small random number generator (SplitMix64) whose output is defined here rather than by the standard library, so
+// that a seed gives the same board whichever Go version the game was built with. It also implements rand.Source64.
+type StableRNG struct {
+	state uint64
+}
+
+func NewStableRNG(seed int64) *StableRNG {
+	return &StableRNG{state: uint64(seed)}
+}
+
+func (sr *StableRNG) Seed(seed int64) {
+	sr.state = uint64(seed)
+}
+
+// The increment of the SplitMix64 state for each number.
+const SPLITMIX_GAMMA = 0x9e3779b97f4a7c15
+
+func (sr *StableRNG) Uint64() uint64 {
+	sr.state += SPLITMIX_GAMMA
+	z := sr.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (sr *StableRNG) Int63() int64 {
+	return int64(sr.Uint64() >> 1)
+}
+
+// Returns a number in [0, n), which must be positive. Numbers from the top of the range which would make some results
+// more likely than others are rejected and drawn again.
+func (sr *StableRNG) Int63n(n int64) int64 {
+	if n <= 0 {
+		panic(fmt.Sprintf("invalid argument to Int63n: %v", n))
+	}
+	limit := (1 << 63) - (1<<63)%uint64(n)
+	for {
+		if v := sr.Uint64() >> 1; v < limit {
+			return int64(v % uint64(n))
+		}
+	}
+}
+
+// The number of rows in each of the blocks a board is randomly filled in, see Simulation.randomizeBlocks.
+const RANDOMIZE_BLOCK_ROWS = 16
+
+// Returns the seed for the given block of rows of a board filled from seed: the block-th number from a StableRNG
+// seeded with seed, which SplitMix64 can jump straight to.
+func blockSeed(seed int64, block int) int64 {
+	sr := NewStableRNG(seed)
+	sr.state += uint64(block) * SPLITMIX_GAMMA
+	return sr.Int63()
+}
+
+// Returns the generator boards are filled from for the given seed, which is a StableRNG if STABLE_RNG is set.
+func newRNG(seed int64) RNG {
+	if STABLE_RNG {
+		return NewStableRNG(seed)
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// Returns the name of the generator selected with STABLE_RNG, as used by the -rng flag and share strings.
+func rngName() string {
+	if STABLE_RNG {
+		return "stable"
+	}
+	return "math"
+}
+
+// Selects the generator with the given name, as returned by rngName.
+func SetRNG(name string) error {
+	switch name {
+	case "math":
+		STABLE_RNG = false
+	case "stable":
+		STABLE_RNG = true
+	default:
+		return fmt.Errorf("unknown RNG %q, should be math or stable", name)
+	}
+	return nil
+}
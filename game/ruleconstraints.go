@@ -0,0 +1,86 @@
+package game
+
+import "fmt"
+
+// Bounds on the rules rolled with W in the pause menu, so that the rolled rules are more likely to be interesting than
+// completely random ones.
+type RuleConstraints struct {
+	// The smallest and largest number of neighbour counts which cause births and survivals.
+	MinBirths, MaxBirths       int
+	MinSurvivals, MaxSurvivals int
+
+	// The fewest live neighbours a birth can need, at least 1 as randomRules never rolls births with no neighbours.
+	// Births with one neighbour make most boards explode.
+	MinBirthNeighbours int
+}
+
+// The constraints the pause menu starts with.
+var DEFAULT_RULE_CONSTRAINTS = RuleConstraints{MinBirths: 1, MaxBirths: 3, MinSurvivals: 2, MaxSurvivals: 4,
+	MinBirthNeighbours: 2}
+
+// The number of adjustable values in a RuleConstraints, in the order they're selected in the pause menu.
+const NUM_RULE_CONSTRAINTS = 5
+
+// Returns random birth and survival rules which meet the constraints, rolling rules with randomRules until they do, so
+// that all the rules which meet them are equally likely. adjust keeps the constraints possible to meet.
+func (c RuleConstraints) random(rng RNG) (Ruleset, Ruleset) {
+	for {
+		bRules, sRules := randomRules(rng)
+		if c.allows(bRules, sRules) {
+			return bRules, sRules
+		}
+	}
+}
+
+// Returns whether the rules meet the constraints.
+func (c RuleConstraints) allows(bRules, sRules Ruleset) bool {
+	births, survivals := 0, 0
+	for i := range bRules {
+		if bRules[i] {
+			if i < c.MinBirthNeighbours {
+				return false
+			}
+			births++
+		}
+		if sRules[i] {
+			survivals++
+		}
+	}
+	return births >= c.MinBirths && births <= c.MaxBirths && survivals >= c.MinSurvivals && survivals <= c.MaxSurvivals
+}
+
+// Changes the constraint with the given index by delta, keeping the constraints possible to meet: each minimum at most
+// its maximum, births from at least 1 neighbour, and no more birth counts than there are neighbour counts from
+// MinBirthNeighbours to 8.
+func (c *RuleConstraints) adjust(index, delta int) {
+	switch index {
+	case 0:
+		c.MinBirths = clamp(0, 9, c.MinBirths+delta)
+		c.MaxBirths = intMax(c.MaxBirths, c.MinBirths)
+	case 1:
+		c.MaxBirths = clamp(0, 9, c.MaxBirths+delta)
+		c.MinBirths = intMin(c.MinBirths, c.MaxBirths)
+	case 2:
+		c.MinSurvivals = clamp(0, 9, c.MinSurvivals+delta)
+		c.MaxSurvivals = intMax(c.MaxSurvivals, c.MinSurvivals)
+	case 3:
+		c.MaxSurvivals = clamp(0, 9, c.MaxSurvivals+delta)
+		c.MinSurvivals = intMin(c.MinSurvivals, c.MaxSurvivals)
+	case 4:
+		c.MinBirthNeighbours = clamp(1, 8, c.MinBirthNeighbours+delta)
+	}
+	available := 9 - c.MinBirthNeighbours
+	c.MaxBirths = intMin(c.MaxBirths, available)
+	c.MinBirths = intMin(c.MinBirths, available)
+}
+
+// Describes the constraints for the pause menu, with a * after the one with the given index.
+func (c RuleConstraints) describe(selected int) string {
+	values := []any{c.MinBirths, c.MaxBirths, c.MinSurvivals, c.MaxSurvivals, c.MinBirthNeighbours}
+	for i := range values {
+		if i == selected {
+			values[i] = fmt.Sprintf("%v*", values[i])
+		}
+	}
+	return fmt.Sprintf("%v to %v births, %v to %v survivals, births from %v neighbours", values...)
+}
@@ -0,0 +1,18 @@
+package game
+
+import "strconv"
+
+// Creates the writer for RULE_LOG_FILE, which gets a row for every new board and every change of the running rules.
+// Together with the seeds, this is enough to replay an interactive session exactly.
+func newRuleLogWriter(path string) *CSVWriter {
+	return newCSVWriter(path, "generation", "seed", "rule")
+}
+
+// Writes the rules running at the current generation to the rule log, if there is one.
+func (g *Game) logRules() {
+	if g.ruleLog == nil {
+		return
+	}
+	g.ruleLog.writeRow(strconv.Itoa(g.generation), strconv.FormatInt(g.boardSeed, 10),
+		FormatRulestring(g.bRules, g.sRules))
+}
@@ -0,0 +1,79 @@
+package game
+
+import "image"
+
+// The most rule regions a board can have. Each cell looks up its tables through a one byte index, and with only a few
+// regions their tables stay in cache.
+const MAX_RULE_REGIONS = 8
+
+// A rectangle of cells which follows its own rules instead of the board's, with transition tables filled the same way
+// as the board's. Cells near the edge of a region count their neighbours outside it as usual, so the two sets of rules
+// interact across the edge.
+type ruleRegion struct {
+	rect              image.Rectangle
+	bRules, sRules    Ruleset
+	becomesAliveTable [18]bool
+	becomesDeadTable  [18]bool
+}
+
+// Makes the cells in rect, given in board coordinates without the border, follow the given rules. Where regions
+// overlap the one added last wins. Once there are MAX_RULE_REGIONS regions the oldest one is dropped to make room.
+// Rule regions are ignored when a mask is set.
+func (s *Simulation) AddRuleRegion(rect image.Rectangle, bRules, sRules Ruleset) {
+	region := ruleRegion{rect: rect.Canon(), bRules: bRules, sRules: sRules}
+	fillTransitionTables(&region.becomesAliveTable, &region.becomesDeadTable, bRules.Predicate(), sRules.Predicate())
+
+	if len(s.ruleRegions) == MAX_RULE_REGIONS {
+		s.ruleRegions = s.ruleRegions[1:]
+	}
+	s.ruleRegions = append(s.ruleRegions, region)
+	s.indexRuleRegions()
+}
+
+// Removes all rule regions, so that the whole board follows the board's rules again.
+func (s *Simulation) ClearRuleRegions() {
+	s.ruleRegions = nil
+	s.indexRuleRegions()
+}
+
+// Fills ruleRegionIndex, which holds for each cell 0 if it follows the board's rules and i if it follows those of
+// ruleRegions[i-1], using the same indexing as worldGrid. Regions are clipped to the board, so that they survive the
+// board being resized.
+func (s *Simulation) indexRuleRegions() {
+	if len(s.ruleRegions) == 0 {
+		s.ruleRegionIndex = nil
+		return
+	}
+
+	s.ruleRegionIndex = make([]uint8, (s.gridX+2)*(s.gridY+2))
+	board := image.Rect(0, 0, s.gridX, s.gridY)
+	for i, region := range s.ruleRegions {
+		rect := region.rect.Intersect(board)
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				s.ruleRegionIndex[(y+1)*(s.gridX+2)+x+1] = uint8(i + 1)
+			}
+		}
+	}
+}
+
+// Returns the transition tables the cell at index ind of worldGrid follows.
+func (s *Simulation) tablesAt(ind int) (*[18]bool, *[18]bool) {
+	if s.ruleRegionIndex != nil {
+		if i := s.ruleRegionIndex[ind]; i > 0 {
+			region := &s.ruleRegions[i-1]
+			return &region.becomesAliveTable, &region.becomesDeadTable
+		}
+	}
+	return &s.becomesAliveTable, &s.becomesDeadTable
+}
+
+// Returns whether the board's rules or those of any rule region have dead cells with no live neighbours being born.
+func (s *Simulation) hasBirthFromNothing() bool {
+	for _, region := range s.ruleRegions {
+		if region.becomesAliveTable[0] {
+			return true
+		}
+	}
+	return s.becomesAliveTable[0]
+}
@@ -0,0 +1,119 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Decides, given a number of live neighbours, whether a transition happens. Birth and survival rules can be given as
+// predicates to express rules like "born with 3 to 5 neighbours" without listing every neighbour count.
+type RulePredicate func(neighbours int) bool
+
+// Returns a predicate which holds when the number of live neighbours is between min and max inclusive.
+func NeighboursInRange(min, max int) RulePredicate {
+	return func(neighbours int) bool {
+		return neighbours >= min && neighbours <= max
+	}
+}
+
+// Returns the predicate which holds exactly for the neighbour counts set in the ruleset. This is how the digit based
+// rules edited in the UI are turned into transition tables.
+func (rs Ruleset) Predicate() RulePredicate {
+	return func(neighbours int) bool {
+		return neighbours >= 0 && neighbours < len(rs) && rs[neighbours]
+	}
+}
+
+// Returns the ruleset which has the neighbour counts for which the predicate holds set.
+func RulesetFromPredicate(p RulePredicate) Ruleset {
+	rs := Ruleset{}
+	for i := range rs {
+		rs[i] = p(i)
+	}
+	return rs
+}
+
+// Returns the rules in the usual B/S notation, e.g. B3/S23 for Conway's Game of Life, with the neighbour counts in
+// ascending order. Rules with no neighbour counts set come out as B/S, which ParseRulestring reads back.
+func FormatRulestring(bRules, sRules Ruleset) string {
+	b, s := "", ""
+	for i := 0; i <= 8; i++ {
+		if bRules[i] {
+			b += strconv.Itoa(i)
+		}
+		if sRules[i] {
+			s += strconv.Itoa(i)
+		}
+	}
+	return "B" + b + "/S" + s
+}
+
+// Parses rules in the notations used by Golly and LifeWiki: B/S notation like B3/S23 for Conway's Game of Life, as
+// returned by FormatRulestring, or the older S/B notation without letters like 23/3 for the same rules. Letters can be
+// either case, and either part can be empty, as in B/S012345678. Neighbour counts must be between 0 and 8.
+func ParseRulestring(str string) (bRules, sRules Ruleset, err error) {
+	first, second, ok := strings.Cut(strings.ToUpper(strings.TrimSpace(str)), "/")
+	if !ok {
+		return bRules, sRules, fmt.Errorf("invalid rule %q, should look like B3/S23 or 23/3", str)
+	}
+
+	var bDigits, sDigits string
+	switch {
+	case strings.HasPrefix(first, "B") && strings.HasPrefix(second, "S"):
+		bDigits, sDigits = first[1:], second[1:]
+	case strings.HasPrefix(first, "S") && strings.HasPrefix(second, "B"):
+		bDigits, sDigits = second[1:], first[1:]
+	default:
+		// The S/B notation, with survival first.
+		bDigits, sDigits = second, first
+	}
+
+	for _, part := range []struct {
+		digits string
+		rules  *Ruleset
+	}{{bDigits, &bRules}, {sDigits, &sRules}} {
+		for _, c := range part.digits {
+			if c < '0' || c > '8' {
+				return Ruleset{}, Ruleset{}, fmt.Errorf("invalid rule %q, neighbour counts should be between 0 and 8",
+					str)
+			}
+			part.rules[c-'0'] = true
+		}
+	}
+	return bRules, sRules, nil
+}
+
+// Sets the birth and survival rules of the simulation from predicates. With a mask, the predicates are also asked
+// about neighbour counts above 8.
+func (s *Simulation) SetRules(birth, survival RulePredicate) {
+	s.bRules = RulesetFromPredicate(birth)
+	s.sRules = RulesetFromPredicate(survival)
+	s.fillTables(birth, survival)
+}
+
+// Returns a readable dump of the transition tables, for checking that the rules were turned into the tables as
+// intended. Each row is one cell value, i.e. a state and a number of live neighbours, and says what the tables do with
+// it.
+func (s *Simulation) DescribeTables() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "rules %v\n", FormatRulestring(s.bRules, s.sRules))
+	fmt.Fprintf(&sb, "%5v %6v %10v %13v %12v\n", "value", "state", "neighbours", "becomesAlive", "becomesDead")
+	for val := range s.becomesAliveTable {
+		state := "dead"
+		if val&1 == 1 {
+			state = "alive"
+		}
+		fmt.Fprintf(&sb, "%5v %6v %10v %13v %12v\n", val, state, val>>1, s.becomesAliveTable[val],
+			s.becomesDeadTable[val])
+	}
+
+	if s.mask != nil {
+		fmt.Fprintf(&sb, "mask with %v neighbours\n", len(s.mask))
+		fmt.Fprintf(&sb, "%10v %6v %9v\n", "neighbours", "birth", "survival")
+		for n := range s.maskBirthTable {
+			fmt.Fprintf(&sb, "%10v %6v %9v\n", n, s.maskBirthTable[n], s.maskSurvivalTable[n])
+		}
+	}
+	return sb.String()
+}
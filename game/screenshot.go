@@ -0,0 +1,61 @@
+package game
+
+import (
+	"image"
+	"image/png"
+	"log"
+	"os"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Returns a copy of the frame last drawn to the board image, one pixel per cell: the neighbour count field or the
+// blended layers if they're shown, flattened against the background if it's visible.
+func (g *Game) currentFrame() *image.RGBA {
+	if g.isBackgroundVisible {
+		return g.flattenedFrame()
+	}
+	pixels := g.boardPixels()
+	if g.isFieldVisible {
+		pixels = g.fieldPixels
+	} else if g.layer != nil {
+		pixels = g.layerPixels
+	}
+	return &image.RGBA{Pix: append([]byte{}, pixels...), Stride: 4 * g.gridX, Rect: image.Rect(0, 0, g.gridX, g.gridY)}
+}
+
+// Saves the board as it's shown as a PNG at path, either with one pixel per cell or, if isUpscaled is set, with each
+// cell drawn as a square of scaleFactor by scaleFactor pixels, as on screen. The UI and any overlays aren't included.
+func (g *Game) SaveScreenshotPNG(path string, isUpscaled bool) error {
+	var img image.Image = g.currentFrame()
+	if isUpscaled && g.scaleFactor > 1 {
+		upscaled := image.NewRGBA(image.Rect(0, 0, g.gridX*g.scaleFactor, g.gridY*g.scaleFactor))
+		xdraw.NearestNeighbor.Scale(upscaled, upscaled.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+		img = upscaled
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Saves a screenshot to a PNG named after the time and the rules in the output directory, the same way as the GIF
+// recordings are named. See SaveScreenshotPNG.
+func (g *Game) saveScreenshot(isUpscaled bool) {
+	if err := os.MkdirAll(OUTPUT_DIR, os.ModePerm); err != nil {
+		log.Printf("could not create output directory: %v", err)
+		return
+	}
+	path := outputPath(recordingFileName(g.bRules, g.sRules, "png"))
+	if err := g.SaveScreenshotPNG(path, isUpscaled); err != nil {
+		log.Printf("could not save the screenshot: %v", err)
+		return
+	}
+	log.Printf("saved a screenshot to %v", path)
+}
@@ -0,0 +1,94 @@
+package game
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// The result of running a simulation from one seed during a seed search.
+type SeedScore struct {
+	Seed int64
+
+	// The number of cells which were born or died during the last quarter of the generations the seed was run for.
+	// Boards which die out or freeze score 0, so a high score means the board was still lively at the end. With
+	// SEARCH_BY_ENTROPY, the block entropy of the board after the last generation in thousandths of a bit instead, so
+	// that boards with more structure score higher.
+	Score int
+}
+
+// Runs a headless simulation for each of the numSeeds seeds starting at firstSeed, each for the given number of
+// generations, and returns the seeds sorted from highest to lowest score. The simulations have the same board size and
+// rules as template, whose board is left untouched. The seeds are run in parallel across POOL_SIZE goroutines.
+func SearchSeeds(template *Simulation, liveCellPercentage float64, firstSeed int64, numSeeds,
+	generations int) []SeedScore {
+	results := make([]SeedScore, numSeeds)
+	forEachInParallel(numSeeds, func(ind int) {
+		seed := firstSeed + int64(ind)
+		results[ind] = SeedScore{Seed: seed, Score: scoreSeed(template, liveCellPercentage, seed, generations)}
+	})
+
+	// Sort by descending score, breaking ties by seed so the output is deterministic.
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Seed < results[j].Seed
+	})
+	return results
+}
+
+// Calls f with each index from 0 to n-1, spread across POOL_SIZE goroutines, and returns once all the calls have
+// returned.
+func forEachInParallel(n int, f func(ind int)) {
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < POOL_SIZE; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ind := range indices {
+				f(ind)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+}
+
+// Runs a simulation from the given seed and returns its score, as described in SeedScore.
+func scoreSeed(template *Simulation, liveCellPercentage float64, seed int64, generations int) int {
+	s := template.blankCopy()
+	s.Randomize(liveCellPercentage, seed)
+
+	score := 0
+	prev := make([]int8, len(s.worldGrid))
+	for gen := 0; gen < generations; gen++ {
+		scoring := !SEARCH_BY_ENTROPY && gen >= generations-generations/4
+		if scoring {
+			copy(prev, s.worldGrid)
+		}
+
+		s.Step()
+
+		if scoring {
+			for i := range prev {
+				if prev[i]&1 != s.worldGrid[i]&1 {
+					score++
+				}
+			}
+		}
+	}
+	if SEARCH_BY_ENTROPY {
+		return int(math.Round(1000 * s.BlockEntropy()))
+	}
+	return score
+}
+
+// Runs a seed search with the game's current board size, rules and initial live cell percentage. See SearchSeeds.
+func (g *Game) SearchSeeds(numSeeds, generations int) []SeedScore {
+	return SearchSeeds(&g.Simulation, g.avgStartingLiveCellPercentage, SEED, numSeeds, generations)
+}
@@ -0,0 +1,107 @@
+package game
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// The colour of the outline of the rectangle being selected.
+var SELECTION_COLOR = color.RGBA{79, 195, 247, 255}
+
+// The colour of the outlines of the rule regions, drawn while paused.
+var RULE_REGION_COLOR = color.RGBA{255, 183, 77, 255}
+
+// Lets a rectangle of cells be selected by dragging with the right mouse button, and fills it with random cells at the
+// selected live cell percentage when the button is released. The cells outside the rectangle are left as they are.
+// With ALT held the right button erases cells instead, see handleDrawing.
+func (g *Game) handleSelection() {
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) && !ebiten.IsKeyPressed(ebiten.KeyAlt) {
+		if x, y, ok := g.screenToCell(ebiten.CursorPosition()); ok {
+			g.selectionStart = &image.Point{x, y}
+		}
+	}
+	if g.selectionStart == nil || !inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonRight) {
+		// The button may have been released while the game wasn't paused, which cancels the selection.
+		if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) {
+			g.selectionStart = nil
+		}
+		return
+	}
+
+	g.fillRandomly(g.selection(), g.ui.selectedLiveCellPercent)
+	g.selectionStart = nil
+}
+
+// Returns the cells selected so far, from where the drag started to the cell under the cursor, clamped to the board.
+func (g *Game) selection() image.Rectangle {
+	cx, cy := ebiten.CursorPosition()
+	offsetX, offsetY := g.boardOffset()
+	scale := g.drawScale()
+	x := clamp(0, g.gridX-1, int(float64(cx-offsetX)/scale))
+	y := clamp(0, g.gridY-1, int(float64(cy-offsetY)/scale))
+
+	// Both the start cell and the cursor cell are included whichever way the drag went.
+	sx, sy := g.selectionStart.X, g.selectionStart.Y
+	return image.Rect(intMin(sx, x), intMin(sy, y), intMax(sx, x)+1, intMax(sy, y)+1)
+}
+
+// Sets each cell in the rectangle alive with a chance of liveCellPercentage percent, and dead otherwise.
+func (g *Game) fillRandomly(rect image.Rectangle, liveCellPercentage float64) {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			g.paintCell(x, y, r.Float64()*100 < liveCellPercentage)
+		}
+	}
+	g.finishPainting()
+}
+
+// Gives the rectangle being selected the rules being edited in the pause menu on ENTER press, using up the selection,
+// or removes all rule regions on SHIFT+ENTER.
+func (g *Game) handleRuleRegions() {
+	if !inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		return
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		g.ClearRuleRegions()
+		return
+	}
+	if g.selectionStart == nil {
+		return
+	}
+
+	g.AddRuleRegion(g.selection(), g.ui.selectedBRules, g.ui.selectedSRules)
+	// The selection is used up, rather than filled when the mouse button is released.
+	g.selectionStart = nil
+}
+
+// Draws the outline of the rectangle being selected, if any, and of the rule regions.
+func (g *Game) drawSelection(screen *ebiten.Image) {
+	for _, region := range g.ruleRegions {
+		g.drawOutline(screen, region.rect.Intersect(image.Rect(0, 0, g.gridX, g.gridY)), RULE_REGION_COLOR)
+	}
+	if g.selectionStart != nil {
+		g.drawOutline(screen, g.selection(), SELECTION_COLOR)
+	}
+}
+
+// Draws the outline of a rectangle of cells in the given colour.
+func (g *Game) drawOutline(screen *ebiten.Image, rect image.Rectangle, clr color.Color) {
+	offsetX, offsetY := g.boardOffset()
+	scale := g.drawScale()
+	toScreen := func(p image.Point) image.Point {
+		return image.Pt(offsetX+int(float64(p.X)*scale), offsetY+int(float64(p.Y)*scale))
+	}
+	box := image.Rectangle{toScreen(rect.Min), toScreen(rect.Max)}
+
+	for _, edge := range []image.Rectangle{
+		image.Rect(box.Min.X, box.Min.Y, box.Max.X, box.Min.Y+1),
+		image.Rect(box.Min.X, box.Max.Y-1, box.Max.X, box.Max.Y),
+		image.Rect(box.Min.X, box.Min.Y, box.Min.X+1, box.Max.Y),
+		image.Rect(box.Max.X-1, box.Min.Y, box.Max.X, box.Max.Y),
+	} {
+		screen.SubImage(edge).(*ebiten.Image).Fill(clr)
+	}
+}
@@ -0,0 +1,250 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Settings which can be changed from the command line. These are set in main.go before the game is initialized.
+var (
+	// The format in which recordings are saved, either "gif" or "apng".
+	RECORD_FORMAT = "gif"
+
+	// Whether GIF recordings are written to disk frame by frame as they're captured, rather than all at once when
+	// the recording stops. This keeps memory use flat during long recordings.
+	STREAM_RECORDINGS = false
+
+	// The most frames a recording can have, after which it's stopped and written to file, or 0 for no limit.
+	MAX_RECORDING_FRAMES = 0
+
+	// Whether recordings play forwards and then backwards, so that they loop smoothly. Not for streamed recordings,
+	// which are written as they're captured.
+	BOOMERANG_RECORDINGS = false
+
+	// The number of chunks of rows per goroutine the board is split into for updating. Goroutines which finish their
+	// chunks take the ones left over, so more chunks keep them busy when the activity is in a few places on the board.
+	// 1 splits the board evenly between the goroutines.
+	CHUNKS_PER_WORKER = 8
+
+	// Whether to draw lines between the chunks of rows the board is split into for updating, for debugging.
+	SHOW_PARTITIONS = false
+
+	// Whether each generation only updates the cells around the live cells rather than the whole board, which is much
+	// faster for small patterns on a big board.
+	BOUNDED_UPDATES = false
+
+	// The size of the centered area the simulation is drawn in, as a percentage (1 to 100) of the screen width and
+	// height. The rest of the screen is filled with BORDER_COLOR.
+	SIM_AREA_PERCENT = 100
+
+	// The colour of the frame around the simulation area when SIM_AREA_PERCENT is less than 100.
+	BORDER_COLOR color.Color = color.RGBA{40, 40, 40, 255}
+
+	// An exact number of board updates per second to start the simulation at. If 0, the default speed is used.
+	GENERATIONS_PER_SECOND = 0.0
+
+	// The most board updates run per game update (i.e. per frame), however high the speed is set, so that the game
+	// stays responsive to input. 0 for no limit.
+	MAX_GENERATIONS_PER_FRAME = 1024
+
+	// Whether to start with the speed following the activity on the board, running quiet stretches faster and bursts
+	// of activity slower than the selected speed.
+	AUTO_SPEED = false
+
+	// The number of generations every new board is run for before it's first shown, to skip the start.
+	WARMUP_GENERATIONS = 0
+
+	// Whether seed searches score each seed by the block entropy of its board at the end, rather than by how active it
+	// was. See SeedScore.
+	SEARCH_BY_ENTROPY = false
+
+	// The birth and survival rules the game starts with, Conway's Game of Life by default.
+	INITIAL_B_RULES = Ruleset{3: true}
+	INITIAL_S_RULES = Ruleset{2: true, 3: true}
+
+	// The number of states of a Generations rule, counting alive and dead, or 2 for the usual rules.
+	STATES = 2
+
+	// The number of generations kept to step back through while paused, or 0 to keep none.
+	REWIND_GENERATIONS = 120
+
+	// The number of generations a cell can survive before dying of old age, or 0 for no limit.
+	LIFESPAN = 0
+
+	// The chances (0.0 to 1.0) that a birth or survival allowed by the rules actually happens.
+	BIRTH_PROBABILITY    = 1.0
+	SURVIVAL_PROBABILITY = 1.0
+
+	// How the cells at the edge of the board see the cells beyond it.
+	BOUNDARY_MODE = BOUNDARY_DEAD
+
+	// The symmetry of the initial random fill.
+	SYMMETRY = SYMMETRY_NONE
+
+	// How every new board is mirrored once it has been filled.
+	FLIP = FLIP_NONE
+
+	// A custom neighbourhood to use instead of the usual 8 surrounding cells, or nil for the usual one.
+	NEIGHBOUR_MASK NeighbourMask
+
+	// A Larger than Life rule to run instead of the birth and survival rules, or nil to use those.
+	LTL_RULE *LtLRule
+
+	// How long changes of speed take to ramp smoothly to the new speed, or 0 for instant changes.
+	SPEED_RAMP time.Duration
+
+	// Whether to signal notable board events (extinction, stabilizing, crossing CUE_POPULATION) with a flashing border
+	// and a beep.
+	CUES_ENABLED = false
+
+	// The population which triggers a cue when crossed, or 0 for no population cue.
+	CUE_POPULATION = 0
+
+	// Whether to pause the game when the board stops changing or starts repeating with a period of up to
+	// STABLE_MAX_PERIOD generations, unless it's recording.
+	PAUSE_WHEN_STABLE = false
+
+	// The colour of live cells, and of cells born in the last generation when TWO_TONE is set.
+	ALIVE_COLOR = color.RGBA{255, 255, 255, 255}
+	BORN_COLOR  = color.RGBA{79, 195, 247, 255}
+	TWO_TONE    = false
+
+	// Whether live cells start out coloured by age, fading from ALIVE_COLOR when born to OLD_COLOR after
+	// AGE_COLOR_STEPS generations.
+	AGE_COLORED = false
+	OLD_COLOR   = color.RGBA{48, 79, 254, 255}
+
+	// Whether dead cells start out showing fading trails of TRAIL_COLOR where cells recently died, and how much the
+	// trails fade on each frame, out of MAX_TRAIL.
+	TRAILS      = false
+	TRAIL_COLOR = color.RGBA{255, 112, 67, 255}
+	TRAIL_DECAY = 16
+
+	// The radius in cells of the airbrush for painting random cells while paused, and the percentage of the cells
+	// under it which it brings to life.
+	BRUSH_RADIUS  = 8
+	BRUSH_DENSITY = 20.0
+
+	// Whether there's a second board which starts from the same cells as the main one, and the rules it runs under.
+	// The two boards are drawn blended together, each in its own colour.
+	LAYER_ENABLED = false
+	LAYER_B_RULES = Ruleset{}
+	LAYER_S_RULES = Ruleset{}
+	LAYER_COLORS  = [2]color.RGBA{{255, 48, 48, 255}, {48, 96, 255, 255}}
+
+	// The longest side in pixels GIF recordings can have, larger frames being downscaled to fit, or 0 for no limit.
+	GIF_MAX_DIM = 0
+
+	// Whether GIF recordings with more than two colours, such as ones with a background, are dithered to the palette
+	// with Floyd-Steinberg dithering rather than each pixel being set to the nearest colour. Dithering is slower but
+	// doesn't band gradients. Black and white recordings are never dithered, as they have no colours in between.
+	GIF_DITHER = false
+
+	// The number of generations a new random board has to survive without dying out, or 0 to keep every board. Boards
+	// which don't are rerolled, see survival.go.
+	MIN_SURVIVAL = 0
+
+	// Whether boards are filled using StableRNG, which gives the same board for a seed with any Go version, rather
+	// than math/rand.
+	STABLE_RNG = false
+
+	// Whether GIF recordings skip frames which are the same as the one before, showing that one for longer instead.
+	// This shrinks recordings of boards which have stopped changing.
+	DEDUPE_FRAMES = false
+
+	// The size of the board in cells, or 0 by 0 to fit the board to the screen. A fixed size board is the same on
+	// every monitor, so that the same seed gives the same run everywhere, and is scaled to fit the screen.
+	BOARD_WIDTH  = 0
+	BOARD_HEIGHT = 0
+
+	// Whether the board starts out drawn like an old CRT monitor, with scanlines and a glow, and how strongly, from 0 to
+	// 1. Recordings never have the effect.
+	CRT_ENABLED   = false
+	CRT_INTENSITY = 0.5
+
+	// Whether cells start out always drawn as whole squares of screen pixels, see Game.drawScale.
+	PIXEL_PERFECT = false
+
+	// A pattern to fill new boards with copies of instead of random cells, or nil for random boards, and the number
+	// of dead cells between the copies.
+	TILE_PATTERN Pattern
+	TILE_SPACING = 4
+
+	// A pattern to start every board from, placed in the middle of an otherwise empty board, or nil for random boards.
+	INIT_PATTERN Pattern
+
+	// A reference pattern drawn as a faint ghost over the board, for comparing the board against, or nil for none.
+	GHOST_PATTERN Pattern
+
+	// The directory recordings and stats are written to.
+	OUTPUT_DIR = "output"
+
+	// A CSV file to write the population, births and deaths of every generation to, or "" for none.
+	STATS_FILE = ""
+
+	// A CSV file to log the rules of every board and every change to them to, with the generations and seeds, or ""
+	// for none.
+	RULE_LOG_FILE = ""
+
+	// An OpenType or TrueType font file to use for the UI text instead of the embedded font, or "" for the embedded one.
+	FONT_FILE = ""
+
+	// The change in population from one generation to the next, as a percentage, which triggers saving a GIF of the
+	// generations around it, or 0 for no captures. The GIF covers CAPTURE_BEFORE generations before the change and
+	// CAPTURE_AFTER generations after it.
+	CAPTURE_SPIKE_PERCENT = 0.0
+	CAPTURE_BEFORE        = 100
+	CAPTURE_AFTER         = 100
+
+	// How long the game runs before exiting by itself, or 0 to run until it's closed.
+	MAX_RUNTIME time.Duration
+
+	// How long each board runs before it's replaced by a fresh random one, or 0 to keep it. Time with the pause menu
+	// showing doesn't count. With IDLE_RESET_RULES, the new board also gets random rules.
+	IDLE_RESET       time.Duration
+	IDLE_RESET_RULES = false
+
+	// An image which can be shown through the dead cells, stretched to fill the board, or nil if there is none.
+	BACKGROUND_IMAGE image.Image
+)
+
+// The longest lifespan which can be set, limited by the size of the per-cell age counters.
+const MAX_LIFESPAN = 255
+
+// The fastest speed which can be set with GENERATIONS_PER_SECOND.
+const MAX_GENERATIONS_PER_SECOND = 10000.0
+
+// Parses a colour given as a hex string like "ff8800" or "#ff8800".
+func ParseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q, should be 6 hex digits like ff8800", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q: %v", s, err)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
+
+// Loads a PNG or JPEG image from a file.
+func LoadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode image %v: %v", path, err)
+	}
+	return img, nil
+}
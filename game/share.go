@@ -0,0 +1,214 @@
+package game
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// The number of light modules around the share overlay's QR code, which scanners need to find it. The standard asks
+// for 4.
+const QR_QUIET_ZONE = 4
+
+// Returns a short string describing the current run, which can be passed to ApplyShareString (or the -config flag) to
+// start the same run again, e.g. on someone else's computer. It's a query string like
+//
+//	rule=B3/S23&density=50&seed=5577006791947779410&size=960x540&scale=2&boundary=dead
+//
+// with the symmetry, random number generator, lifespan and birth and survival probabilities added when they're not the
+// defaults.
+func (g *Game) ShareString() string {
+	params := []string{
+		"rule=" + g.generationsRulestring(),
+		"density=" + strconv.FormatFloat(g.avgStartingLiveCellPercentage, 'g', -1, 64),
+		"seed=" + strconv.FormatInt(g.boardSeed, 10),
+		fmt.Sprintf("size=%vx%v", g.gridX, g.gridY),
+		"scale=" + strconv.Itoa(g.scaleFactor),
+		"boundary=" + g.boundaryMode.String(),
+	}
+	if g.symmetry != SYMMETRY_NONE {
+		params = append(params, "symmetry="+g.symmetry.String())
+	}
+	if STABLE_RNG {
+		params = append(params, "rng="+rngName())
+	}
+	if g.maxLifespan > 0 {
+		params = append(params, "lifespan="+strconv.Itoa(g.maxLifespan))
+	}
+	if g.birthProbability < 1 {
+		params = append(params, "birth-probability="+strconv.FormatFloat(g.birthProbability, 'g', -1, 64))
+	}
+	if g.survivalProbability < 1 {
+		params = append(params, "survival-probability="+strconv.FormatFloat(g.survivalProbability, 'g', -1, 64))
+	}
+	return strings.Join(params, "&")
+}
+
+// Sets up the game to run the configuration described by a string from ShareString. The next InitializeBoard call
+// starts the shared run. Parameters missing from the string keep their current values. If the shared board size
+// doesn't fit this screen, the board is still generated from the shared seed but will look different.
+func (g *Game) ApplyShareString(str string) error {
+	params, err := url.ParseQuery(str)
+	if err != nil {
+		return fmt.Errorf("invalid config %q: %v", str, err)
+	}
+
+	if params.Has("rule") {
+		bRules, sRules, states, err := ParseGenerationsRule(params.Get("rule"))
+		if err != nil {
+			return err
+		}
+		if states > 2 && (g.mask != nil || g.ltl != nil) {
+			return fmt.Errorf("the Generations rule %q only works with the usual neighbourhood", params.Get("rule"))
+		}
+		g.useRules(bRules, sRules)
+		STATES = states
+		g.SetStates(states)
+	}
+
+	if params.Has("density") {
+		density, err := strconv.ParseFloat(params.Get("density"), 64)
+		if err != nil || density < 0 || density > 100 {
+			return fmt.Errorf("invalid density %q, should be between 0 and 100", params.Get("density"))
+		}
+		g.avgStartingLiveCellPercentage = density
+		g.ui.selectedLiveCellPercent = density
+	}
+
+	if params.Has("seed") {
+		seed, err := strconv.ParseInt(params.Get("seed"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid seed %q", params.Get("seed"))
+		}
+		g.UseSeed(seed)
+	}
+
+	if params.Has("scale") {
+		scale, err := strconv.Atoi(params.Get("scale"))
+		if err != nil {
+			return fmt.Errorf("invalid scale %q", params.Get("scale"))
+		}
+		found := false
+		for i, s := range g.ui.possibleScaleFactors {
+			if s == scale {
+				g.ui.scaleFactorIndex = i
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("scale %v isn't possible on this screen, should be one of %v", scale,
+				g.ui.possibleScaleFactors)
+		}
+		g.scaleFactor = scale
+	}
+
+	if params.Has("boundary") {
+		m, err := ParseBoundaryMode(params.Get("boundary"))
+		if err != nil {
+			return err
+		}
+		g.boundaryMode = m
+		g.ui.selectedBoundaryMode = m
+	}
+
+	if params.Has("symmetry") {
+		sym, err := ParseSymmetry(params.Get("symmetry"))
+		if err != nil {
+			return err
+		}
+		g.symmetry = sym
+		g.ui.selectedSymmetry = sym
+	}
+
+	if params.Has("rng") {
+		if err := SetRNG(params.Get("rng")); err != nil {
+			return err
+		}
+	}
+
+	if params.Has("lifespan") {
+		lifespan, err := strconv.Atoi(params.Get("lifespan"))
+		if err != nil || lifespan < 0 || lifespan > MAX_LIFESPAN {
+			return fmt.Errorf("invalid lifespan %q, should be between 0 and %v", params.Get("lifespan"), MAX_LIFESPAN)
+		}
+		g.maxLifespan = lifespan
+	}
+
+	birth, survival := g.birthProbability, g.survivalProbability
+	for name, p := range map[string]*float64{"birth-probability": &birth, "survival-probability": &survival} {
+		if params.Has(name) {
+			v, err := strconv.ParseFloat(params.Get(name), 64)
+			if err != nil || v < 0 || v > 1 {
+				return fmt.Errorf("invalid %v %q, should be between 0 and 1", name, params.Get(name))
+			}
+			*p = v
+		}
+	}
+	g.SetProbabilities(birth, survival)
+
+	// The board size follows from the screen size and scale, or from -board, so it can only be checked.
+	boardX, boardY := boardSize(g.scaleFactor)
+	size := fmt.Sprintf("%vx%v", boardX, boardY)
+	if params.Has("size") && params.Get("size") != size {
+		log.Printf("shared board size is %v but the board here will be %v, so it will look different", params.Get("size"),
+			size)
+	}
+
+	return nil
+}
+
+// Draws the rules and seed with a QR code of the share string under them in the lower left corner, above where cues
+// are shown, so that screenshots say how to run what they show again.
+func (g *Game) drawShareOverlay(screen *ebiten.Image) {
+	str := g.ShareString()
+	if g.shareQRImage == nil || str != g.shareQRString {
+		modules, err := encodeQR([]byte(str))
+		if err != nil {
+			log.Printf("can't draw the share QR code: %v", err)
+			g.isShareOverlayVisible = false
+			return
+		}
+		size := len(modules) + 2*QR_QUIET_ZONE
+		g.shareQRImage = ebiten.NewImage(size, size)
+		g.shareQRImage.Fill(color.White)
+		for y, row := range modules {
+			for x, isDark := range row {
+				if isDark {
+					g.shareQRImage.Set(x+QR_QUIET_ZONE, y+QR_QUIET_ZONE, color.Black)
+				}
+			}
+		}
+		g.shareQRString = str
+	}
+
+	// Scale the code up to whole pixels per module, about a quarter of the screen height.
+	_, screenY := screen.Size()
+	size := g.shareQRImage.Bounds().Dx()
+	scale := screenY / 4 / size
+	if scale < 1 {
+		scale = 1
+	}
+
+	h := g.ui.fontFace.Metrics().Height.Round()
+	qrY := screenY - MARGIN - h - size*scale // Leaves a line free for cues.
+	options := &ebiten.DrawImageOptions{}
+	options.GeoM.Scale(float64(scale), float64(scale))
+	options.GeoM.Translate(MARGIN, float64(qrY))
+	screen.DrawImage(g.shareQRImage, options)
+
+	drawTextWithShadow(screen, FormatRulestring(g.bRules, g.sRules), g.ui.fontFace, MARGIN, qrY-h-MARGIN)
+	drawTextWithShadow(screen, fmt.Sprintf("seed %v", g.boardSeed), g.ui.fontFace, MARGIN, qrY-MARGIN)
+}
+
+// Makes the game start with the given rules, with no previous rules to switch back to.
+func (g *Game) useRules(bRules, sRules Ruleset) {
+	g.bRules, g.sRules = bRules, sRules
+	g.prevBRules, g.prevSRules = bRules, sRules
+	g.updateTables()
+	g.ui.selectedBRules, g.ui.selectedSRules = bRules, sRules
+}
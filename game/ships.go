@@ -0,0 +1,128 @@
+package game
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// The colour of the boxes drawn around spaceships when they're highlighted.
+var SHIP_COLOR = color.RGBA{105, 240, 174, 255}
+
+// The largest spaceships which are recognized: the most cells across their bounding box in either direction, and the
+// most generations they can take to come back to their shape.
+const (
+	SHIP_MAX_SIZE   = 6
+	SHIP_MAX_PERIOD = 4
+)
+
+// Returns the bounding boxes of the spaceships on the board, in board coordinates. A spaceship is a group of live cells
+// at least 2 dead cells away from any others, so that nothing else affects its next generation, which on its own comes
+// back to the same shape somewhere else within SHIP_MAX_PERIOD generations. Which shapes are spaceships under the
+// current rules is remembered in isShipByShape, so each shape is only run once. Nothing is recognized with a mask, a
+// Larger than Life rule or Generations states.
+func (s *Simulation) findShips(isShipByShape map[string]bool) []image.Rectangle {
+	if s.mask != nil || s.ltl != nil || s.states > 2 || s.becomesAliveTable[0] {
+		return nil
+	}
+
+	var ships []image.Rectangle
+	rowLen := s.gridX + 2
+	visited := make([]bool, len(s.worldGrid))
+	stack := []int{}
+	for start, val := range s.worldGrid {
+		if val&1 == 0 || visited[start] {
+			continue
+		}
+
+		// Find the group of live cells around this one, each within 2 cells of another.
+		bounds := cellRect{start % rowLen, start / rowLen, start % rowLen, start / rowLen}
+		visited[start] = true
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			ind := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := ind%rowLen, ind/rowLen
+			bounds = bounds.including(x, y)
+			for ny := intMax(1, y-2); ny <= intMin(s.gridY, y+2); ny++ {
+				for nx := intMax(1, x-2); nx <= intMin(s.gridX, x+2); nx++ {
+					if n := ny*rowLen + nx; s.worldGrid[n]&1 == 1 && !visited[n] {
+						visited[n] = true
+						stack = append(stack, n)
+					}
+				}
+			}
+		}
+
+		if bounds.maxX-bounds.minX >= SHIP_MAX_SIZE || bounds.maxY-bounds.minY >= SHIP_MAX_SIZE {
+			continue
+		}
+		rect := image.Rect(bounds.minX-1, bounds.minY-1, bounds.maxX, bounds.maxY)
+		shape := s.patternAt(rect)
+		key := shape.String()
+		isShip, ok := isShipByShape[key]
+		if !ok {
+			isShip = s.isShip(shape)
+			isShipByShape[key] = isShip
+		}
+		if isShip {
+			ships = append(ships, rect)
+		}
+	}
+	return ships
+}
+
+// Returns the cells in rect, in board coordinates, as a pattern.
+func (s *Simulation) patternAt(rect image.Rectangle) Pattern {
+	p := make(Pattern, rect.Dy())
+	for y := range p {
+		p[y] = make([]bool, rect.Dx())
+		for x := range p[y] {
+			p[y][x] = s.Get(rect.Min.X+x, rect.Min.Y+y)
+		}
+	}
+	return p
+}
+
+// Returns whether the pattern, which must fill its bounding box, comes back to the same shape in a different place
+// within SHIP_MAX_PERIOD generations under the simulation's rules, when run on its own.
+func (s *Simulation) isShip(p Pattern) bool {
+	w, h := p.size()
+	// A spaceship can't move faster than a cell per generation.
+	margin := SHIP_MAX_PERIOD + 1
+	t := NewSimulation(w+2*margin, h+2*margin, s.bRules, s.sRules)
+	t.stamp(p, margin, margin)
+	shape := p.String()
+
+	for gen := 0; gen < SHIP_MAX_PERIOD; gen++ {
+		t.Step()
+		bounds := t.findLiveBounds(cellRect{1, 1, t.gridX, t.gridY})
+		if bounds.isEmpty() {
+			return false
+		}
+		rect := image.Rect(bounds.minX-1, bounds.minY-1, bounds.maxX, bounds.maxY)
+		if rect.Min != image.Pt(margin, margin) && t.patternAt(rect).String() == shape {
+			return true
+		}
+	}
+	return false
+}
+
+// Draws a box around each spaceship on the board, finding them again whenever the generation has changed since they
+// were last found, or on every frame while paused, since cells can be painted then. See findShips.
+func (g *Game) drawShips(screen *ebiten.Image) {
+	if rule := FormatRulestring(g.bRules, g.sRules); g.isShipByShape == nil || rule != g.shipRule {
+		g.isShipByShape = map[string]bool{}
+		g.shipRule = rule
+		g.shipsGeneration = -1
+	}
+	if g.isPaused || g.shipsGeneration != g.generation {
+		g.ships = g.findShips(g.isShipByShape)
+		g.shipsGeneration = g.generation
+	}
+
+	for _, rect := range g.ships {
+		g.drawOutline(screen, rect.Inset(-1), SHIP_COLOR)
+	}
+}
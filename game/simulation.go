@@ -0,0 +1,760 @@
+package game
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// A Simulation holds a board and the rules it evolves under. It knows nothing about input or drawing to the screen, so
+// it can also be run headlessly, for example to search for interesting seeds.
+type Simulation struct {
+	// Grid state.
+	// Each int8 value represents both the state of the cell at that position (dead or alive) and the number of living
+	// neighbours of that cell, from 0 to 8 with the usual neighbourhood.
+	//
+	// The last bit is 0 if the cell is dead and 1 if the cell is alive.
+	// The other bits (value>>1, i.e. all except the last) represent the number of living neighbours. A cell doesn't
+	// count itself as a neighbour.
+	//
+	// The worldGrid slice is implicitly two dimensional. There is a one cell border around the grid filled with cells
+	// which are always dead. This allows us to skip index out of bounds checks.
+	//
+	// The grid size is dependent on the scale factor when the simulation is being displayed.
+	worldGrid    []int8
+	buffer       []int8
+	gridX, gridY int
+
+	// The raw pixels of our image. Each image pixel is represented as 4 bytes in pixels (RGBA channels), so we must
+	// have len(pixels) = 4 * (img width) * (img height).
+	pixels []byte
+
+	// Game rules.
+	// A dead cell becomes alive iff bRules at the number of its living neighbours (out of 8) is true
+	// A living cell stays alive iff SRules at the number of its living neighbours (out of 8) is true
+	// These rules do NOT count a live cell as its own neighbour.
+	bRules Ruleset
+	sRules Ruleset
+
+	becomesAliveTable [18]bool
+	becomesDeadTable  [18]bool
+
+	// The predicates the transition tables were filled from. These can allow more than 8 neighbours, which only
+	// matters with a mask.
+	birthRule    RulePredicate
+	survivalRule RulePredicate
+
+	// Rectangles of cells which follow their own rules, and which of them each cell is in. See ruleregions.go.
+	ruleRegions     []ruleRegion
+	ruleRegionIndex []uint8
+
+	// A custom neighbourhood, or nil for the usual 8 cell one, and the transition tables for it, indexed by the number
+	// of live neighbours in the mask. See mask.go.
+	mask              NeighbourMask
+	maskBirthTable    []bool
+	maskSurvivalTable []bool
+
+	// A Larger than Life rule which replaces the rules above, or nil, and the summed-area table used to update the
+	// board under it. See ltl.go.
+	ltl     *LtLRule
+	ltlSums []int32
+
+	// WaitGroup used to wait until all tasks are done.
+	wg sync.WaitGroup
+
+	// The number of generations a cell can survive before it dies of old age regardless of its neighbours, or 0 if
+	// cells live forever. age holds the number of generations each cell has survived so far, using the same indexing
+	// as worldGrid, and is only kept up to date while maxLifespan is set.
+	maxLifespan int
+	age         []uint8
+
+	// The number of states of a Generations rule, with 2 (or 0) for the usual alive and dead, and for each cell the
+	// number of generations it has left dying, using the same indexing as worldGrid. See generations.go.
+	states int
+	dying  []uint8
+
+	// Which cells are immortal, using the same indexing as worldGrid. Immortal cells are alive and never die, whatever
+	// the rules say, but count as neighbours like any other live cell. Useful for building walls and fixed sources.
+	// hasImmortals is set once any cell is made immortal, and stays set until the board is allocated again.
+	immortal     []bool
+	hasImmortals bool
+
+	// The chances (0.0 to 1.0) that a cell which the rules say is born is actually born, and that a cell which the rules
+	// say survives actually survives. Both are 1 for the usual deterministic rules.
+	birthProbability    float64
+	survivalProbability float64
+
+	// Seed for the random decisions made when the probabilities are less than 1. Together with the generation and
+	// the cell position this determines each random decision, so the results don't depend on how the board is split
+	// up between goroutines.
+	noiseSeed uint64
+
+	// The number of generations simulated since the board was initialized.
+	generation int
+
+	// How the cells at the edge of the board see the cells beyond it.
+	boundaryMode BoundaryMode
+
+	// Whether cells born in the last generation are drawn in the born colour rather than the usual live colour.
+	isTwoTone bool
+
+	// Whether live cells are drawn in a colour which fades with their age, see agecolors.go. Ages are kept up to date
+	// while this is set, as with maxLifespan.
+	isAgeColored bool
+
+	// The symmetry of the initial random fill.
+	symmetry Symmetry
+
+	// Whether to count the cells born and the cells which died in each generation, and the counts for the last one.
+	// Counting takes an extra pass over the board, so it's only done when needed.
+	isCountingChanges bool
+	births, deaths    int
+
+	// Whether each generation only updates the cells around the live cells rather than the whole board, see bounds.go.
+	isBounded bool
+
+	// A rectangle holding every live cell, and whether it's known. Bounded updates and setCell keep it up to date, and
+	// after any other change to the board it's found again by searching the whole board.
+	liveBounds        cellRect
+	isLiveBoundsKnown bool
+
+	// The cells being updated in the current generation.
+	region cellRect
+}
+
+// Creates a simulation with an empty board of the given size which runs under the given rules.
+func NewSimulation(gridX, gridY int, bRules, sRules Ruleset) *Simulation {
+	s := &Simulation{bRules: bRules, sRules: sRules, birthProbability: 1, survivalProbability: 1}
+	s.updateTables()
+	s.allocate(gridX, gridY)
+	return s
+}
+
+// Returns a simulation with an empty board of the same size as this one, running under the same rules.
+func (s *Simulation) blankCopy() *Simulation {
+	res := NewSimulation(s.gridX, s.gridY, s.bRules, s.sRules)
+	res.maxLifespan = s.maxLifespan
+	res.birthProbability, res.survivalProbability = s.birthProbability, s.survivalProbability
+	res.boundaryMode = s.boundaryMode
+	res.mask = s.mask
+	res.ltl = s.ltl
+	res.states = s.states
+	res.symmetry = s.symmetry
+	res.isBounded = s.isBounded
+	res.isAgeColored = s.isAgeColored
+	res.fillTables(s.birthRule, s.survivalRule)
+	return res
+}
+
+// Randomizes the board using the given seed. The chance of a given cell being set to alive is liveCellPercentage
+// percent. Boards without a symmetry are filled in parallel, giving the same board whatever POOL_SIZE is.
+func (s *Simulation) Randomize(liveCellPercentage float64, seed int64) {
+	if s.symmetry == SYMMETRY_NONE {
+		s.randomizeBlocks(liveCellPercentage, seed)
+	} else {
+		s.randomize(liveCellPercentage, newRNG(seed))
+	}
+}
+
+// Advances the simulation by one generation.
+func (s *Simulation) Step() {
+	s.updateBoard()
+}
+
+// Sets the number of generations a cell can survive before it dies of old age, or 0 to let cells live forever. The
+// ages of all cells are reset.
+func (s *Simulation) SetMaxLifespan(generations int) {
+	s.maxLifespan = clamp(0, MAX_LIFESPAN, generations)
+	for i := range s.age {
+		s.age[i] = 0
+	}
+}
+
+// Sets the chances (0.0 to 1.0) that births and survivals allowed by the rules actually happen. Probabilities of 1
+// give the usual deterministic rules.
+func (s *Simulation) SetProbabilities(birth, survival float64) {
+	s.birthProbability = clamp(0, 1, birth)
+	s.survivalProbability = clamp(0, 1, survival)
+}
+
+// Returns whether any rule modifiers or rule regions are in use, in which case the board is updated with
+// updateRangeGeneral.
+func (s *Simulation) hasRuleModifiers() bool {
+	return s.maxLifespan > 0 || s.birthProbability < 1 || s.survivalProbability < 1 || s.ruleRegionIndex != nil ||
+		s.states > 2 || s.isAgeColored || s.hasImmortals
+}
+
+// Returns the number of live cells on the board. The rows are split between POOL_SIZE goroutines, each counting its
+// own rows before adding its count to the total, so the count is exact and only takes one pass over the board.
+func (s *Simulation) Population() int {
+	var total atomic.Int64
+	s.forRowRanges(func(minY, maxY int) {
+		count := 0
+		for y := minY; y <= maxY; y++ {
+			for _, val := range s.worldGrid[y*(s.gridX+2)+1 : (y+1)*(s.gridX+2)-1] {
+				count += int(val & 1)
+			}
+		}
+		total.Add(int64(count))
+	})
+	return int(total.Load())
+}
+
+// Returns true with the given probability. The result is a deterministic function of the noise seed, the current
+// generation and the cell index ind, so it doesn't matter which goroutine asks or in which order.
+func (s *Simulation) chance(ind int, probability float64) bool {
+	// SplitMix64 finalizer over the combined inputs.
+	z := s.noiseSeed ^ uint64(s.generation)*0x9e3779b97f4a7c15 ^ uint64(ind)*0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return float64(z>>11)/(1<<53) < probability
+}
+
+// Update board rows from minY to maxY inclusive.
+func (s *Simulation) updateRange(minY, maxY int) {
+	// Rule modifiers are handled by a slower general version of this function, so that the common case stays fast.
+	if s.hasRuleModifiers() {
+		s.updateRangeGeneral(minY, maxY)
+		return
+	}
+
+	// Update the game board.
+	// We do this more efficiently by copying the board state into a buffer and modifying only those cells in the
+	// buffer which are changing state (becoming alive or dying).
+	minX, maxX := s.region.minX, s.region.maxX
+	for i := minY; i <= maxY; i++ {
+		for j := minX; j <= maxX; j++ {
+			// Getting the "2D s.worldGrid[i][j]" index from the 1D slice. +2 because of the board edge border.
+			val := s.worldGrid[i*(s.gridX+2)+j]
+			gridXPlusTwo := s.gridX + 2
+
+			if s.becomesAliveTable[val] { // Checking if the cell is becoming alive. val&1 == 0 ensures that
+				// this cell was dead previously, and val>>1 gets the number of live neighbours.
+
+				// s.buffer[ind] |= 1 // Set the last bit to 1 to indicate that this cell is now alive.
+				s.buffer[(i-1)*(gridXPlusTwo)+j-1] += 2
+				s.buffer[(i-1)*(gridXPlusTwo)+j] += 2
+				s.buffer[(i-1)*(gridXPlusTwo)+j+1] += 2
+				s.buffer[(i)*(gridXPlusTwo)+j-1] += 2
+				s.buffer[(i)*(gridXPlusTwo)+j] += 1
+				s.buffer[(i)*(gridXPlusTwo)+j+1] += 2
+				s.buffer[(i+1)*(gridXPlusTwo)+j-1] += 2
+				s.buffer[(i+1)*(gridXPlusTwo)+j] += 2
+				s.buffer[(i+1)*(gridXPlusTwo)+j+1] += 2
+				// -1 because i and j and 1-indexed due to the border, which the game board image doesn't have.
+				setPixel(s.pixels, s.gridX, j-1, i-1, 0)
+
+			} else if s.becomesDeadTable[val] { // Checking if the cell is becoming dead. val&1 == 1 ensures
+				// that this cell was alive previously. Since this cell is alive, val>>1 is the one more than the number
+				// of live neighbours, as this cell is also counted in val>1, so we check val>>1-1 in SRules.
+
+				// The rest of this case is analogous to the cell becoming alive case.
+				// s.buffer[ind] -= 1 // Set the last bit to 0 to indicate that this cell is now dead.
+				s.buffer[(i-1)*(gridXPlusTwo)+j-1] -= 2
+				s.buffer[(i-1)*(gridXPlusTwo)+j] -= 2
+				s.buffer[(i-1)*(gridXPlusTwo)+j+1] -= 2
+				s.buffer[(i)*(gridXPlusTwo)+j-1] -= 2
+				s.buffer[(i)*(gridXPlusTwo)+j] -= 1
+				s.buffer[(i)*(gridXPlusTwo)+j+1] -= 2
+				s.buffer[(i+1)*(gridXPlusTwo)+j-1] -= 2
+				s.buffer[(i+1)*(gridXPlusTwo)+j] -= 2
+				s.buffer[(i+1)*(gridXPlusTwo)+j+1] -= 2
+				setPixel(s.pixels, s.gridX, j-1, i-1, 1)
+			}
+		}
+	}
+}
+
+// Like updateRange, but also handles the rule modifiers: cells dying of old age and probabilistic transitions, immortal
+// cells, cells in rule regions following their region's tables, and the dying states of Generations rules. It also
+// keeps the ages of the cells, and redraws the surviving cells when they're coloured by age.
+func (s *Simulation) updateRangeGeneral(minY, maxY int) {
+	minX, maxX := s.region.minX, s.region.maxX
+	for i := minY; i <= maxY; i++ {
+		for j := minX; j <= maxX; j++ {
+			ind := i*(s.gridX+2) + j
+			val := s.worldGrid[ind]
+			becomesAliveTable, becomesDeadTable := s.tablesAt(ind)
+
+			if s.dying[ind] > 0 {
+				// A dying cell can't be born, and only gets closer to dead.
+				s.dying[ind]--
+				s.setDyingPixel(j-1, i-1, s.dying[ind])
+			} else if becomesAliveTable[val] {
+				if s.birthProbability < 1 && !s.chance(ind, s.birthProbability) {
+					continue
+				}
+				s.addToNeighbourhood(ind, 2)
+				s.age[ind] = 0
+				setPixel(s.pixels, s.gridX, j-1, i-1, 0)
+			} else if val&1 == 1 {
+				// A cell which survives by the rules can still die of old age or by chance, unless it's immortal. Either
+				// way the neighbour counts are updated the same.
+				if !s.immortal[ind] && (becomesDeadTable[val] ||
+					(s.maxLifespan > 0 && int(s.age[ind]) >= s.maxLifespan) ||
+					(s.survivalProbability < 1 && !s.chance(ind, s.survivalProbability))) {
+					s.addToNeighbourhood(ind, -2)
+					if s.states > 2 {
+						s.dying[ind] = uint8(s.states - 2)
+					}
+					s.setDyingPixel(j-1, i-1, s.dying[ind])
+				} else {
+					s.growOlder(ind)
+					if s.isAgeColored {
+						setPixel(s.pixels, s.gridX, j-1, i-1, s.liveColor(ind))
+					}
+				}
+			}
+		}
+	}
+}
+
+// Adds delta to the neighbour counts in the buffer of the 8 cells around the cell at index ind and sets or clears
+// that cell's alive bit, for delta 2 and -2 respectively.
+func (s *Simulation) addToNeighbourhood(ind int, delta int8) {
+	rowLen := s.gridX + 2
+	s.buffer[ind-rowLen-1] += delta
+	s.buffer[ind-rowLen] += delta
+	s.buffer[ind-rowLen+1] += delta
+	s.buffer[ind-1] += delta
+	s.buffer[ind] += delta / 2
+	s.buffer[ind+1] += delta
+	s.buffer[ind+rowLen-1] += delta
+	s.buffer[ind+rowLen] += delta
+	s.buffer[ind+rowLen+1] += delta
+}
+
+// Update the game board. To do this efficiently we copy the board state into a buffer and modifying only those cells in
+// the buffer which are changing state (becoming alive or dying).
+func (s *Simulation) updateBoard() error {
+	if s.ltl != nil {
+		s.updateBoardLtL()
+		s.isLiveBoundsKnown = false
+		return nil
+	}
+	if s.mask != nil {
+		s.updateBoardMasked()
+		s.isLiveBoundsKnown = false
+		return nil
+	}
+
+	s.region = s.updateRegion()
+	if s.region.isEmpty() {
+		// There are no live cells, so nothing can change.
+		s.births, s.deaths = 0, 0
+		s.generation++
+		return nil
+	}
+
+	// Only the rows of the region and the rows either side of it can change.
+	minY, maxY := s.region.minY, s.region.maxY
+	lo, hi := (minY-1)*(s.gridX+2), (maxY+2)*(s.gridX+2)
+	copy(s.buffer[lo:hi], s.worldGrid[lo:hi])
+
+	if maxY-minY+1 < MIN_CHUNK_ROWS {
+		// Too small to split up.
+		s.updateRanges([][2]int{{minY, maxY}})
+	} else {
+		// Updating a row changes the neighbour counts of the rows next to it, so chunks of rows can only be updated at
+		// the same time if they're apart. First update the inside of every chunk, then the rows where chunks meet.
+		starts := s.chunkStarts(minY, maxY)
+		var insides, edges [][2]int
+		for i := 0; i+1 < len(starts); i++ {
+			insides = append(insides, [2]int{starts[i] + 1, starts[i+1] - 2})
+		}
+		edges = append(edges, [2]int{minY, minY}, [2]int{maxY, maxY})
+		for _, y := range starts[1 : len(starts)-1] {
+			edges = append(edges, [2]int{y - 1, y})
+		}
+		s.updateRanges(insides)
+		s.updateRanges(edges)
+	}
+
+	if s.isTwoTone {
+		s.colorNewborns()
+	}
+	if s.isCountingChanges {
+		s.countChanges()
+	}
+
+	copy(s.worldGrid[lo:hi], s.buffer[lo:hi])
+	s.fixEdgeCounts()
+
+	// Cells can only have been born in the region, so that's the only place the new bounding box can be.
+	if s.isBounded {
+		s.liveBounds = s.findLiveBounds(s.region)
+	}
+	s.isLiveBoundsKnown = s.isBounded
+
+	s.generation++
+
+	return nil
+}
+
+// Draws the live cells in the born colour if they were dead in the last generation, and in the usual live colour
+// otherwise. Must be called after the buffer has been updated but before it's copied back to worldGrid, since the
+// states before and after the update are compared. The update only draws the cells which change, so the cells born
+// in the last generation are redrawn here too. Only the cells in the region being updated can have changed.
+func (s *Simulation) colorNewborns() {
+	for i := s.region.minY; i <= s.region.maxY; i++ {
+		for j := s.region.minX; j <= s.region.maxX; j++ {
+			ind := i*(s.gridX+2) + j
+			if s.buffer[ind]&1 == 1 {
+				colorIndex := s.liveColor(ind)
+				if s.worldGrid[ind]&1 == 0 {
+					colorIndex = 2
+				}
+				setPixel(s.pixels, s.gridX, j-1, i-1, colorIndex)
+			}
+		}
+	}
+}
+
+// Counts the cells born and the cells which died in the generation just computed. Like colorNewborns, must be called
+// before the buffer is copied back to worldGrid.
+func (s *Simulation) countChanges() {
+	s.births, s.deaths = 0, 0
+	for i := s.region.minY; i <= s.region.maxY; i++ {
+		for j := s.region.minX; j <= s.region.maxX; j++ {
+			ind := i*(s.gridX+2) + j
+			if was, is := s.worldGrid[ind]&1, s.buffer[ind]&1; was != is {
+				if is == 1 {
+					s.births++
+				} else {
+					s.deaths++
+				}
+			}
+		}
+	}
+}
+
+// Sets whether cells born in the last generation are drawn in the born colour, redrawing the live cells to match.
+func (s *Simulation) SetTwoTone(twoTone bool) {
+	s.isTwoTone = twoTone
+	for i := 1; i <= s.gridY; i++ {
+		for j := 1; j <= s.gridX; j++ {
+			if ind := i*(s.gridX+2) + j; s.worldGrid[ind]&1 == 1 {
+				setPixel(s.pixels, s.gridX, j-1, i-1, s.liveColor(ind))
+			}
+		}
+	}
+}
+
+// Rebuilds the transition tables from the current rules.
+func (s *Simulation) updateTables() {
+	s.fillTables(s.bRules.Predicate(), s.sRules.Predicate())
+}
+
+// Fills the transition tables from birth and survival predicates. Index 2*n of becomesAliveTable is a dead cell with n
+// live neighbours, and index 2*n+1 of becomesDeadTable is a live cell with n live neighbours. The mask tables are
+// filled too if a mask is set.
+func (s *Simulation) fillTables(birth, survival RulePredicate) {
+	s.birthRule, s.survivalRule = birth, survival
+	if s.mask != nil {
+		s.maskBirthTable = make([]bool, len(s.mask)+1)
+		s.maskSurvivalTable = make([]bool, len(s.mask)+1)
+		for n := range s.maskBirthTable {
+			s.maskBirthTable[n] = birth(n)
+			s.maskSurvivalTable[n] = survival(n)
+		}
+	}
+
+	fillTransitionTables(&s.becomesAliveTable, &s.becomesDeadTable, birth, survival)
+}
+
+// Fills transition tables for the usual 8 cell neighbourhood from birth and survival predicates, as described for
+// fillTables.
+func fillTransitionTables(becomesAliveTable, becomesDeadTable *[18]bool, birth, survival RulePredicate) {
+	for i := 0; i < len(becomesAliveTable); i++ {
+		becomesAliveTable[i] = false
+		becomesDeadTable[i] = false
+	}
+
+	for n := 0; n <= 8; n++ {
+		if birth(n) {
+			becomesAliveTable[2*n] = true
+		}
+		if !survival(n) {
+			becomesDeadTable[1+2*n] = true
+		}
+	}
+}
+
+// Allocates an empty board of the given size, along with the pixels of the corresponding image (all black).
+func (s *Simulation) allocate(gridX, gridY int) {
+	s.gridX = gridX
+	s.gridY = gridY
+
+	// RGBA channels, so 4 bytes per image pixel.
+	s.pixels = make([]byte, 4*s.gridX*s.gridY)
+
+	// Make all pixels black initially.
+	for i := 0; i < s.gridY; i++ {
+		for j := 0; j < s.gridX; j++ {
+			setPixel(s.pixels, s.gridX, j, i, 1)
+		}
+	}
+
+	s.worldGrid = make([]int8, (s.gridX+2)*(s.gridY+2))
+	s.buffer = make([]int8, (s.gridX+2)*(s.gridY+2))
+	s.age = make([]uint8, (s.gridX+2)*(s.gridY+2))
+	s.dying = make([]uint8, (s.gridX+2)*(s.gridY+2))
+	s.immortal = make([]bool, (s.gridX+2)*(s.gridY+2))
+	s.hasImmortals = false
+	s.indexRuleRegions()
+	s.generation = 0
+	s.isLiveBoundsKnown = false
+	s.region = cellRect{1, 1, 0, 0}
+}
+
+// Randomly fills the board, which must be empty. The chance of a given cell being set to alive is liveCellPercentage
+// percent. With a symmetry, the cells which are mirror images of each other all get the state of the first of them.
+func (s *Simulation) randomize(liveCellPercentage float64, rng RNG) {
+	seen := map[[2]int]bool{}
+	for i := 1; i <= s.gridY; i++ {
+		for j := 1; j <= s.gridX; j++ {
+			var isAlive bool
+			if s.symmetry == SYMMETRY_NONE {
+				isAlive = int(rng.Int63n(100000)) < int(1000*liveCellPercentage)
+			} else {
+				key := s.symmetryKey(j-1, i-1)
+				var ok bool
+				if isAlive, ok = seen[key]; !ok {
+					isAlive = int(rng.Int63n(100000)) < int(1000*liveCellPercentage)
+					seen[key] = isAlive
+				}
+			}
+
+			if isAlive { // Cell becomes alive.
+				s.worldGrid[i*(s.gridX+2)+j] |= 1
+				// s.pixels.Set(j-1, i-1, color.White)
+				setPixel(s.pixels, s.gridX, j-1, i-1, 0)
+				// Update live neighbour counts in the cells affected by this cell becoming alive.
+				for a := -1; a <= 1; a++ {
+					for b := -1; b <= 1; b++ {
+						if (a != 0) || (b != 0) {
+							s.worldGrid[(i+a)*(s.gridX+2)+j+b] += 2
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if s.mask != nil {
+		s.recountMasked()
+	} else {
+		s.fixEdgeCounts()
+	}
+
+	// Also derive the seed for probabilistic rules from the random source, so that the whole run is reproducible.
+	s.noiseSeed = uint64(rng.Int63())
+}
+
+// The fewest rows in a chunk of the board updated in parallel. Updating the two rows where chunks meet changes the
+// counts of the rows either side of them, so with at least 4 rows in a chunk no two of those updates touch the same
+// row.
+const MIN_CHUNK_ROWS = 4
+
+// Splits the rows from minY to maxY (1-indexed, as in worldGrid) into about POOL_SIZE*CHUNKS_PER_WORKER chunks of at
+// least MIN_CHUNK_ROWS rows each. Returns the first row of every chunk, followed by maxY+1.
+func (s *Simulation) chunkStarts(minY, maxY int) []int {
+	numRows := maxY - minY + 1
+	numChunks := intMin(POOL_SIZE*CHUNKS_PER_WORKER, numRows/MIN_CHUNK_ROWS)
+	rowsPerChunk := numRows / numChunks
+	starts := make([]int, numChunks+1)
+	for i := range starts {
+		starts[i] = minY + i*rowsPerChunk
+	}
+	starts[numChunks] = maxY + 1 // The last chunk takes the leftover rows.
+	return starts
+}
+
+// Returns the first row of every chunk the rows were split into for the last update, followed by the row after the
+// last chunk, as 0-indexed rows of the board. Empty if nothing was updated, or if the board has a mask, in which case
+// the board isn't split into chunks.
+func (s *Simulation) chunkBoundaries() []int {
+	if s.mask != nil || s.region.isEmpty() {
+		return nil
+	}
+	starts := []int{s.region.minY, s.region.maxY + 1}
+	if s.region.maxY-s.region.minY+1 >= MIN_CHUNK_ROWS {
+		starts = s.chunkStarts(s.region.minY, s.region.maxY)
+	}
+	for i := range starts {
+		starts[i]--
+	}
+	return starts
+}
+
+// Updates the rows in each range (inclusive) in parallel. POOL_SIZE goroutines take the next range whenever they
+// finish one, so a few ranges with a lot going on don't leave the other goroutines idle. The ranges must be far enough
+// apart that updating them doesn't change the same cells.
+func (s *Simulation) updateRanges(ranges [][2]int) {
+	queue := make(chan [2]int, len(ranges))
+	for _, r := range ranges {
+		queue <- r
+	}
+	close(queue)
+
+	for w := 0; w < intMin(POOL_SIZE, len(ranges)); w++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for r := range queue {
+				s.updateRange(r[0], r[1])
+			}
+		}()
+	}
+	s.wg.Wait()
+}
+
+// Randomly fills the board, which must be empty, in parallel. The rows are split into blocks of RANDOMIZE_BLOCK_ROWS,
+// each filled from its own generator seeded from the seed and the block's index, so the board doesn't depend on how
+// many goroutines there are or which one gets which block.
+func (s *Simulation) randomizeBlocks(liveCellPercentage float64, seed int64) {
+	numBlocks := (s.gridY + RANDOMIZE_BLOCK_ROWS - 1) / RANDOMIZE_BLOCK_ROWS
+	blocks := make(chan int, numBlocks)
+	for i := 0; i < numBlocks; i++ {
+		blocks <- i
+	}
+	close(blocks)
+
+	var wg sync.WaitGroup
+	for w := 0; w < POOL_SIZE; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for block := range blocks {
+				rng := newRNG(blockSeed(seed, block))
+				minY := 1 + block*RANDOMIZE_BLOCK_ROWS
+				maxY := intMin(s.gridY, minY+RANDOMIZE_BLOCK_ROWS-1)
+				for i := minY; i <= maxY; i++ {
+					for j := 1; j <= s.gridX; j++ {
+						if int(rng.Int63n(100000)) < int(1000*liveCellPercentage) {
+							s.worldGrid[i*(s.gridX+2)+j] = 1
+							setPixel(s.pixels, s.gridX, j-1, i-1, 0)
+						}
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Only the alive bits are set so far, since counting neighbours while filling would race at the block edges.
+	if s.mask != nil {
+		s.recountMasked()
+	} else {
+		s.countMooreNeighbours()
+	}
+
+	s.noiseSeed = uint64(newRNG(seed).Int63())
+}
+
+// Recomputes every cell's value for the usual 8 cell neighbourhood from the alive bits of the board, in parallel.
+func (s *Simulation) countMooreNeighbours() {
+	w := s.gridX + 2
+	s.forRowRanges(func(minY, maxY int) {
+		for i := minY; i <= maxY; i++ {
+			for j := 1; j <= s.gridX; j++ {
+				ind := i*w + j
+				val := s.worldGrid[ind] & 1
+				for _, n := range [8]int{ind - w - 1, ind - w, ind - w + 1, ind - 1, ind + 1, ind + w - 1, ind + w, ind + w + 1} {
+					val += 2 * (s.worldGrid[n] & 1)
+				}
+				s.buffer[ind] = val
+			}
+		}
+	})
+	copy(s.worldGrid, s.buffer)
+	s.fixEdgeCounts()
+}
+
+// Returns whether the cell at (x, y) is alive. The coordinates are 0-indexed, and cells outside the board are dead.
+func (s *Simulation) Get(x, y int) bool {
+	if !s.isOnBoard(x, y) {
+		return false
+	}
+	return s.worldGrid[(y+1)*(s.gridX+2)+x+1]&1 == 1
+}
+
+// Sets the cell at (x, y) to alive or dead, keeping the neighbour counts and the pixels up to date. The coordinates
+// are 0-indexed, and cells outside the board are ignored.
+func (s *Simulation) Set(x, y int, alive bool) {
+	if s.isOnBoard(x, y) {
+		s.setCell(x, y, alive)
+	}
+}
+
+// Makes the cell at (x, y) immortal, bringing it to life if it's dead, or makes it mortal again, leaving it alive. The
+// coordinates are 0-indexed, and cells outside the board are ignored.
+func (s *Simulation) SetImmortal(x, y int, immortal bool) {
+	if !s.isOnBoard(x, y) {
+		return
+	}
+	if immortal {
+		s.setCell(x, y, true)
+		s.hasImmortals = true
+	}
+	s.immortal[(y+1)*(s.gridX+2)+x+1] = immortal
+}
+
+// Returns whether the 0-indexed coordinates (x, y) are on the board.
+func (s *Simulation) isOnBoard(x, y int) bool {
+	return x >= 0 && x < s.gridX && y >= 0 && y < s.gridY
+}
+
+// Sets the cell at (x, y) to alive or dead, updating the neighbour counts of the surrounding cells and the cell's
+// pixel. The coordinates are 0-indexed, i.e. they don't include the board edge border.
+func (s *Simulation) setCell(x, y int, alive bool) {
+	ind := (y+1)*(s.gridX+2) + x + 1
+	if (s.worldGrid[ind]&1 == 1) == alive {
+		return
+	}
+
+	delta, colorIndex := int8(2), 0
+	if !alive {
+		delta, colorIndex = -2, 1
+		s.immortal[ind] = false
+	}
+	for a := -1; a <= 1; a++ {
+		for b := -1; b <= 1; b++ {
+			if (a != 0) || (b != 0) {
+				s.worldGrid[ind+a*(s.gridX+2)+b] += delta
+			}
+		}
+	}
+	s.worldGrid[ind] += delta / 2
+	s.age[ind] = 0
+	s.dying[ind] = 0
+	if alive && s.isLiveBoundsKnown {
+		s.liveBounds = s.liveBounds.including(x+1, y+1)
+	}
+	setPixel(s.pixels, s.gridX, x, y, colorIndex)
+
+	// Cells on the edge also affect the cells they're seen from across the boundary.
+	if x == 0 || y == 0 || x == s.gridX-1 || y == s.gridY-1 {
+		s.fixEdgeCounts()
+	}
+}
+
+// Changes the size of the board, keeping the live cells of the old board centered on the new one. Cells which don't
+// fit on the new board are lost.
+func (s *Simulation) resize(gridX, gridY int) {
+	oldGrid, oldImmortal, oldX, oldY := s.worldGrid, s.immortal, s.gridX, s.gridY
+	hasImmortals := s.hasImmortals
+	s.allocate(gridX, gridY)
+	s.hasImmortals = hasImmortals
+
+	offsetX, offsetY := (gridX-oldX)/2, (gridY-oldY)/2
+	for i := 0; i < oldY; i++ {
+		for j := 0; j < oldX; j++ {
+			x, y := j+offsetX, i+offsetY
+			if oldGrid[(i+1)*(oldX+2)+j+1]&1 == 1 && x >= 0 && x < gridX && y >= 0 && y < gridY {
+				s.setCell(x, y, true)
+				s.immortal[(y+1)*(gridX+2)+x+1] = oldImmortal[(i+1)*(oldX+2)+j+1]
+			}
+		}
+	}
+}
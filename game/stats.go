@@ -0,0 +1,82 @@
+package game
+
+import (
+	"encoding/csv"
+	"log"
+	"os"
+	"strconv"
+)
+
+// How many rows a CSV writer buffers before flushing them to the file, so that a crash loses at most this many.
+const STATS_FLUSH_ROWS = 100
+
+// Writes rows to a CSV file in the output directory, such as the population, births and deaths of every generation for
+// analysing a rule's population dynamics outside the game.
+type CSVWriter struct {
+	f *os.File
+	w *csv.Writer
+
+	// Rows written since the last flush.
+	unflushed int
+}
+
+// Creates the CSV file, in the output directory unless path is absolute, and writes the header row.
+func newCSVWriter(path string, header ...string) *CSVWriter {
+	f := createOutputFile(path)
+	cw := &CSVWriter{f: f, w: csv.NewWriter(f)}
+	cw.write(header...)
+	return cw
+}
+
+// Writes one row.
+func (cw *CSVWriter) writeRow(fields ...string) {
+	cw.write(fields...)
+
+	cw.unflushed++
+	if cw.unflushed >= STATS_FLUSH_ROWS {
+		cw.flush()
+	}
+}
+
+func (cw *CSVWriter) write(fields ...string) {
+	if err := cw.w.Write(fields); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (cw *CSVWriter) flush() {
+	cw.w.Flush()
+	if err := cw.w.Error(); err != nil {
+		log.Fatal(err)
+	}
+	cw.unflushed = 0
+}
+
+// Flushes the remaining rows and closes the file.
+func (cw *CSVWriter) close() {
+	cw.flush()
+	if err := cw.f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Creates the writer for STATS_FILE. A new board starts again from generation 0.
+func newStatsWriter(path string) *CSVWriter {
+	return newCSVWriter(path, "generation", "population", "births", "deaths")
+}
+
+// Writes the stats row for the generation the board is at. The population is tracked from the births and deaths
+// rather than counted, except at the start of a board.
+func (g *Game) recordStats() {
+	if g.generation == 0 {
+		g.population, _ = g.boardSummary()
+		g.writeStatsRow(0, g.population, 0, 0)
+		return
+	}
+	g.population += g.births - g.deaths
+	g.writeStatsRow(g.generation, g.population, g.births, g.deaths)
+}
+
+func (g *Game) writeStatsRow(generation, population, births, deaths int) {
+	g.stats.writeRow(strconv.Itoa(generation), strconv.Itoa(population), strconv.Itoa(births), strconv.Itoa(deaths))
+}
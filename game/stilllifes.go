@@ -0,0 +1,208 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The largest bounding box, in cells, FindStillLifes can search. Each box of n cells takes 2^n steps to search.
+const MAX_STILL_LIFE_CELLS = 28
+
+// The number of cells whose states split up the arrangements of a box between the goroutines.
+const STILL_LIFE_CHUNK_BITS = 8
+
+// Finds the still lifes of the given rules, meaning the patterns which are unchanged after a step, whose bounding box
+// has at most maxCells cells. Every arrangement of live cells in every box up to that size is run for one step on a
+// headless board with a dead border around it, spread across POOL_SIZE goroutines. Each still life is returned once,
+// in whichever of its rotations and reflections comes first, ordered by number of live cells. Patterns made of
+// several still lifes side by side count too. Rules with B0 have no still lifes, since the empty cells around any
+// pattern are born.
+func FindStillLifes(bRules, sRules Ruleset, maxCells int) []Pattern {
+	if bRules[0] {
+		return nil
+	}
+
+	var mu sync.Mutex
+	found := map[string]Pattern{}
+	for w := 1; w <= maxCells; w++ {
+		// Boxes taller than they are wide are searched as their transposes, which have the same still lifes turned.
+		for h := w; w*h <= maxCells; h++ {
+			// Split up by the first few cells of the box, so that even thin boxes are spread across the goroutines.
+			chunkBits := intMin(w*h, STILL_LIFE_CHUNK_BITS)
+			forEachInParallel(1<<chunkBits, func(firstCells int) {
+				for _, p := range findStillLifesInBox(bRules, sRules, w, h, firstCells, chunkBits) {
+					p, key := canonical(p)
+					mu.Lock()
+					found[key] = p
+					mu.Unlock()
+				}
+			})
+		}
+	}
+
+	keys := make([]string, 0, len(found))
+	for key := range found {
+		keys = append(keys, key)
+	}
+	// Sort by number of live cells, then by size, then by the cells themselves so the order is deterministic.
+	sort.Slice(keys, func(i, j int) bool {
+		ci, cj := strings.Count(keys[i], "O"), strings.Count(keys[j], "O")
+		if ci != cj {
+			return ci < cj
+		}
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	res := make([]Pattern, len(keys))
+	for i, key := range keys {
+		res[i] = found[key]
+	}
+	return res
+}
+
+// Returns the still lifes which exactly fill a w by h box and whose first chunkBits cells are given by the bits of
+// firstCells. Cells are numbered row by row from the top left, the lowest bit being the first cell.
+func findStillLifesInBox(bRules, sRules Ruleset, w, h, firstCells, chunkBits int) []Pattern {
+	s := NewSimulation(w+2, h+2, bRules, sRules)
+	firstRow := 1<<w - 1
+	lastRow := firstRow << (w * (h - 1))
+	firstColumn, lastColumn := 0, 0
+	for y := 0; y < h; y++ {
+		firstColumn |= 1 << (w * y)
+		lastColumn |= 1 << (w*y + w - 1)
+	}
+
+	var res []Pattern
+	for rest := 0; rest < 1<<(w*h-chunkBits); rest++ {
+		cells := firstCells | rest<<chunkBits
+		// Patterns which don't touch all four sides of the box are found in a smaller box.
+		if cells&firstRow == 0 || cells&lastRow == 0 || cells&firstColumn == 0 || cells&lastColumn == 0 {
+			continue
+		}
+
+		for i := 0; i < w*h; i++ {
+			s.setCell(1+i%w, 1+i/w, cells&(1<<i) != 0)
+		}
+		s.Step()
+
+		isStill := true
+		for y := 0; y < h+2 && isStill; y++ {
+			for x := 0; x < w+2; x++ {
+				isInBox := x >= 1 && x <= w && y >= 1 && y <= h
+				if s.Get(x, y) != (isInBox && cells&(1<<((y-1)*w+x-1)) != 0) {
+					isStill = false
+					break
+				}
+			}
+		}
+		if isStill {
+			res = append(res, patternFromBits(cells, w, h))
+		}
+
+		// Clear the cells outside the box which may have been born, ready for the next arrangement.
+		for y := 0; y < h+2; y++ {
+			for x := 0; x < w+2; x++ {
+				if x == 0 || x == w+1 || y == 0 || y == h+1 {
+					s.setCell(x, y, false)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// Returns the w by h pattern whose cells are given by the bits of cells, row by row from the lowest bit.
+func patternFromBits(cells, w, h int) Pattern {
+	p := make(Pattern, h)
+	for y := range p {
+		p[y] = make([]bool, w)
+		for x := range p[y] {
+			p[y][x] = cells&(1<<(y*w+x)) != 0
+		}
+	}
+	return p
+}
+
+// Returns whichever of the pattern's 8 rotations and reflections has the smallest plaintext form, along with that
+// form, so that patterns which are rotations or reflections of each other give the same result.
+func canonical(p Pattern) (Pattern, string) {
+	var best Pattern
+	bestKey := ""
+	for i := 0; i < 8; i++ {
+		// Every rotation and reflection is reached by alternately transposing and reflecting.
+		if i%2 == 0 {
+			p = p.transposed()
+		} else {
+			p = p.mirrored()
+		}
+		if key := p.String(); best == nil || key < bestKey {
+			best, bestKey = p, key
+		}
+	}
+	return best, bestKey
+}
+
+// Returns the pattern reflected in its main diagonal.
+func (p Pattern) transposed() Pattern {
+	w, h := p.size()
+	res := make(Pattern, w)
+	for x := range res {
+		res[x] = make([]bool, h)
+		for y := range res[x] {
+			res[x][y] = p[y][x]
+		}
+	}
+	return res
+}
+
+// Returns the pattern reflected left to right.
+func (p Pattern) mirrored() Pattern {
+	w, _ := p.size()
+	res := make(Pattern, len(p))
+	for y, row := range p {
+		res[y] = make([]bool, w)
+		for x, alive := range row {
+			res[y][w-1-x] = alive
+		}
+	}
+	return res
+}
+
+// Returns the pattern in plaintext format (see ParsePattern).
+func (p Pattern) String() string {
+	var sb strings.Builder
+	for _, row := range p {
+		for _, alive := range row {
+			if alive {
+				sb.WriteByte('O')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Finds the still lifes of the current rules in boxes of at most maxCells cells and returns them in RLE format, each
+// preceded by a comment line numbering it. See FindStillLifes. The search only knows the usual neighbourhood and two
+// states, so it returns an error rather than wrong results for a custom neighbourhood, a Larger than Life rule or a
+// Generations rule.
+func (g *Game) FindStillLifes(maxCells int) (string, error) {
+	if g.mask != nil || g.ltl != nil || g.states > 2 {
+		return "", errors.New("still lifes can only be found with the usual neighbourhood and two states, not with " +
+			"-mask, -range or a Generations rule")
+	}
+
+	rule := FormatRulestring(g.bRules, g.sRules)
+	var sb strings.Builder
+	for i, p := range FindStillLifes(g.bRules, g.sRules, maxCells) {
+		fmt.Fprintf(&sb, "#C still life %v of %v\n%v", i+1, rule, p.RLE(rule))
+	}
+	return sb.String(), nil
+}
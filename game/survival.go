@@ -0,0 +1,44 @@
+package game
+
+import "log"
+
+const (
+	// The most times a new board is rerolled for MIN_SURVIVAL, so that rules under which nothing survives still start.
+	MAX_SURVIVAL_REROLLS = 100
+
+	// The fraction of the cells which have to be alive after MIN_SURVIVAL generations for a board to count as
+	// surviving. Boards with only a few scattered leftovers are about as dull as dead ones.
+	MIN_SURVIVAL_FRACTION = 0.001
+)
+
+// Returns whether the board still has a real population after the given number of generations. The generations are
+// run on a scratch copy, so the board itself is left untouched.
+func (s *Simulation) survives(generations int) bool {
+	c := s.blankCopy()
+	copy(c.worldGrid, s.worldGrid)
+	copy(c.age, s.age)
+	copy(c.immortal, s.immortal)
+	c.hasImmortals = s.hasImmortals
+	copy(c.dying, s.dying)
+	c.noiseSeed = s.noiseSeed
+	for i := 0; i < generations; i++ {
+		c.Step()
+	}
+
+	population, _ := c.boardSummary()
+	return population > 0 && float64(population) >= MIN_SURVIVAL_FRACTION*float64(s.gridX*s.gridY)
+}
+
+// Fills the board randomly from a new seed until it survives MIN_SURVIVAL generations, giving up after
+// MAX_SURVIVAL_REROLLS tries. The board must already have been filled once from boardSeed.
+func (g *Game) rerollUntilSurviving() {
+	for i := 0; !g.survives(MIN_SURVIVAL); i++ {
+		if i == MAX_SURVIVAL_REROLLS {
+			log.Printf("no board survived %v generations in %v tries, keeping the last one", MIN_SURVIVAL, i)
+			return
+		}
+		g.boardSeed = r.Int63()
+		g.allocate(g.gridX, g.gridY)
+		g.Randomize(g.avgStartingLiveCellPercentage, g.boardSeed)
+	}
+}
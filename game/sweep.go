@@ -0,0 +1,84 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// The height in pixels of the strip under each board of a seed sweep which holds its seed.
+	SWEEP_LABEL_HEIGHT = 16
+
+	// The gap in pixels between the boards of a seed sweep.
+	SWEEP_GAP = 4
+)
+
+// Runs a headless simulation from each of the numSeeds seeds starting at firstSeed for the given number of
+// generations, and tiles the final boards into a roughly square grid, each labelled with its seed. The simulations
+// have the same board size and rules as template, whose board is left untouched, and are run in parallel across
+// POOL_SIZE goroutines. Each cell is one pixel.
+func SweepSeeds(template *Simulation, liveCellPercentage float64, firstSeed int64, numSeeds,
+	generations int) *image.RGBA {
+	columns := int(math.Ceil(math.Sqrt(float64(numSeeds))))
+	rows := (numSeeds + columns - 1) / columns
+	tileX, tileY := template.gridX+SWEEP_GAP, template.gridY+SWEEP_LABEL_HEIGHT+SWEEP_GAP
+
+	res := image.NewRGBA(image.Rect(0, 0, columns*tileX-SWEEP_GAP, rows*tileY-SWEEP_GAP))
+	draw.Draw(res, res.Bounds(), image.NewUniform(BORDER_COLOR), image.Point{}, draw.Src)
+
+	forEachInParallel(numSeeds, func(ind int) {
+		s := template.blankCopy()
+		seed := firstSeed + int64(ind)
+		s.Randomize(liveCellPercentage, seed)
+		for gen := 0; gen < generations; gen++ {
+			s.Step()
+		}
+
+		// Each goroutine draws to its own part of the image. The label is clipped to the tile, as it can be wider than
+		// a narrow board.
+		x, y := (ind%columns)*tileX, (ind/columns)*tileY
+		s.drawBoard(res, x, y)
+		tile := res.SubImage(image.Rect(x, y, x+s.gridX, y+s.gridY+SWEEP_LABEL_HEIGHT)).(*image.RGBA)
+		d := font.Drawer{Dst: tile, Src: image.White, Face: basicfont.Face7x13}
+		d.Dot = fixed.P(x+2, y+s.gridY+SWEEP_LABEL_HEIGHT-4)
+		d.DrawString(fmt.Sprintf("seed %v", seed))
+	})
+	return res
+}
+
+// Draws the board with its top left corner at (x, y) in img, one pixel per cell, in the live colour on black.
+func (s *Simulation) drawBoard(img *image.RGBA, x, y int) {
+	alive := color.RGBA{colors[0][0], colors[0][1], colors[0][2], 255}
+	for i := 0; i < s.gridY; i++ {
+		for j := 0; j < s.gridX; j++ {
+			c := color.RGBA{0, 0, 0, 255}
+			if s.worldGrid[(i+1)*(s.gridX+2)+j+1]&1 == 1 {
+				c = alive
+			}
+			img.SetRGBA(x+j, y+i, c)
+		}
+	}
+}
+
+// Runs a seed sweep with the game's current board size, rules and initial live cell percentage, starting at SEED,
+// and saves it as a PNG in the output directory. Returns the path it was saved to. See SweepSeeds.
+func (g *Game) SweepSeeds(numSeeds, generations int) string {
+	img := SweepSeeds(&g.Simulation, g.avgStartingLiveCellPercentage, SEED, numSeeds, generations)
+
+	fileName := recordingFileName(g.bRules, g.sRules, "png")
+	f := createOutputFile(fileName)
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		log.Fatal(err)
+	}
+	return outputPath(fileName)
+}
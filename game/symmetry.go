@@ -0,0 +1,80 @@
+package game
+
+import "fmt"
+
+// A symmetry for the initial random fill. Symmetric starts evolve symmetrically (for a while, at least), which makes
+// for striking patterns.
+type Symmetry int
+
+const (
+	// Every cell is random.
+	SYMMETRY_NONE Symmetry = iota
+
+	// The right half mirrors the left half.
+	SYMMETRY_LEFT_RIGHT
+
+	// The bottom half mirrors the top half.
+	SYMMETRY_TOP_BOTTOM
+
+	// Each quadrant mirrors its neighbours, i.e. both of the above.
+	SYMMETRY_QUADRANTS
+
+	// Like SYMMETRY_QUADRANTS, but also mirrored across the diagonals, as far as the board isn't too wide or tall for
+	// that.
+	SYMMETRY_EIGHT_FOLD
+
+	// The number of symmetries, for cycling through them.
+	NUM_SYMMETRIES
+)
+
+func (sym Symmetry) String() string {
+	switch sym {
+	case SYMMETRY_LEFT_RIGHT:
+		return "left-right"
+	case SYMMETRY_TOP_BOTTOM:
+		return "top-bottom"
+	case SYMMETRY_QUADRANTS:
+		return "quadrants"
+	case SYMMETRY_EIGHT_FOLD:
+		return "eight-fold"
+	default:
+		return "none"
+	}
+}
+
+// Parses a symmetry from its name, as returned by String.
+func ParseSymmetry(s string) (Symmetry, error) {
+	for sym := Symmetry(0); sym < NUM_SYMMETRIES; sym++ {
+		if sym.String() == s {
+			return sym, nil
+		}
+	}
+	return SYMMETRY_NONE, fmt.Errorf("unknown symmetry %q, should be none, left-right, top-bottom, quadrants or eight-fold",
+		s)
+}
+
+// Returns a key which is the same for all cells which are mirror images of each other under the symmetry, so that the
+// random fill can give them all the same state. The coordinates are 0-indexed.
+func (s *Simulation) symmetryKey(x, y int) [2]int {
+	// Coordinates relative to the center of the board, doubled so that they're whole numbers for boards of even size.
+	u, v := 2*x-(s.gridX-1), 2*y-(s.gridY-1)
+
+	switch s.symmetry {
+	case SYMMETRY_LEFT_RIGHT:
+		return [2]int{abs(u), v}
+	case SYMMETRY_TOP_BOTTOM:
+		return [2]int{u, abs(v)}
+	case SYMMETRY_QUADRANTS:
+		return [2]int{abs(u), abs(v)}
+	case SYMMETRY_EIGHT_FOLD:
+		a, b := abs(u), abs(v)
+		// The diagonal mirror image of a cell only exists if it fits on the board, and u and v need the same parity
+		// for it to land on a cell.
+		if b <= s.gridX-1 && a <= s.gridY-1 && (s.gridX-s.gridY)%2 == 0 {
+			return [2]int{intMin(a, b), intMax(a, b)}
+		}
+		return [2]int{a, b}
+	default:
+		return [2]int{u, v}
+	}
+}
@@ -0,0 +1,56 @@
+package game
+
+// The most a trail can hold, which is what a cell's trail is set to while it's alive. A dead cell's trail is drawn
+// with an opacity of trail/MAX_TRAIL.
+const MAX_TRAIL = 255
+
+// Fills trailPixels with the board, with each dead cell showing a fading trail of TRAIL_COLOR where a cell recently
+// died. The trails are only kept here, so they don't change the board or slow down updating it. They're set in full
+// while a cell is alive and shrink by TRAIL_DECAY on every frame drawn while the game is running, so they stay put
+// while paused.
+func (g *Game) updateTrailPixels() {
+	if len(g.trailPixels) != len(g.pixels) {
+		g.trailPixels = make([]byte, len(g.pixels))
+		g.trail = make([]uint8, g.gridX*g.gridY)
+	}
+	copy(g.trailPixels, g.pixels)
+
+	trailColor := [4]int{int(TRAIL_COLOR.R), int(TRAIL_COLOR.G), int(TRAIL_COLOR.B), 255}
+	for y := 0; y < g.gridY; y++ {
+		for x := 0; x < g.gridX; x++ {
+			i := y*g.gridX + x
+			if g.worldGrid[(y+1)*(g.gridX+2)+x+1]&1 == 1 {
+				g.trail[i] = MAX_TRAIL
+				continue
+			}
+			if !g.isPaused {
+				g.trail[i] = uint8(intMax(0, int(g.trail[i])-TRAIL_DECAY))
+			}
+			if g.trail[i] == 0 {
+				continue
+			}
+
+			// The trail colour drawn over the dead cell's pixel, which is premultiplied by its alpha, with the trail
+			// as the opacity.
+			t := int(g.trail[i])
+			for c := 0; c < 4; c++ {
+				p := &g.trailPixels[4*i+c]
+				*p = byte(int(*p) + (trailColor[c]-int(*p))*t/MAX_TRAIL)
+			}
+		}
+	}
+}
+
+// Sets whether dead cells show fading trails, starting without any.
+func (g *Game) setTrailVisible(visible bool) {
+	g.isTrailVisible = visible
+	g.trail, g.trailPixels = nil, nil
+}
+
+// Returns the pixels of the board as last drawn, with the trails if they're shown but without any other display modes.
+func (g *Game) boardPixels() []byte {
+	if g.isTrailVisible && len(g.trailPixels) == len(g.pixels) {
+		return g.trailPixels
+	}
+	return g.pixels
+}
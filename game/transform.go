@@ -0,0 +1,184 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log"
+)
+
+// Which ways to flip the board.
+type Flip int
+
+const (
+	FLIP_NONE Flip = iota
+
+	// Mirror the board left to right.
+	FLIP_HORIZONTAL
+
+	// Mirror the board top to bottom.
+	FLIP_VERTICAL
+
+	// Both of the above, i.e. a half turn.
+	FLIP_BOTH
+)
+
+func (f Flip) String() string {
+	switch f {
+	case FLIP_HORIZONTAL:
+		return "horizontal"
+	case FLIP_VERTICAL:
+		return "vertical"
+	case FLIP_BOTH:
+		return "both"
+	default:
+		return "none"
+	}
+}
+
+// Parses a flip from its name, as returned by String.
+func ParseFlip(s string) (Flip, error) {
+	for f := FLIP_NONE; f <= FLIP_BOTH; f++ {
+		if f.String() == s {
+			return f, nil
+		}
+	}
+	return FLIP_NONE, fmt.Errorf("unknown flip %q, should be none, horizontal, vertical or both", s)
+}
+
+// Mirrors the board in place as given by f. A mirror image of a cell's neighbourhood has as many live cells as the
+// neighbourhood itself, so the whole of worldGrid, border included, can just be mirrored along with the ages,
+// immortality, dying states and pixels, without recounting. Only a custom neighbourhood which isn't itself symmetric
+// needs recounting.
+func (s *Simulation) Flip(f Flip) {
+	if f == FLIP_HORIZONTAL || f == FLIP_BOTH {
+		flipColumns(s.worldGrid, s.gridX+2, 1)
+		flipColumns(s.age, s.gridX+2, 1)
+		flipColumns(s.immortal, s.gridX+2, 1)
+		flipColumns(s.dying, s.gridX+2, 1)
+		flipColumns(s.pixels, s.gridX, 4)
+	}
+	if f == FLIP_VERTICAL || f == FLIP_BOTH {
+		flipRows(s.worldGrid, s.gridX+2)
+		flipRows(s.age, s.gridX+2)
+		flipRows(s.immortal, s.gridX+2)
+		flipRows(s.dying, s.gridX+2)
+		flipRows(s.pixels, 4*s.gridX)
+	}
+
+	if s.mask != nil {
+		s.recountMasked()
+	}
+	s.isLiveBoundsKnown = false
+}
+
+// Turns the square of cells rect (0-indexed) a quarter turn clockwise, or anticlockwise, along with the cells' ages,
+// immortality and dying states.
+// The cells around the square didn't turn with it, so the neighbour counts are recounted for the square and the ring
+// of cells around it.
+func (s *Simulation) RotateSquare(rect image.Rectangle, clockwise bool) error {
+	if rect.Dx() != rect.Dy() {
+		return fmt.Errorf("can't rotate a %vx%v area, only square ones", rect.Dx(), rect.Dy())
+	}
+	if !rect.In(image.Rect(0, 0, s.gridX, s.gridY)) {
+		return errors.New("can't rotate an area which isn't on the board")
+	}
+
+	n := rect.Dx()
+	index := func(x, y int) int { return (rect.Min.Y+y+1)*(s.gridX+2) + rect.Min.X + x + 1 }
+	alive, ages, immortal := make([]int8, n*n), make([]uint8, n*n), make([]bool, n*n)
+	dying := make([]uint8, n*n)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			ind := index(x, y)
+			alive[y*n+x], ages[y*n+x], immortal[y*n+x] = s.worldGrid[ind]&1, s.age[ind], s.immortal[ind]
+			dying[y*n+x] = s.dying[ind]
+		}
+	}
+
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			// The cell which ends up at (x, y).
+			src := (n-1-x)*n + y
+			if !clockwise {
+				src = x*n + n - 1 - y
+			}
+			ind := index(x, y)
+			s.worldGrid[ind] = s.worldGrid[ind]&^1 | alive[src]
+			s.age[ind] = ages[src]
+			s.immortal[ind] = immortal[src]
+			s.dying[ind] = dying[src]
+			if alive[src] == 1 {
+				setPixel(s.pixels, s.gridX, rect.Min.X+x, rect.Min.Y+y, s.liveColor(ind))
+			} else {
+				s.setDyingPixel(rect.Min.X+x, rect.Min.Y+y, dying[src])
+			}
+		}
+	}
+
+	if s.mask != nil {
+		s.recountMasked()
+	} else {
+		for y := rect.Min.Y - 1; y <= rect.Max.Y; y++ {
+			for x := rect.Min.X - 1; x <= rect.Max.X; x++ {
+				if s.isOnBoard(x, y) {
+					s.recountNeighbours(x, y)
+				}
+			}
+		}
+		// A wrapping board's edges see each other.
+		s.fixEdgeCounts()
+	}
+	s.isLiveBoundsKnown = false
+	return nil
+}
+
+// Reverses the order of the cells within each row of a grid stored row by row, where each row has width cells of
+// size elements each.
+func flipColumns[T any](grid []T, width, size int) {
+	rowLen := width * size
+	for row := 0; row < len(grid); row += rowLen {
+		for a, b := 0, width-1; a < b; a, b = a+1, b-1 {
+			for i := 0; i < size; i++ {
+				grid[row+a*size+i], grid[row+b*size+i] = grid[row+b*size+i], grid[row+a*size+i]
+			}
+		}
+	}
+}
+
+// Reverses the order of the rows of a grid stored row by row, with rowLen elements per row.
+func flipRows[T any](grid []T, rowLen int) {
+	for a, b := 0, len(grid)/rowLen-1; a < b; a, b = a+1, b-1 {
+		for i := 0; i < rowLen; i++ {
+			grid[a*rowLen+i], grid[b*rowLen+i] = grid[b*rowLen+i], grid[a*rowLen+i]
+		}
+	}
+}
+
+// Mirrors the board in place as given by f, along with the layer if there is one.
+func (g *Game) flipBoard(f Flip) {
+	g.Flip(f)
+	if g.layer != nil {
+		g.layer.Flip(f)
+	}
+}
+
+// Turns the square being selected a quarter turn, or the whole board if nothing is being selected, along with the
+// layer if there is one. Only square areas can be turned, so a board which isn't square can only be turned a square
+// selection at a time.
+func (g *Game) rotate(clockwise bool) {
+	rect := image.Rect(0, 0, g.gridX, g.gridY)
+	if g.selectionStart != nil {
+		rect = g.selection()
+		// The selection is used up, rather than filled when the mouse button is released.
+		g.selectionStart = nil
+	}
+
+	if err := g.RotateSquare(rect, clockwise); err != nil {
+		log.Print(err)
+		return
+	}
+	if g.layer != nil {
+		g.layer.RotateSquare(rect, clockwise)
+	}
+}
@@ -0,0 +1,83 @@
+package game
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// The number of generations per second the terminal renderer runs at when GENERATIONS_PER_SECOND isn't set.
+const TUI_DEFAULT_SPEED = 10
+
+// The characters for the four ways the two cells shown by one character can be lit, indexed by 2*top + bottom.
+var halfBlocks = [4]string{" ", "▄", "▀", "█"}
+
+// Runs the game without a window, redrawing the board as text to w after every generation, for terminals where no
+// display is available. The board fills columns by rows characters, less a line for the status, unless BOARD_WIDTH
+// fixes its size, in which case it's downscaled to fit. Runs until MAX_RUNTIME has passed, or forever if it's 0.
+func (g *Game) RunTUI(w io.Writer, columns, rows int) {
+	gridX, gridY := columns, 2*(rows-1)
+	if BOARD_WIDTH > 0 {
+		gridX, gridY = BOARD_WIDTH, BOARD_HEIGHT
+	}
+	g.allocate(gridX, gridY)
+	g.fillBoard()
+	g.Flip(FLIP)
+	g.warmUp()
+
+	speed := GENERATIONS_PER_SECOND
+	if speed == 0 {
+		speed = TUI_DEFAULT_SPEED
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / speed))
+	defer ticker.Stop()
+
+	out := bufio.NewWriter(w)
+	fmt.Fprint(out, "\x1b[2J") // Clear the screen once, after which each frame draws over the last one.
+	for MAX_RUNTIME == 0 || time.Since(g.startTime) < MAX_RUNTIME {
+		population, _ := g.boardSummary()
+		fmt.Fprint(out, "\x1b[H", g.renderText(columns, rows-1))
+		fmt.Fprintf(out, "\ngeneration %v, population %v, %v, seed %v\x1b[K", g.generation, population,
+			FormatRulestring(g.bRules, g.sRules), g.boardSeed)
+		out.Flush()
+
+		<-ticker.C
+		g.Step()
+	}
+	fmt.Fprintln(out)
+	out.Flush()
+}
+
+// Draws the board as at most rows lines of at most columns characters each, using half-block characters so that
+// each character shows two cells, one above the other. Boards too big to fit are downscaled, with each half of a
+// character standing for a square block of cells and lit if any of them are alive.
+func (s *Simulation) renderText(columns, rows int) string {
+	scale := 1
+	for (s.gridX+scale-1)/scale > columns || (s.gridY+scale-1)/scale > 2*rows {
+		scale++
+	}
+	width, height := (s.gridX+scale-1)/scale, (s.gridY+scale-1)/scale
+
+	// The extra row is the dead bottom half of the last line when the height is odd.
+	lit := make([]int, width*(height+1))
+	for y := 0; y < s.gridY; y++ {
+		for x := 0; x < s.gridX; x++ {
+			if s.worldGrid[(y+1)*(s.gridX+2)+x+1]&1 == 1 {
+				lit[(y/scale)*width+x/scale] = 1
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for y := 0; y < height; y += 2 {
+		if y > 0 {
+			sb.WriteByte('\n')
+		}
+		for x := 0; x < width; x++ {
+			sb.WriteString(halfBlocks[2*lit[y*width+x]+lit[(y+1)*width+x]])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+package game
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// The furthest the view can be zoomed in on the board with the mouse wheel, as a multiple of the usual scale.
+const MAX_VIEW_ZOOM = 16
+
+// Zooms the view in or out on a mouse wheel scroll, keeping the cell under the cursor where it is on screen. Unlike [
+// and ], which change the resolution and so restart the board, this only changes how the board is drawn. Each step
+// doubles or halves the zoom, between the usual scale and MAX_VIEW_ZOOM times it, so cells stay whole pixels wide.
+func (g *Game) handleWheelZoom() {
+	_, dy := ebiten.Wheel()
+	zoom := intMax(1, g.viewZoom)
+	if dy > 0 {
+		zoom = intMin(MAX_VIEW_ZOOM, zoom*2)
+	} else if dy < 0 {
+		zoom = intMax(1, zoom/2)
+	}
+	if zoom != intMax(1, g.viewZoom) {
+		cx, cy := ebiten.CursorPosition()
+		g.zoomView(cx, cy, zoom)
+	}
+}
+
+// Zooms the view to the given multiple of the usual scale, keeping the point of the board at the given screen position
+// in place.
+func (g *Game) zoomView(screenX, screenY, zoom int) {
+	// The point stays in place if its distance from the board's corner scales with the zoom.
+	offsetX, offsetY := g.boardOffset()
+	unzoomedX, unzoomedY := g.unzoomedBoardOffset()
+	ratio := float64(zoom) / float64(intMax(1, g.viewZoom))
+	g.viewX = float64(screenX) - float64(screenX-offsetX)*ratio - float64(unzoomedX)
+	g.viewY = float64(screenY) - float64(screenY-offsetY)*ratio - float64(unzoomedY)
+	g.viewZoom = zoom
+	g.clampView()
+}
+
+// Pans the zoomed view by dragging with the middle mouse button, the board following the cursor. Unzoomed the board
+// already fits the area it's drawn in, centered if it's smaller than the screen, so clampView keeps it where it is.
+func (g *Game) handlePanning() {
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonMiddle) {
+		g.panFrom = nil
+		return
+	}
+
+	cx, cy := ebiten.CursorPosition()
+	if g.panFrom != nil {
+		g.viewX += float64(cx - g.panFrom.X)
+		g.viewY += float64(cy - g.panFrom.Y)
+		g.clampView()
+	}
+	g.panFrom = &image.Point{cx, cy}
+}
+
+// Keeps the view from being moved off the board. The zoomed board has to cover all of the area the board covers
+// unzoomed, so its top left corner can't be further right or down than the unzoomed one, and its bottom right corner
+// can't be further left or up than the unzoomed one. That leaves the corner between 0 and (zoom-1) times the unzoomed
+// board size up and left of where it's drawn unzoomed, and unzoomed, in exactly the same place.
+func (g *Game) clampView() {
+	scale := g.unzoomedDrawScale()
+	extra := float64(intMax(1, g.viewZoom) - 1)
+	g.viewX = clamp(-extra*float64(g.gridX)*scale, 0, g.viewX)
+	g.viewY = clamp(-extra*float64(g.gridY)*scale, 0, g.viewY)
+}
+
+// Goes back to the unzoomed view, for when the board or the screen changes size.
+func (g *Game) resetView() {
+	g.viewZoom = 1
+	g.viewX, g.viewY = 0, 0
+}
+
+// Returns the image the board should be drawn to on target. While zoomed in, that's the part of target the board
+// covers unzoomed, so that the rest of the board is cut off rather than drawn over the border around the simulation
+// area.
+func (g *Game) viewTarget(target *ebiten.Image) *ebiten.Image {
+	if g.viewZoom <= 1 {
+		return target
+	}
+	x, y := g.unzoomedBoardOffset()
+	scale := g.unzoomedDrawScale()
+	rect := image.Rect(x, y, x+int(float64(g.gridX)*scale), y+int(float64(g.gridY)*scale))
+	return target.SubImage(rect).(*ebiten.Image)
+}
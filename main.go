@@ -2,10 +2,12 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"runtime"
 	"runtime/pprof"
+	"strings"
 
 	"github.com/fplonka/go-llca/game"
 	"github.com/hajimehoshi/ebiten/v2"
@@ -13,31 +15,456 @@ import (
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to `file`")
 var memprofile = flag.String("memprofile", "", "write memory profile to `file`")
+var recordFormat = flag.String("record-format", "gif", "save recordings as `format`, either gif or apng")
+var streamGif = flag.Bool("stream-gif", false, "write GIF recordings to disk as they're captured, for long recordings")
+var rewind = flag.Int("rewind", 120, "keep the last `n` generations to step back through with SHIFT+, while paused, or 0 to keep none")
+var maxFrames = flag.Int("max-frames", 0, "stop and save recordings once they have `n` frames, or 0 for no limit")
+var boomerang = flag.Bool("boomerang", false, "make recordings play forwards then backwards, so that they loop smoothly")
+var area = flag.Int("area", 100, "size of the simulation area as a `percentage` of the screen size")
+var borderColor = flag.String("border-color", "282828", "hex `colour` of the frame around the simulation area")
+var search = flag.Int("search", 0, "before starting, run `n` random seeds and start with the most active one")
+var searchGens = flag.Int("search-gens", 500, "number of `generations` to run each seed for when searching")
+var searchEntropy = flag.Bool("search-entropy", false, "when searching, pick the seed whose board ends up most complex, by block entropy, rather than the most active one")
+var sweep = flag.Int("sweep", 0, "run `n` random seeds and save their boards side by side, labelled by seed, as a PNG, then exit")
+var sweepGens = flag.Int("sweep-gens", 500, "number of `generations` to run each seed for in a -sweep")
+var stillLifes = flag.Int("still-lifes", 0, "print the still lifes of the rules which fit in a box of at most `n` cells, in RLE format, then exit")
+var speed = flag.Float64("speed", 0, "run at exactly `n` generations per second (0 for the default speed)")
+var maxGensPerFrame = flag.Int("max-gens-per-frame", 1024, "run at most `n` generations per frame at high speeds, so that the game stays responsive (0 for no limit)")
+var autoSpeed = flag.Bool("auto-speed", false, "start with the speed following the activity, faster when little is changing and slower during bursts (toggled with S)")
+var warmup = flag.Int("warmup", 0, "run every new board for `n` generations before showing it, to skip the start")
+var rule = flag.String("rule", "B3/S23", "start with the birth and survival `rules` in B/S notation (e.g. B36/S23) or S/B notation (e.g. 23/36), optionally with a number of Generations states (e.g. B2/S/3)")
+var lifespan = flag.Int("lifespan", 0, "make cells die of old age after surviving `n` generations (0 for no limit)")
+var birthProbability = flag.Float64("birth-probability", 1, "`chance` (0 to 1) that a birth allowed by the rules happens")
+var survivalProbability = flag.Float64("survival-probability", 1, "`chance` (0 to 1) that a survival allowed by the rules happens")
+var boundary = flag.String("boundary", "dead", "what cells at the edge see beyond it: `mode` dead, wrap or reflect")
+var config = flag.String("config", "", "start the run described by `string`, as printed by pressing E")
+var background = flag.String("background", "", "PNG or JPEG `file` to show through the dead cells, toggled with T")
+var duration = flag.Duration("duration", 0, "exit after running for `time`, e.g. 30s or 5m, finishing any recording first")
+var idleReset = flag.Duration("idle-reset", 0, "start over with a new random board after each board has run for `time`, e.g. 10m, for unattended displays")
+var idleResetRules = flag.Bool("idle-reset-rules", false, "with -idle-reset, also switch to random rules when starting over")
+var ltlRange = flag.String("range", "", "run the Larger than Life `rule` in Golly notation (e.g. R5,C0,M1,S34..58,B34..45,NM), counting neighbours within a range, instead of -rule")
+var mask = flag.String("mask", "", "load a custom neighbourhood from `file`, drawn as a grid of # and . around the middle cell")
+var cues = flag.Bool("cues", false, "flash the screen and beep when the board goes extinct or stabilizes")
+var pauseWhenStable = flag.Bool("pause-when-stable", false, "pause when the board stops changing or repeats with a period of up to 4 generations, except while recording")
+var cuePopulation = flag.Int("cue-population", 0, "with -cues, also cue when the population crosses `n` cells")
+var aliveColor = flag.String("alive-color", "ffffff", "hex `colour` of live cells")
+var bornColor = flag.String("born-color", "4fc3f7", "hex `colour` of just born cells in two-tone mode")
+var ageColors = flag.Bool("age-colors", false, "start with live cells coloured by age, fading from -alive-color to -old-color (toggled with SHIFT+N)")
+var oldColor = flag.String("old-color", "304ffe", "hex `colour` of long-lived cells when colouring by age")
+var trails = flag.Bool("trails", false, "start with dead cells showing fading trails where cells recently died (toggled with SHIFT+T)")
+var trailColor = flag.String("trail-color", "ff7043", "hex `colour` of the trails behind dying cells")
+var trailDecay = flag.Int("trail-decay", 16, "how much the trails fade on each frame, from 1 (slowly) to 255 (at once)")
+var twoTone = flag.Bool("two-tone", false, "start in two-tone mode, drawing just born cells in their own colour (toggled with N)")
+var dumpTables = flag.Bool("dump-tables", false, "print the transition tables for the starting rules and exit")
+var speedRamp = flag.Duration("speed-ramp", 0, "ramp smoothly to a new speed over `time`, e.g. 500ms, instead of jumping")
+var stats = flag.String("stats", "", "write the population, births and deaths of every generation to CSV `file` in the output directory")
+var symmetry = flag.String("symmetry", "none", "`symmetry` of the initial cells: none, left-right, top-bottom, quadrants or eight-fold")
+var flip = flag.String("flip", "none", "mirror every new board once it's filled: `direction` none, horizontal, vertical or both")
+var load = flag.String("load", "", "start from the RLE pattern in `file`, such as one saved with SHIFT+S, placed in the middle of an empty board and run under the rule in its header")
+var initPattern = flag.String("init", "", "start from the pattern in `file`, or - to read it from stdin, in plaintext (O and .) or RLE format, placed in the middle of an empty board")
+var resumeGif = flag.String("resume-gif", "", "carry on from the last frame of the GIF in `file`, e.g. a recording, placed in the middle of the board, with the rules in its name")
+var resumeRule = flag.String("resume-rule", "", "run a -resume-gif GIF under `rule` (e.g. B3/S23) instead of the rules in its name")
+var tile = flag.String("tile", "", "fill the board with copies of the pattern in `file`, in plaintext (O and .) or RLE format, instead of random cells")
+var tileSpacing = flag.Int("tile-spacing", 4, "number of dead `cells` between the copies of the -tile pattern")
+var board = flag.String("board", "", "fix the board size to `WxH` cells, e.g. 640x360, instead of fitting it to the screen, whatever the zoom")
+var crt = flag.Bool("crt", false, "draw the board like an old CRT monitor, with scanlines and a glow (toggled with SHIFT+V)")
+var crtIntensity = flag.Float64("crt-intensity", 0.5, "`strength` (0 to 1) of the -crt effect")
+var pixelPerfect = flag.Bool("pixel-perfect", false, "draw a -board board at whole pixel sizes only, cutting off its edges if it doesn't fit (toggled with P)")
+var brushRadius = flag.Int("brush-radius", 8, "radius in `cells` of the airbrush for painting random cells while paused")
+var brushDensity = flag.Float64("brush-density", 20, "`percentage` of the cells under the airbrush it brings to life")
+var layer = flag.String("layer", "", "also run the starting cells under the `rule` (e.g. B36/S23) and draw both boards blended")
+var layerColors = flag.String("layer-colors", "ff3030,3060ff", "hex `colours` of the main board and the -layer board, separated by a comma")
+var gifMaxDim = flag.Int("gif-max-dim", 0, "downscale GIF recordings so that neither side is longer than `pixels` (0 for no limit)")
+var minSurvival = flag.Int("min-survival", 0, "reroll random boards which die out within `n` generations (0 to keep every board)")
+var rng = flag.String("rng", "math", "random number `generator` for filling boards: math (math/rand) or stable (the same boards with any Go version)")
+var gifDither = flag.Bool("gif-dither", false, "dither GIF recordings with more than two colours, e.g. with a background, for smoother gradients at the cost of speed")
+var dedupeFrames = flag.Bool("dedupe-frames", false, "skip GIF frames which are the same as the last one, showing that one for longer")
+var ruleLog = flag.String("rule-log", "", "log the rules of every board and every rule change, with generations and seeds, to CSV `file` in the output directory")
+var fontFile = flag.String("font", "", "draw the UI text with the OpenType or TrueType font in `file`")
+var captureSpike = flag.Float64("capture-spike", 0, "save a GIF around every generation where the population changes by `percent` or more (0 for never)")
+var captureBefore = flag.Int("capture-before", 100, "number of `generations` before a -capture-spike change to include")
+var captureAfter = flag.Int("capture-after", 100, "number of `generations` after a -capture-spike change to include")
+var chunks = flag.Int("chunks", 8, "split the board into `n` chunks of rows per goroutine for updating, handed out as goroutines finish (1 for even static parts)")
+var debugPartitions = flag.Bool("debug-partitions", false, "draw lines between the chunks of rows the board is split into for updating, for debugging")
+var bounded = flag.Bool("bounded", false, "only update the cells around the live cells each generation, which is faster for small patterns on big boards")
+var ghost = flag.String("ghost", "", "draw the pattern in `file`, in plaintext or RLE format, as a faint ghost over the board for comparison (toggled with H)")
+var tui = flag.Bool("tui", false, "run in the terminal instead of a window, drawing the board with text characters")
+var tuiSize = flag.String("tui-size", "80x24", "size of the terminal for -tui as `COLSxROWS` characters, e.g. 80x24")
+var outdir = flag.String("outdir", "output", "`directory` to write recordings and stats to, created if needed")
 
 func run() {
-	// Set the right window properties. Should give pixel perfect image in fullscreen.
-	if game.SAVING_ENABLED {
-		ebiten.SetFullscreen(true)
-		ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	// Set the right window properties. Should give pixel perfect image in fullscreen. There's no window in the
+	// terminal.
+	if !*tui {
+		if game.SAVING_ENABLED {
+			ebiten.SetFullscreen(true)
+			ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 
-	} else {
-		ebiten.SetFullscreen(false)
-	}
-	ebiten.SetWindowSize(ebiten.ScreenSizeInFullscreen())
+		} else {
+			ebiten.SetFullscreen(false)
+		}
+		ebiten.SetWindowSize(game.FullscreenSize())
 
-	ebiten.SetVsyncEnabled(true)
-	ebiten.SetWindowTitle("go-llca")
+		ebiten.SetVsyncEnabled(true)
+		ebiten.SetWindowTitle("go-llca")
+	}
 
 	g := &game.Game{}
 	g.InitializeState() // Only called here.
+
+	if *config != "" {
+		if err := g.ApplyShareString(*config); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *resumeGif != "" {
+		if err := g.ResumeFromGIF(*resumeGif, *resumeRule); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *load != "" {
+		if err := g.LoadRLE(*load); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *dumpTables {
+		fmt.Print(g.DescribeTables())
+		return
+	}
+
+	if *sweep > 0 {
+		fmt.Println("saved seed sweep to", g.SweepSeeds(*sweep, *sweepGens))
+		return
+	}
+
+	if *stillLifes > 0 {
+		found, err := g.FindStillLifes(*stillLifes)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(found)
+		return
+	}
+
+	if *search > 0 {
+		results := g.SearchSeeds(*search, *searchGens)
+		if *searchEntropy {
+			fmt.Println("most complex seeds:")
+		} else {
+			fmt.Println("most active seeds:")
+		}
+		for i := 0; i < len(results) && i < 10; i++ {
+			fmt.Printf("seed %v: score %v\n", results[i].Seed, results[i].Score)
+		}
+		g.UseSeed(results[0].Seed)
+	}
+
+	if *tui {
+		var columns, rows int
+		if n, _ := fmt.Sscanf(*tuiSize, "%dx%d", &columns, &rows); n != 2 || columns < 3 || rows < 3 {
+			log.Fatalf("invalid terminal size %q, should be COLSxROWS with both at least 3, e.g. 80x24", *tuiSize)
+		}
+		g.RunTUI(os.Stdout, columns, rows)
+		g.Close()
+		return
+	}
+
 	g.InitializeBoard()
 
 	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
 	}
+	g.Close()
 }
 
 func main() {
+	flag.Parse()
+
+	// Pass the settings given on the command line on to the game.
+	if *recordFormat != "gif" && *recordFormat != "apng" {
+		log.Fatalf("unknown record format %q, should be gif or apng", *recordFormat)
+	}
+	game.RECORD_FORMAT = *recordFormat
+	game.STREAM_RECORDINGS = *streamGif
+	if *boomerang && *streamGif {
+		log.Fatal("-boomerang can't be used with -stream-gif, as streamed recordings are written as they're captured")
+	}
+	game.BOOMERANG_RECORDINGS = *boomerang
+	if *maxFrames < 0 {
+		log.Fatalf("maximum recording length of %v frames is negative", *maxFrames)
+	}
+	game.MAX_RECORDING_FRAMES = *maxFrames
+	if *rewind < 0 {
+		log.Fatalf("rewind length of %v generations is negative", *rewind)
+	}
+	game.REWIND_GENERATIONS = *rewind
+
+	if *area < 1 || *area > 100 {
+		log.Fatalf("simulation area %v%% is out of range, should be between 1 and 100", *area)
+	}
+	game.SIM_AREA_PERCENT = *area
+
+	c, err := game.ParseHexColor(*borderColor)
+	if err != nil {
+		log.Fatal(err)
+	}
+	game.BORDER_COLOR = c
+
+	if *speed < 0 {
+		log.Fatalf("speed %v is negative", *speed)
+	}
+	game.GENERATIONS_PER_SECOND = *speed
+
+	if *maxGensPerFrame < 0 {
+		log.Fatalf("maximum generations per frame %v is negative", *maxGensPerFrame)
+	}
+	game.MAX_GENERATIONS_PER_FRAME = *maxGensPerFrame
+	game.AUTO_SPEED = *autoSpeed
+
+	if *warmup < 0 {
+		log.Fatalf("warm-up of %v generations is negative", *warmup)
+	}
+	game.WARMUP_GENERATIONS = *warmup
+	game.SEARCH_BY_ENTROPY = *searchEntropy
+
+	game.INITIAL_B_RULES, game.INITIAL_S_RULES, game.STATES, err = game.ParseGenerationsRule(*rule)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *stillLifes < 0 || *stillLifes > game.MAX_STILL_LIFE_CELLS {
+		log.Fatalf("still life box size %v is out of range, should be between 0 and %v", *stillLifes,
+			game.MAX_STILL_LIFE_CELLS)
+	}
+
+	if *lifespan < 0 || *lifespan > game.MAX_LIFESPAN {
+		log.Fatalf("lifespan %v is out of range, should be between 0 and %v", *lifespan, game.MAX_LIFESPAN)
+	}
+	game.LIFESPAN = *lifespan
+
+	if *birthProbability < 0 || *birthProbability > 1 || *survivalProbability < 0 || *survivalProbability > 1 {
+		log.Fatal("birth and survival probabilities should be between 0 and 1")
+	}
+	game.BIRTH_PROBABILITY = *birthProbability
+	game.SURVIVAL_PROBABILITY = *survivalProbability
+
+	game.BOUNDARY_MODE, err = game.ParseBoundaryMode(*boundary)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *speedRamp < 0 {
+		log.Fatalf("speed ramp %v is negative", *speedRamp)
+	}
+	game.SPEED_RAMP = *speedRamp
+
+	if *duration < 0 {
+		log.Fatalf("duration %v is negative", *duration)
+	}
+	game.MAX_RUNTIME = *duration
+
+	if *idleReset < 0 {
+		log.Fatalf("idle reset %v is negative", *idleReset)
+	}
+	game.IDLE_RESET = *idleReset
+	game.IDLE_RESET_RULES = *idleResetRules
+
+	if *ltlRange != "" {
+		if *mask != "" {
+			log.Fatal("-range and -mask can't be used together, as a Larger than Life rule sets its own neighbourhood")
+		}
+		game.LTL_RULE, err = game.ParseLtLRule(*ltlRange)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *mask != "" {
+		game.NEIGHBOUR_MASK, err = game.LoadMask(*mask)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if game.STATES > 2 && (*mask != "" || *ltlRange != "") {
+		log.Fatalf("the Generations rule %v only works with the usual neighbourhood, so it can't be used with -mask or "+
+			"-range", *rule)
+	}
+
+	if *cuePopulation < 0 {
+		log.Fatalf("cue population %v is negative", *cuePopulation)
+	}
+	game.CUES_ENABLED = *cues
+	game.CUE_POPULATION = *cuePopulation
+	game.PAUSE_WHEN_STABLE = *pauseWhenStable
+
+	game.ALIVE_COLOR, err = game.ParseHexColor(*aliveColor)
+	if err != nil {
+		log.Fatal(err)
+	}
+	game.BORN_COLOR, err = game.ParseHexColor(*bornColor)
+	if err != nil {
+		log.Fatal(err)
+	}
+	game.TWO_TONE = *twoTone
+	game.OLD_COLOR, err = game.ParseHexColor(*oldColor)
+	if err != nil {
+		log.Fatal(err)
+	}
+	game.AGE_COLORED = *ageColors
+
+	if *trailDecay < 1 || *trailDecay > game.MAX_TRAIL {
+		log.Fatalf("trail decay %v should be between 1 and %v", *trailDecay, game.MAX_TRAIL)
+	}
+	game.TRAILS = *trails
+	game.TRAIL_DECAY = *trailDecay
+	game.TRAIL_COLOR, err = game.ParseHexColor(*trailColor)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	game.STATS_FILE = *stats
+	game.RULE_LOG_FILE = *ruleLog
+
+	game.FLIP, err = game.ParseFlip(*flip)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *resumeGif != "" && (*initPattern != "" || *search > 0 || *tile != "") {
+		log.Fatal("-resume-gif can't be used with -init, -search or -tile, as it sets the whole starting board")
+	}
+	if *load != "" && (*initPattern != "" || *resumeGif != "" || *search > 0 || *tile != "") {
+		log.Fatal("-load can't be used with -init, -resume-gif, -search or -tile, as it sets the whole starting board")
+	}
+	if *initPattern != "" {
+		if *search > 0 || *tile != "" {
+			log.Fatal("-init can't be used with -search or -tile, as it sets the whole starting board")
+		}
+		game.INIT_PATTERN, err = game.LoadPattern(*initPattern)
+		if err != nil {
+			log.Fatalf("could not read the -init pattern: %v", err)
+		}
+	}
+
+	if *tile != "" {
+		if *search > 0 {
+			log.Fatal("-tile and -search can't be used together, as tiled boards don't depend on the seed")
+		}
+		game.TILE_PATTERN, err = game.LoadPattern(*tile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *tileSpacing < 0 {
+		log.Fatalf("tile spacing %v is negative", *tileSpacing)
+	}
+	game.TILE_SPACING = *tileSpacing
+
+	if *board != "" {
+		var w, h int
+		if n, _ := fmt.Sscanf(*board, "%dx%d", &w, &h); n != 2 || w < 3 || h < 3 {
+			log.Fatalf("invalid board size %q, should be WxH with both at least 3, e.g. 640x360", *board)
+		}
+		game.BOARD_WIDTH, game.BOARD_HEIGHT = w, h
+	}
+	game.PIXEL_PERFECT = *pixelPerfect
+
+	if *crtIntensity < 0 || *crtIntensity > 1 {
+		log.Fatalf("CRT intensity %v is out of range, should be between 0 and 1", *crtIntensity)
+	}
+	game.CRT_ENABLED = *crt
+	game.CRT_INTENSITY = *crtIntensity
+
+	if *ghost != "" {
+		game.GHOST_PATTERN, err = game.LoadPattern(*ghost)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *brushRadius < 0 {
+		log.Fatalf("brush radius %v is negative", *brushRadius)
+	}
+	game.BRUSH_RADIUS = *brushRadius
+	if *brushDensity < 0 || *brushDensity > 100 {
+		log.Fatalf("brush density %v%% is out of range, should be between 0 and 100", *brushDensity)
+	}
+	game.BRUSH_DENSITY = *brushDensity
+
+	if *layer != "" {
+		game.LAYER_B_RULES, game.LAYER_S_RULES, err = game.ParseRulestring(*layer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game.LAYER_ENABLED = true
+	}
+	layerColorStrs := strings.Split(*layerColors, ",")
+	if len(layerColorStrs) != 2 {
+		log.Fatalf("invalid layer colours %q, should be two hex colours separated by a comma", *layerColors)
+	}
+	for i, str := range layerColorStrs {
+		game.LAYER_COLORS[i], err = game.ParseHexColor(str)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *gifMaxDim < 0 {
+		log.Fatalf("GIF max dimension %v is negative", *gifMaxDim)
+	}
+	game.GIF_MAX_DIM = *gifMaxDim
+	game.GIF_DITHER = *gifDither
+
+	if *minSurvival < 0 {
+		log.Fatalf("minimum survival %v is negative", *minSurvival)
+	}
+	game.MIN_SURVIVAL = *minSurvival
+
+	if err := game.SetRNG(*rng); err != nil {
+		log.Fatal(err)
+	}
+
+	game.DEDUPE_FRAMES = *dedupeFrames
+
+	game.FONT_FILE = *fontFile
+
+	if *captureSpike < 0 || *captureBefore < 0 || *captureAfter < 0 {
+		log.Fatal("the capture spike and the numbers of generations to capture should not be negative")
+	}
+	game.CAPTURE_SPIKE_PERCENT = *captureSpike
+	game.CAPTURE_BEFORE = *captureBefore
+	game.CAPTURE_AFTER = *captureAfter
+
+	if *chunks < 1 {
+		log.Fatalf("chunks per goroutine %v should be at least 1", *chunks)
+	}
+	game.CHUNKS_PER_WORKER = *chunks
+	game.SHOW_PARTITIONS = *debugPartitions
+	game.BOUNDED_UPDATES = *bounded
+
+	game.OUTPUT_DIR = *outdir
+	if game.SAVING_ENABLED || *stats != "" || *ruleLog != "" {
+		if err := game.PrepareOutputDir(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	game.SYMMETRY, err = game.ParseSymmetry(*symmetry)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *background != "" {
+		game.BACKGROUND_IMAGE, err = game.LoadImage(*background)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// Wrapper for run() to enable profiling
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)